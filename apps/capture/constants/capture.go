@@ -0,0 +1,37 @@
+package constants
+
+// kinds of capture target
+const (
+	TARGET_DCU   = "dcu"
+	TARGET_METER = "meter"
+)
+
+// verdicts recorded in the packet comment
+const (
+	VERDICT_OK                  = "ok"
+	VERDICT_INVALID_WP          = "invalid_wp"
+	VERDICT_UNSUPPORTED_VERSION = "unsupported_wp_version"
+	VERDICT_INVALID_TAP         = "invalid_tap"
+)
+
+// interfaces of every capture file, in the order they are written
+const (
+	INTERFACE_WP   = 0
+	INTERFACE_IRDA = 1
+)
+
+const (
+	CAPTURE_FILE_TIME_FORMAT = "20060102T150405"
+	CAPTURE_FILE_EXTENSION   = ".pcapng"
+)
+
+const (
+	ERR_INVALID_TARGET_KIND   = "capture target kind must be dcu or meter, got %q"
+	ERR_EMPTY_TARGET_ID       = "capture target id cannot be empty"
+	ERR_TARGET_NOT_FOUND      = "no capture running for %s %s"
+	ERR_CREATING_CAPTURE_FILE = "error occurred while creating capture file %v: %v"
+	ERR_WRITING_CAPTURE       = "error occurred while writing capture of %s %s: %v"
+	ERR_CLOSING_CAPTURE       = "error occurred while closing capture of %s %s: %v"
+	ERR_ROTATING_CAPTURE      = "error occurred while rotating capture of %s %s, switching it off: %v"
+	ERR_REMOVING_CAPTURE      = "error occurred while removing old capture file %v: %v"
+)
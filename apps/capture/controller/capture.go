@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	captureConstants "parsing-service/apps/capture/constants"
+	services "parsing-service/apps/capture/service_interfaces"
+	"parsing-service/constants"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/pcapng"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CaptureController struct {
+	captureService services.ICaptureService
+	logger         logger.ILogger
+}
+
+func NewCaptureController(
+	captureServiceIntf services.ICaptureService,
+	logger logger.ILogger,
+) *CaptureController {
+	return &CaptureController{
+		captureService: captureServiceIntf,
+		logger:         logger,
+	}
+}
+
+// ListTargets returns the DCUs and meters being captured.
+func (c *CaptureController) ListTargets(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.captureService.Targets())
+}
+
+// EnableTarget starts capturing /capture/:kind/:id.
+func (c *CaptureController) EnableTarget(ctx *gin.Context) {
+	target, err := c.captureService.Enable(ctx.Param("kind"), ctx.Param("id"))
+	if err != nil {
+		c.logger.Errorf("<EnableTarget> Error %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{constants.ERROR_STRING: err.Error(), constants.ERROR_CODE_STRING: constants.BAD_REQUEST_ERROR_CODE})
+		return
+	}
+	ctx.JSON(http.StatusOK, target)
+}
+
+// DisableTarget stops capturing /capture/:kind/:id.
+func (c *CaptureController) DisableTarget(ctx *gin.Context) {
+	kind, id := ctx.Param("kind"), ctx.Param("id")
+	if _, ok := c.captureService.Target(kind, id); !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{constants.ERROR_STRING: fmt.Sprintf(captureConstants.ERR_TARGET_NOT_FOUND, kind, id), constants.ERROR_CODE_STRING: constants.RECORD_NOT_FOUND_ERROR_CODE})
+		return
+	}
+	target, err := c.captureService.Disable(kind, id)
+	if err != nil {
+		c.logger.Errorf("<DisableTarget> Error %v", err)
+		ctx.JSON(http.StatusBadRequest, gin.H{constants.ERROR_STRING: err.Error(), constants.ERROR_CODE_STRING: constants.BAD_REQUEST_ERROR_CODE})
+		return
+	}
+	ctx.JSON(http.StatusOK, target)
+}
+
+// DownloadCapture sends the file a running target is being written to,
+// everything written to it so far. Earlier files of the target are listed
+// with it.
+func (c *CaptureController) DownloadCapture(ctx *gin.Context) {
+	kind, id := ctx.Param("kind"), ctx.Param("id")
+	target, ok := c.captureService.Target(kind, id)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{constants.ERROR_STRING: fmt.Sprintf(captureConstants.ERR_TARGET_NOT_FOUND, kind, id), constants.ERROR_CODE_STRING: constants.RECORD_NOT_FOUND_ERROR_CODE})
+		return
+	}
+	ctx.FileAttachment(target.File, filepath.Base(target.File))
+}
+
+// DownloadDissector sends the Wireshark Lua plugin for the capture files.
+func (c *CaptureController) DownloadDissector(ctx *gin.Context) {
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", pcapng.LUA_DISSECTOR_FILE_NAME))
+	ctx.Data(http.StatusOK, "text/x-lua", pcapng.LuaDissector)
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// CaptureTarget is a DCU or meter whose traffic is being written to File.
+// Files lists the files of the target still on disk, oldest first, File
+// being the last of them.
+type CaptureTarget struct {
+	Kind    string    `json:"kind"`
+	ID      string    `json:"id"`
+	File    string    `json:"file"`
+	Files   []string  `json:"files"`
+	Packets int       `json:"packets"`
+	Since   time.Time `json:"since"`
+}
+
+// CapturedFrame is one Kafka payload as the consumer received it, with what
+// the consumer made of it.
+type CapturedFrame struct {
+	Time        time.Time
+	DcuNumber   string
+	MeterIps    []string
+	GatewayMode string
+	Verdict     string
+	Payload     []byte
+}
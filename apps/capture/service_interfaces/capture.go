@@ -0,0 +1,12 @@
+package serviceinterfaces
+
+import "parsing-service/apps/capture/models"
+
+type ICaptureService interface {
+	Enable(kind string, id string) (models.CaptureTarget, error)
+	Disable(kind string, id string) (models.CaptureTarget, error)
+	Targets() []models.CaptureTarget
+	Target(kind string, id string) (models.CaptureTarget, bool)
+	Active() bool
+	Record(frame models.CapturedFrame)
+}
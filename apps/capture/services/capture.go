@@ -0,0 +1,322 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"parsing-service/apps/capture/constants"
+	"parsing-service/apps/capture/models"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/pcapng"
+)
+
+// CaptureService writes the frames of selected DCUs and meters to one pcapng
+// file per target. Targets are switched on and off at runtime; a frame is
+// written to the file of its DCU and of every meter it carried a packet of.
+// A file that reaches the size limit is closed and the target goes on in a
+// new one, the oldest files beyond the file limit are removed, and targets
+// running past the duration limit are switched off.
+type CaptureService struct {
+	cfg    *config.Configuration
+	logger logger.ILogger
+
+	mu      sync.Mutex
+	targets map[string]*captureTarget
+}
+
+type captureTarget struct {
+	models.CaptureTarget
+	file   *os.File
+	writer *pcapng.Writer
+	// bytes written to file
+	written *countingWriter
+	// files opened so far, removed ones included
+	parts int
+}
+
+// snapshot copies the state of target, safe to use after the lock is
+// released.
+func (t *captureTarget) snapshot() models.CaptureTarget {
+	snapshot := t.CaptureTarget
+	snapshot.Files = append([]string(nil), t.Files...)
+	return snapshot
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func NewCaptureService(cfg *config.Configuration, logger logger.ILogger) *CaptureService {
+	s := &CaptureService{
+		cfg:     cfg,
+		logger:  logger,
+		targets: make(map[string]*captureTarget),
+	}
+	for _, dcu := range cfg.CaptureConfig.Dcus {
+		if _, err := s.Enable(constants.TARGET_DCU, dcu); err != nil {
+			logger.Errorf("%v", err)
+		}
+	}
+	for _, meterIp := range cfg.CaptureConfig.MeterIps {
+		if _, err := s.Enable(constants.TARGET_METER, meterIp); err != nil {
+			logger.Errorf("%v", err)
+		}
+	}
+	return s
+}
+
+func targetKey(kind string, id string) string {
+	return kind + ":" + id
+}
+
+func validateTarget(kind string, id string) error {
+	if kind != constants.TARGET_DCU && kind != constants.TARGET_METER {
+		return fmt.Errorf(constants.ERR_INVALID_TARGET_KIND, kind)
+	}
+	if strings.TrimSpace(id) == "" {
+		return errors.New(constants.ERR_EMPTY_TARGET_ID)
+	}
+	return nil
+}
+
+// Enable starts capturing the traffic of a target into a new file. Enabling
+// a running target keeps its current file.
+func (s *CaptureService) Enable(kind string, id string) (models.CaptureTarget, error) {
+	if err := validateTarget(kind, id); err != nil {
+		return models.CaptureTarget{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := targetKey(kind, id)
+	if target, ok := s.targets[key]; ok {
+		return target.snapshot(), nil
+	}
+
+	target := &captureTarget{CaptureTarget: models.CaptureTarget{Kind: kind, ID: id, Since: time.Now()}}
+	if err := s.openFile(target); err != nil {
+		return models.CaptureTarget{}, err
+	}
+	s.targets[key] = target
+	s.logger.Infof("capturing %s %s into %s", kind, id, target.File)
+	return target.snapshot(), nil
+}
+
+// openFile starts the next file of target. Files after the first are
+// numbered from 2 on.
+func (s *CaptureService) openFile(target *captureTarget) error {
+	part := ""
+	if target.parts > 0 {
+		part = fmt.Sprintf("_%d", target.parts+1)
+	}
+	name := fmt.Sprintf("%s_%s_%s%s%s", target.Kind, strings.NewReplacer(".", "-", ":", "-", "/", "-").Replace(target.ID), target.Since.Format(constants.CAPTURE_FILE_TIME_FORMAT), part, constants.CAPTURE_FILE_EXTENSION)
+	path := filepath.Join(s.cfg.CaptureConfig.Dir, name)
+	if err := os.MkdirAll(s.cfg.CaptureConfig.Dir, 0o755); err != nil {
+		return fmt.Errorf(constants.ERR_CREATING_CAPTURE_FILE, path, err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf(constants.ERR_CREATING_CAPTURE_FILE, path, err)
+	}
+	written := &countingWriter{w: file}
+	writer, err := pcapng.NewWriter(written,
+		pcapng.Interface{LinkType: pcapng.LINKTYPE_WP, Name: "wp"},
+		pcapng.Interface{LinkType: pcapng.LINKTYPE_IRDA, Name: "irda"},
+	)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf(constants.ERR_CREATING_CAPTURE_FILE, path, err)
+	}
+
+	target.file, target.writer, target.written = file, writer, written
+	target.parts++
+	target.File = path
+	target.Files = append(target.Files, path)
+	return nil
+}
+
+// rotate closes the full file of target, starts its next one and removes
+// its oldest files beyond the file limit.
+func (s *CaptureService) rotate(target *captureTarget) error {
+	if err := target.file.Close(); err != nil {
+		s.logger.Errorf(constants.ERR_CLOSING_CAPTURE, target.Kind, target.ID, err)
+	}
+	if err := s.openFile(target); err != nil {
+		return err
+	}
+
+	maxFiles := s.cfg.CaptureConfig.MaxFiles
+	for maxFiles > 0 && len(target.Files) > maxFiles {
+		if err := os.Remove(target.Files[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.logger.Errorf(constants.ERR_REMOVING_CAPTURE, target.Files[0], err)
+		}
+		target.Files = target.Files[1:]
+	}
+	return nil
+}
+
+// Disable stops capturing a target and closes its file, which stays on disk.
+func (s *CaptureService) Disable(kind string, id string) (models.CaptureTarget, error) {
+	if err := validateTarget(kind, id); err != nil {
+		return models.CaptureTarget{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target, ok := s.targets[targetKey(kind, id)]
+	if !ok {
+		return models.CaptureTarget{}, fmt.Errorf(constants.ERR_TARGET_NOT_FOUND, kind, id)
+	}
+	s.stop(target)
+	return target.snapshot(), nil
+}
+
+// stop closes the file of target and forgets it.
+func (s *CaptureService) stop(target *captureTarget) {
+	delete(s.targets, targetKey(target.Kind, target.ID))
+	if err := target.file.Close(); err != nil {
+		s.logger.Errorf(constants.ERR_CLOSING_CAPTURE, target.Kind, target.ID, err)
+	}
+	s.logger.Infof("stopped capturing %s %s, %d packet(s) in %s", target.Kind, target.ID, target.Packets, strings.Join(target.Files, ", "))
+}
+
+func (s *CaptureService) Targets() []models.CaptureTarget {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	targets := make([]models.CaptureTarget, 0, len(s.targets))
+	for _, target := range s.targets {
+		targets = append(targets, target.snapshot())
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Kind != targets[j].Kind {
+			return targets[i].Kind < targets[j].Kind
+		}
+		return targets[i].ID < targets[j].ID
+	})
+	return targets
+}
+
+func (s *CaptureService) Target(kind string, id string) (models.CaptureTarget, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target, ok := s.targets[targetKey(kind, id)]
+	if !ok {
+		return models.CaptureTarget{}, false
+	}
+	return target.snapshot(), true
+}
+
+// Active reports whether any target is being captured, so callers can skip
+// collecting what Record needs.
+func (s *CaptureService) Active() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.targets) > 0
+}
+
+// Record writes frame to the files of the targets it belongs to. Targets
+// past the duration limit are switched off first.
+func (s *CaptureService) Record(frame models.CapturedFrame) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expire(time.Now())
+	if len(s.targets) == 0 {
+		return
+	}
+
+	frame.MeterIps = uniqueMeterIps(frame.MeterIps)
+	var matched []*captureTarget
+	if target, ok := s.targets[targetKey(constants.TARGET_DCU, frame.DcuNumber)]; ok {
+		matched = append(matched, target)
+	}
+	for _, meterIp := range frame.MeterIps {
+		if target, ok := s.targets[targetKey(constants.TARGET_METER, meterIp)]; ok {
+			matched = append(matched, target)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	iface := constants.INTERFACE_WP
+	if frame.GatewayMode != "wp" {
+		iface = constants.INTERFACE_IRDA
+	}
+	comment := captureComment(frame)
+
+	maxFileBytes := s.cfg.CaptureConfig.MaxFileBytes
+	for _, target := range matched {
+		if maxFileBytes > 0 && target.written.n >= maxFileBytes {
+			if err := s.rotate(target); err != nil {
+				s.logger.Errorf(constants.ERR_ROTATING_CAPTURE, target.Kind, target.ID, err)
+				delete(s.targets, targetKey(target.Kind, target.ID))
+				continue
+			}
+		}
+		if err := target.writer.WritePacket(iface, frame.Time, frame.Payload, comment); err != nil {
+			s.logger.Errorf(constants.ERR_WRITING_CAPTURE, target.Kind, target.ID, err)
+			continue
+		}
+		target.Packets++
+	}
+}
+
+// expire switches off the targets captured for longer than the duration
+// limit.
+func (s *CaptureService) expire(now time.Time) {
+	maxDuration := time.Duration(s.cfg.CaptureConfig.MaxDurationMs) * time.Millisecond
+	if maxDuration <= 0 {
+		return
+	}
+	for _, target := range s.targets {
+		if now.Sub(target.Since) >= maxDuration {
+			s.stop(target)
+		}
+	}
+}
+
+// uniqueMeterIps drops repeats, a frame often carries several packets of
+// one meter.
+func uniqueMeterIps(meterIps []string) []string {
+	seen := make(map[string]bool, len(meterIps))
+	unique := make([]string, 0, len(meterIps))
+	for _, meterIp := range meterIps {
+		if !seen[meterIp] {
+			seen[meterIp] = true
+			unique = append(unique, meterIp)
+		}
+	}
+	return unique
+}
+
+// captureComment is the packet comment Wireshark shows for frame:
+//
+//	dcu=300 mode=wp verdict=ok meters=10.0.0.1,10.0.0.2
+func captureComment(frame models.CapturedFrame) string {
+	comment := fmt.Sprintf("dcu=%s mode=%s verdict=%s", frame.DcuNumber, frame.GatewayMode, frame.Verdict)
+	if len(frame.MeterIps) > 0 {
+		comment += " meters=" + strings.Join(frame.MeterIps, ",")
+	}
+	return comment
+}
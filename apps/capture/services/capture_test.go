@@ -0,0 +1,99 @@
+package services
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"parsing-service/apps/capture/constants"
+	"parsing-service/apps/capture/models"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+)
+
+func captureService(t *testing.T, cfg config.CaptureConfig) *CaptureService {
+	cfg.Dir = t.TempDir()
+	return NewCaptureService(&config.Configuration{CaptureConfig: cfg}, logger.NewLogger())
+}
+
+func dcuFrame() models.CapturedFrame {
+	return models.CapturedFrame{Time: time.Now(), DcuNumber: "300", GatewayMode: "wp", Verdict: constants.VERDICT_OK, Payload: make([]byte, 64)}
+}
+
+func TestCaptureRotatesFullFiles(t *testing.T) {
+	s := captureService(t, config.CaptureConfig{MaxFileBytes: 100, MaxFiles: 2})
+	if _, err := s.Enable(constants.TARGET_DCU, "300"); err != nil {
+		t.Fatal(err)
+	}
+
+	var removed []string
+	for i := 0; i < 4; i++ {
+		before, _ := s.Target(constants.TARGET_DCU, "300")
+		s.Record(dcuFrame())
+		after, _ := s.Target(constants.TARGET_DCU, "300")
+		if len(before.Files) == 2 && before.Files[0] != after.Files[0] {
+			removed = append(removed, before.Files[0])
+		}
+	}
+
+	target, ok := s.Target(constants.TARGET_DCU, "300")
+	if !ok || target.Packets != 4 {
+		t.Fatalf("target %+v", target)
+	}
+	if len(target.Files) != 2 || target.File != target.Files[1] {
+		t.Fatalf("files %v, current %s", target.Files, target.File)
+	}
+	// the headers are below the limit, every file takes one packet
+	var sizes []int64
+	for _, path := range target.Files {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes = append(sizes, info.Size())
+	}
+	if sizes[0] != sizes[1] {
+		t.Fatalf("file sizes %v", sizes)
+	}
+	if len(removed) == 0 {
+		t.Fatal("no file was removed")
+	}
+	for _, path := range removed {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("%s kept past the file limit: %v", path, err)
+		}
+	}
+}
+
+func TestCaptureUnlimited(t *testing.T) {
+	s := captureService(t, config.CaptureConfig{})
+	if _, err := s.Enable(constants.TARGET_DCU, "300"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		s.Record(dcuFrame())
+	}
+	if target, _ := s.Target(constants.TARGET_DCU, "300"); len(target.Files) != 1 || target.Packets != 4 {
+		t.Fatalf("target %+v", target)
+	}
+}
+
+func TestCaptureSwitchesOffPastDuration(t *testing.T) {
+	s := captureService(t, config.CaptureConfig{MaxDurationMs: 60000})
+	if _, err := s.Enable(constants.TARGET_DCU, "300"); err != nil {
+		t.Fatal(err)
+	}
+	s.Record(dcuFrame())
+
+	s.mu.Lock()
+	s.targets[targetKey(constants.TARGET_DCU, "300")].Since = time.Now().Add(-time.Minute)
+	s.mu.Unlock()
+	s.Record(dcuFrame())
+
+	if target, ok := s.Target(constants.TARGET_DCU, "300"); ok {
+		t.Fatalf("target still captured: %+v", target)
+	}
+	if s.Active() {
+		t.Fatal("capture still active")
+	}
+}
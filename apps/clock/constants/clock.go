@@ -0,0 +1,11 @@
+package constants
+
+const (
+	EVENT_DRIFT_EXCEEDED  = "drift_exceeded"
+	EVENT_DRIFT_RECOVERED = "drift_recovered"
+)
+
+const (
+	ERR_INVALID_DCU_NUMBER = "invalid dcu number %q"
+	ERR_DCU_NOT_FOUND      = "no clock samples for dcu %d"
+)
@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	clockConstants "parsing-service/apps/clock/constants"
+	services "parsing-service/apps/clock/service_interfaces"
+	"parsing-service/constants"
+	"parsing-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClockController struct {
+	clockTracker services.IClockTracker
+	logger       logger.ILogger
+}
+
+func NewClockController(
+	clockTrackerIntf services.IClockTracker,
+	logger logger.ILogger,
+) *ClockController {
+	return &ClockController{
+		clockTracker: clockTrackerIntf,
+		logger:       logger,
+	}
+}
+
+// ListClocks returns the clock drift of every DCU heard from, largest first.
+func (c *ClockController) ListClocks(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.clockTracker.ListClocks())
+}
+
+// GetClock returns the clock drift of DCU :dcuNumber.
+func (c *ClockController) GetClock(ctx *gin.Context) {
+	dcuNumber, err := strconv.ParseUint(ctx.Param("dcuNumber"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{constants.ERROR_STRING: fmt.Sprintf(clockConstants.ERR_INVALID_DCU_NUMBER, ctx.Param("dcuNumber")), constants.ERROR_CODE_STRING: constants.INVALID_REQ_PARAMS_CODE})
+		return
+	}
+
+	clock, ok := c.clockTracker.GetClock(uint32(dcuNumber))
+	if !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{constants.ERROR_STRING: fmt.Sprintf(clockConstants.ERR_DCU_NOT_FOUND, dcuNumber), constants.ERROR_CODE_STRING: constants.RECORD_NOT_FOUND_ERROR_CODE})
+		return
+	}
+	ctx.JSON(http.StatusOK, clock)
+}
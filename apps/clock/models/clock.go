@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// DcuClock is how far a DCU's clock is off the time its frames reach the
+// broker. DriftSeconds is positive when the DCU clock is ahead.
+type DcuClock struct {
+	DcuNumber      uint32     `json:"dcuNumber"`
+	DriftSeconds   float64    `json:"driftSeconds"`
+	Samples        int        `json:"samples"`
+	Exceeded       bool       `json:"exceeded"`
+	ExceededSince  *time.Time `json:"exceededSince,omitempty"`
+	LastDcuTime    uint32     `json:"lastDcuTime"`
+	LastReceivedAt time.Time  `json:"lastReceivedAt"`
+}
+
+// DriftEvent is published on the clock drift topic when a DCU's drift
+// crosses the threshold, either way.
+type DriftEvent struct {
+	Event            string    `json:"event"`
+	DcuNumber        uint32    `json:"dcuNumber"`
+	SinkID           uint8     `json:"sinkId"`
+	DriftSeconds     float64   `json:"driftSeconds"`
+	ThresholdSeconds int       `json:"thresholdSeconds"`
+	DcuTime          uint32    `json:"dcuTime"`
+	ReceivedAt       time.Time `json:"receivedAt"`
+}
@@ -0,0 +1,14 @@
+package serviceinterfaces
+
+import (
+	"parsing-service/apps/clock/models"
+	"parsing-service/pkg/wp"
+	"time"
+)
+
+type IClockTracker interface {
+	Observe(frame wp.Frame, receivedAt time.Time) bool
+	Flush()
+	GetClock(dcuNumber uint32) (models.DcuClock, bool)
+	ListClocks() []models.DcuClock
+}
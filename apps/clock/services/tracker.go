@@ -0,0 +1,170 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"parsing-service/apps/clock/constants"
+	"parsing-service/apps/clock/models"
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/wp"
+)
+
+// dcuClock is the drift window of one DCU.
+type dcuClock struct {
+	state   models.DcuClock
+	samples []float64
+	next    int
+}
+
+// ClockTracker compares the DcuTime of every WP frame with the time the frame
+// reached the broker. Frames only arrive late, never early, so the drift of a
+// DCU is estimated as the largest offset in its window: the frame that spent
+// the least time in transit.
+type ClockTracker struct {
+	KafkaProducer kafkaIntf.IKafkaProducer
+	cfg           *config.Configuration
+	logger        logger.ILogger
+
+	mu     sync.Mutex
+	clocks map[uint32]*dcuClock
+	events []models.DriftEvent
+}
+
+func NewClockTracker(
+	kafkaProducer kafkaIntf.IKafkaProducer,
+	cfg *config.Configuration,
+	logger logger.ILogger,
+) *ClockTracker {
+	return &ClockTracker{
+		KafkaProducer: kafkaProducer,
+		cfg:           cfg,
+		logger:        logger,
+		clocks:        make(map[uint32]*dcuClock),
+	}
+}
+
+// Observe adds the offset of a frame received at receivedAt to its DCU's
+// window and reports whether the frame carried a DCU time. A drift event is
+// raised when the estimate crosses the threshold, either way.
+func (t *ClockTracker) Observe(frame wp.Frame, receivedAt time.Time) bool {
+	if frame.DcuNumber == 0 || frame.DcuTime == 0 {
+		return false
+	}
+	clockCfg := t.cfg.ClockConfig
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	clock, ok := t.clocks[frame.DcuNumber]
+	if !ok {
+		clock = &dcuClock{state: models.DcuClock{DcuNumber: frame.DcuNumber}}
+		t.clocks[frame.DcuNumber] = clock
+	}
+	offset := float64(frame.DcuTime) - float64(receivedAt.UnixMilli())/1000
+	clock.add(offset, clockCfg.WindowSize)
+	clock.state.LastDcuTime = frame.DcuTime
+	clock.state.LastReceivedAt = receivedAt
+
+	if len(clock.samples) < clockCfg.MinSamples {
+		return true
+	}
+
+	exceeded := math.Abs(clock.state.DriftSeconds) > float64(clockCfg.DriftThresholdSeconds)
+	if exceeded == clock.state.Exceeded {
+		return true
+	}
+
+	event := models.DriftEvent{
+		Event:            constants.EVENT_DRIFT_RECOVERED,
+		DcuNumber:        frame.DcuNumber,
+		SinkID:           frame.SinkID,
+		DriftSeconds:     clock.state.DriftSeconds,
+		ThresholdSeconds: clockCfg.DriftThresholdSeconds,
+		DcuTime:          frame.DcuTime,
+		ReceivedAt:       receivedAt,
+	}
+	clock.state.Exceeded = exceeded
+	clock.state.ExceededSince = nil
+	if exceeded {
+		event.Event = constants.EVENT_DRIFT_EXCEEDED
+		since := receivedAt
+		clock.state.ExceededSince = &since
+	}
+	t.events = append(t.events, event)
+
+	return true
+}
+
+func (c *dcuClock) add(offset float64, windowSize int) {
+	if windowSize <= 0 {
+		windowSize = 1
+	}
+	if len(c.samples) < windowSize {
+		c.samples = append(c.samples, offset)
+	} else {
+		c.samples[c.next] = offset
+		c.next = (c.next + 1) % windowSize
+	}
+
+	drift := c.samples[0]
+	for _, sample := range c.samples[1:] {
+		drift = math.Max(drift, sample)
+	}
+	c.state.DriftSeconds = math.Round(drift*1000) / 1000
+	c.state.Samples = len(c.samples)
+}
+
+// Flush publishes the drift events since the last flush.
+func (t *ClockTracker) Flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.events) == 0 {
+		return
+	}
+	events := make([][]byte, 0, len(t.events))
+	for _, event := range t.events {
+		if data, err := json.Marshal(event); err == nil {
+			events = append(events, data)
+		}
+	}
+	t.events = nil
+	t.logger.Debugf("<Flush> %d clock drift events", len(events))
+	go t.KafkaProducer.ProduceMessagesInBatch(t.cfg.KafkaTopicsConfig.PRODUCE_CLOCK_DRIFT_KAFKA_TOPIC_NAME, events)
+}
+
+func (t *ClockTracker) GetClock(dcuNumber uint32) (models.DcuClock, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	clock, ok := t.clocks[dcuNumber]
+	if !ok {
+		return models.DcuClock{}, false
+	}
+	return clock.state, true
+}
+
+// ListClocks returns the clocks of every DCU heard from, largest drift first.
+func (t *ClockTracker) ListClocks() []models.DcuClock {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	clocks := make([]models.DcuClock, 0, len(t.clocks))
+	for _, clock := range t.clocks {
+		clocks = append(clocks, clock.state)
+	}
+	sort.Slice(clocks, func(i, j int) bool {
+		a, b := math.Abs(clocks[i].DriftSeconds), math.Abs(clocks[j].DriftSeconds)
+		if a != b {
+			return a > b
+		}
+		return clocks[i].DcuNumber < clocks[j].DcuNumber
+	})
+	return clocks
+}
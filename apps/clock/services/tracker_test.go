@@ -0,0 +1,126 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"parsing-service/apps/clock/constants"
+	"parsing-service/apps/clock/models"
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/wp"
+)
+
+// eventProducer hands the published batches over on a channel, the other
+// methods are not used by the tests.
+type eventProducer struct {
+	kafkaIntf.IKafkaProducer
+	batches chan [][]byte
+}
+
+func (p *eventProducer) ProduceMessagesInBatch(topic string, messages [][]byte) error {
+	p.batches <- messages
+	return nil
+}
+
+func newTestTracker() (*ClockTracker, *eventProducer) {
+	cfg := &config.Configuration{}
+	cfg.ClockConfig = config.ClockConfig{DriftThresholdSeconds: 60, WindowSize: 3, MinSamples: 2}
+	producer := &eventProducer{batches: make(chan [][]byte, 8)}
+	return NewClockTracker(producer, cfg, logger.NewLogger()), producer
+}
+
+func flushedEvents(t *testing.T, tracker *ClockTracker, producer *eventProducer) []models.DriftEvent {
+	t.Helper()
+	tracker.Flush()
+	select {
+	case batch := <-producer.batches:
+		events := make([]models.DriftEvent, len(batch))
+		for i, data := range batch {
+			if err := json.Unmarshal(data, &events[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return events
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
+
+// observe feeds a frame whose DCU clock is drift seconds off, received
+// transit seconds after it was stamped.
+func observe(tracker *ClockTracker, receivedAt time.Time, drift, transit int64) {
+	dcuTime := uint32(receivedAt.Unix() + drift - transit)
+	tracker.Observe(wp.Frame{DcuNumber: 300, SinkID: 1, DcuTime: dcuTime}, receivedAt)
+}
+
+func TestClockDriftCrossingAndRecovery(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tracker, producer := newTestTracker()
+
+	if tracker.Observe(wp.Frame{DcuNumber: 300}, start) {
+		t.Fatal("a frame without a DCU time was observed")
+	}
+
+	// a single sample is not enough to raise an event
+	observe(tracker, start, 120, 0)
+	if events := flushedEvents(t, tracker, producer); len(events) != 0 {
+		t.Fatalf("events %+v before the minimum samples", events)
+	}
+
+	// the least delayed frame of the window sets the drift
+	observe(tracker, start.Add(time.Minute), 120, 30)
+	events := flushedEvents(t, tracker, producer)
+	if len(events) != 1 || events[0].Event != constants.EVENT_DRIFT_EXCEEDED || events[0].DriftSeconds != 120 {
+		t.Fatalf("events %+v, want drift exceeded at 120s", events)
+	}
+	clock, ok := tracker.GetClock(300)
+	if !ok || !clock.Exceeded || clock.ExceededSince == nil || !clock.ExceededSince.Equal(start.Add(time.Minute)) {
+		t.Fatalf("clock %+v, want exceeded since the second frame", clock)
+	}
+
+	// staying past the threshold raises nothing new
+	observe(tracker, start.Add(2*time.Minute), 100, 0)
+	if events := flushedEvents(t, tracker, producer); len(events) != 0 {
+		t.Fatalf("events %+v while the drift stays exceeded", events)
+	}
+
+	// the corrected clock pushes the old offsets out of the window
+	for i := 3; i <= 5; i++ {
+		observe(tracker, start.Add(time.Duration(i)*time.Minute), 5, 0)
+	}
+	events = flushedEvents(t, tracker, producer)
+	if len(events) != 1 || events[0].Event != constants.EVENT_DRIFT_RECOVERED || events[0].DriftSeconds != 5 {
+		t.Fatalf("events %+v, want drift recovered at 5s", events)
+	}
+	if clock, _ := tracker.GetClock(300); clock.Exceeded || clock.ExceededSince != nil || clock.Samples != 3 {
+		t.Fatalf("clock %+v, want recovered over a full window", clock)
+	}
+}
+
+func TestClockDriftBehind(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tracker, producer := newTestTracker()
+
+	observe(tracker, start, -90, 0)
+	observe(tracker, start.Add(time.Minute), -90, 10)
+	events := flushedEvents(t, tracker, producer)
+	if len(events) != 1 || events[0].Event != constants.EVENT_DRIFT_EXCEEDED || events[0].DriftSeconds != -90 {
+		t.Fatalf("events %+v, want a DCU clock 90s behind exceeded", events)
+	}
+}
+
+func TestListClocksLargestDriftFirst(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	tracker, _ := newTestTracker()
+
+	for dcuNumber, drift := range map[uint32]int64{300: 5, 301: -40, 302: 20} {
+		tracker.Observe(wp.Frame{DcuNumber: dcuNumber, DcuTime: uint32(start.Unix() + drift)}, start)
+	}
+	clocks := tracker.ListClocks()
+	if len(clocks) != 3 || clocks[0].DcuNumber != 301 || clocks[1].DcuNumber != 302 || clocks[2].DcuNumber != 300 {
+		t.Fatalf("clocks %+v, want 301, 302, 300", clocks)
+	}
+}
@@ -8,4 +8,6 @@ const (
 	ERR_COMMITTING_OFFSET_SYNC                     = "error occurred while committing offset sync: %v, offset: %v"
 	ERROR_IN_GETTING_CMD_ID							= "error occured while getting command id: %v"
 	ERROR_IN_GETTING_METER_IP 						= "error occured while getting meter ip: %v"
-)
\ No newline at end of file
+)
+// message fields that identify the dcu of an irda payload, in order of preference
+var IRDA_DCU_KEYS = []string{"dcuNumber", "dcu_no", "dcuId"}
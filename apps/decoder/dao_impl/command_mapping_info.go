@@ -14,6 +14,28 @@ type CommandMappingImpl struct {
 	logger logger.ILogger
 }
 
+func NewCommandMappingDAO(logger logger.ILogger) *CommandMappingImpl {
+	return &CommandMappingImpl{logger: logger}
+}
+
+func (commandMappingDao *CommandMappingImpl) GetCommandMappings(requestID string) []models.CommandMapping {
+	var commandMappingData []models.CommandMapping
+
+	err := database.DB.Model(&models.CommandMapping{}).Order("command_mapping.cmd_id, command_mapping.group_id").Find(&commandMappingData).Error
+	if err != nil {
+		commandMappingDao.logger.Errorf("<GetCommandMappings> RequestID %v, Error %v", requestID, err)
+		customError := customErrorPkg.NewCustomError(
+			errors.New(constants.PROCESSING_ERROR),
+			constants.INTERNAL_SERVER_ERROR_CODE,
+			http.StatusInternalServerError,
+		)
+
+		panic(customError)
+	}
+
+	return commandMappingData
+}
+
 func (commandMappingDao *CommandMappingImpl) GetCommandMappingByCmdID(cmdID int, requestID string) []models.CommandMapping {
 	var commandMappingData []models.CommandMapping
 
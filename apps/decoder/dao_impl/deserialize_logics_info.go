@@ -14,6 +14,10 @@ type DeserializeLogicsImpl struct {
 	logger logger.ILogger
 }
 
+func NewDeserializeLogicsDAO(logger logger.ILogger) *DeserializeLogicsImpl {
+	return &DeserializeLogicsImpl{logger: logger}
+}
+
 func (DeserializeLogicsDao *DeserializeLogicsImpl) GetDeserializeLogicsByCmdId(cmdID int, requestID string) []models.DeserializeLogics {
 	var deserializeLogicsData []models.DeserializeLogics
 
@@ -0,0 +1,37 @@
+package daoimpl
+
+import (
+	"errors"
+	"net/http"
+	"parsing-service/apps/decoder/models"
+	"parsing-service/constants"
+	customErrorPkg "parsing-service/pkg/custom_error"
+	"parsing-service/pkg/database"
+	"parsing-service/pkg/logger"
+)
+
+type EventCatalogueImpl struct {
+	logger logger.ILogger
+}
+
+func NewEventCatalogueDAO(logger logger.ILogger) *EventCatalogueImpl {
+	return &EventCatalogueImpl{logger: logger}
+}
+
+func (eventCatalogueDao *EventCatalogueImpl) GetEventCatalogue(requestID string) []models.EventCatalogue {
+	var catalogue []models.EventCatalogue
+
+	err := database.DB.Model(&models.EventCatalogue{}).Order("event_catalogue.vendor_code, event_catalogue.group_id").Find(&catalogue).Error
+	if err != nil {
+		eventCatalogueDao.logger.Errorf("<GetEventCatalogue> RequestID %v, Error %v", requestID, err)
+		customError := customErrorPkg.NewCustomError(
+			errors.New(constants.PROCESSING_ERROR),
+			constants.INTERNAL_SERVER_ERROR_CODE,
+			http.StatusInternalServerError,
+		)
+
+		panic(customError)
+	}
+
+	return catalogue
+}
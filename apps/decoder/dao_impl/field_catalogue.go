@@ -0,0 +1,37 @@
+package daoimpl
+
+import (
+	"errors"
+	"net/http"
+	"parsing-service/apps/decoder/models"
+	"parsing-service/constants"
+	customErrorPkg "parsing-service/pkg/custom_error"
+	"parsing-service/pkg/database"
+	"parsing-service/pkg/logger"
+)
+
+type FieldCatalogueImpl struct {
+	logger logger.ILogger
+}
+
+func NewFieldCatalogueDAO(logger logger.ILogger) *FieldCatalogueImpl {
+	return &FieldCatalogueImpl{logger: logger}
+}
+
+func (fieldCatalogueDao *FieldCatalogueImpl) GetFieldCatalogue(requestID string) []models.FieldCatalogue {
+	var catalogue []models.FieldCatalogue
+
+	err := database.DB.Model(&models.FieldCatalogue{}).Order("field_catalogue.field_name, field_catalogue.record_type").Find(&catalogue).Error
+	if err != nil {
+		fieldCatalogueDao.logger.Errorf("<GetFieldCatalogue> RequestID %v, Error %v", requestID, err)
+		customError := customErrorPkg.NewCustomError(
+			errors.New(constants.PROCESSING_ERROR),
+			constants.INTERNAL_SERVER_ERROR_CODE,
+			http.StatusInternalServerError,
+		)
+
+		panic(customError)
+	}
+
+	return catalogue
+}
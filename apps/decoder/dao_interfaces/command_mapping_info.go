@@ -3,6 +3,7 @@ package daointerfaces
 import "parsing-service/apps/decoder/models"
 
 type ICommandMappingDAO interface {
+	GetCommandMappings(requestID string) []models.CommandMapping
 	GetCommandMappingByCmdID(cmdID int, requestID string) []models.CommandMapping
 	GetCommandMappingByCmdName(cmdName string, requestID string)  []models.CommandMapping
 	GetCommandMappingBySwVersionAndCmdID(swVersion string, cmdID int, requestID string) []models.CommandMapping
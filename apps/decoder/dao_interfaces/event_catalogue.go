@@ -0,0 +1,7 @@
+package daointerfaces
+
+import "parsing-service/apps/decoder/models"
+
+type IEventCatalogueDAO interface {
+	GetEventCatalogue(requestID string) []models.EventCatalogue
+}
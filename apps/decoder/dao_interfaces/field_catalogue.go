@@ -0,0 +1,7 @@
+package daointerfaces
+
+import "parsing-service/apps/decoder/models"
+
+type IFieldCatalogueDAO interface {
+	GetFieldCatalogue(requestID string) []models.FieldCatalogue
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// BackfillRequest asks for the missing block-load slots of a meter's day to
+// be read again, raised when the day's completeness falls below the
+// threshold. From and To span the missing slots.
+type BackfillRequest struct {
+	MeterIp      string    `json:"meter_ip"`
+	DcuNo        uint64    `json:"dcu_no"`
+	Day          string    `json:"day"`
+	MissingSlots []int     `json:"missing_slots"`
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	Completeness float64   `json:"completeness"`
+	Threshold    float64   `json:"threshold"`
+	RequestedAt  time.Time `json:"requested_at"`
+}
@@ -9,6 +9,8 @@ type CommandMapping struct {
 	DP          int32
 	ActualCmdID int32
 	GroupID     int32
+	// checksum variant of this command's packets, empty for the group default
+	ChecksumVariant string `gorm:"type:varchar(30)"`
 }
 
 func (CommandMapping) TableName() string {
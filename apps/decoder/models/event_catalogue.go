@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// event categories of the catalogue
+const (
+	EVENT_CATEGORY_POWER   = "power"
+	EVENT_CATEGORY_TAMPER  = "tamper"
+	EVENT_CATEGORY_UNKNOWN = "unknown"
+)
+
+// event states of the catalogue
+const (
+	EVENT_STATE_OCCURRENCE  = "occurrence"
+	EVENT_STATE_RESTORATION = "restoration"
+)
+
+// EVENT_CODE_UNKNOWN is published for vendor codes missing from the
+// catalogue.
+const EVENT_CODE_UNKNOWN = "UNKNOWN"
+
+// EventCatalogue maps the event code a meter logs to the canonical event.
+// Rows with GroupID 0 hold for every firmware group; a row of the meter's
+// group wins over them.
+type EventCatalogue struct {
+	ID          int32  `gorm:"primaryKey"`
+	VendorCode  int32  `gorm:"uniqueIndex:idx_event_catalogue_vendor_code"`
+	GroupID     int32  `gorm:"uniqueIndex:idx_event_catalogue_vendor_code"`
+	EventCode   string `gorm:"type:varchar(50)"`
+	State       string `gorm:"type:varchar(20)"`
+	Category    string `gorm:"type:varchar(30)"`
+	Description string `gorm:"type:varchar(100)"`
+}
+
+func (EventCatalogue) TableName() string {
+	return "event_catalogue"
+}
+
+// MeterEvent is one entry of a meter's event log, published on the meter
+// event topic with the canonical event of its vendor code.
+type MeterEvent struct {
+	MeterIp     string    `json:"meter_ip"`
+	DcuNo       uint64    `json:"dcu_no"`
+	CmdID       int       `json:"cmd_id"`
+	GroupID     int       `json:"group_id"`
+	VendorCode  int       `json:"vendor_code"`
+	EventCode   string    `json:"event_code"`
+	State       string    `json:"state,omitempty"`
+	Category    string    `json:"category"`
+	Description string    `json:"description,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+	ReceivedAt  time.Time `json:"received_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// FieldCatalogue describes a decoded field: the OBIS code of its register,
+// its unit, the scaler taking the decoded value to the unit and the quantity
+// it measures. Rows with an empty RecordType describe the field in every
+// record type; a row of the record's own type wins over them. Meter events
+// are looked up as event_log records and the per-slot interval records as
+// block_load_interval.
+type FieldCatalogue struct {
+	ID           int32  `gorm:"primaryKey"`
+	RecordType   string `gorm:"type:varchar(30);uniqueIndex:idx_field_catalogue_field"`
+	FieldName    string `gorm:"type:varchar(60);uniqueIndex:idx_field_catalogue_field"`
+	ObisCode     string `gorm:"type:varchar(30)"`
+	Unit         string `gorm:"type:varchar(20)"`
+	Scaler       int32
+	QuantityType string `gorm:"type:varchar(50)"`
+}
+
+func (FieldCatalogue) TableName() string {
+	return "field_catalogue"
+}
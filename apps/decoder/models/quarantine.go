@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"parsing-service/pkg/validation"
+)
+
+// QuarantinedReading is a decoded record that failed validation, published
+// on the quarantine topic instead of its own. FailedRules lists the ids of
+// the rules it failed, Violations why.
+type QuarantinedReading struct {
+	RecordType    string                 `json:"record_type"`
+	MeterIp       string                 `json:"meter_ip"`
+	DcuNo         uint64                 `json:"dcu_no"`
+	Category      string                 `json:"category,omitempty"`
+	GroupID       int                    `json:"group_id"`
+	FailedRules   []string               `json:"failed_rules"`
+	Violations    []validation.Violation `json:"violations"`
+	Record        json.RawMessage        `json:"record"`
+	QuarantinedAt time.Time              `json:"quarantined_at"`
+}
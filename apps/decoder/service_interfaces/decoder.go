@@ -2,12 +2,17 @@ package serviceinterfaces
 
 import (
 	"parsing-service/apps/decoder/models"
+	"parsing-service/pkg/dissect"
 )
 
 type IDecoderService interface {
+	GetCommandMappings(requestID string) []models.CommandMapping
 	GetCommandMappingFromCmdID(cmdID int, requestID string) []models.CommandMapping
 	GetCommandMappingFromCmdName(cmdName string, requestID string) []models.CommandMapping
 	GetCommandMappingFromSwVersionAndCmdID(swVersion string, cmdID int, requestID string) []models.CommandMapping
 	GetDeserializeLogicsFromCmdId(cmdID int, requestID string) []models.DeserializeLogics
 	GetDeserializeLogicsFromSwVersionAndCmdID(swVersion string, cmdID int, requestID string) []models.DeserializeLogics
+	GetEventCatalogue(requestID string) []models.EventCatalogue
+	GetFieldCatalogue(requestID string) []models.FieldCatalogue
+	DissectTapPacket(raw []byte, requestID string) *dissect.Node
 }
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"parsing-service/apps/decoder/models"
+	"parsing-service/pkg/blockload"
+	"parsing-service/pkg/tap"
+)
+
+// how long the slots of a meter's day are remembered after its last record;
+// a record after that publishes all its slots again
+const slotStateRetention = 24 * time.Hour
+
+// daySlots is the status of every slot of a meter's day as of its latest
+// record.
+type daySlots struct {
+	statuses   []blockload.SlotStatus
+	recordedAt time.Time
+	seenAt     time.Time
+}
+
+// blockLoadGaps expands block-load records into interval records and keeps,
+// for every meter and day seen in a batch, the summary of its latest record.
+// Only the slots whose status changed since the previous record of the same
+// meter and day are published; a record older than that one publishes
+// nothing. Backfill requests are remembered per meter and day so that one is
+// raised at most once per minimum interval.
+type blockLoadGaps struct {
+	location     *time.Location
+	intervals    [][]byte
+	summaries    map[string]blockload.Completeness
+	slots        map[string]*daySlots
+	lastBackfill map[string]time.Time
+}
+
+// newBlockLoadGaps returns gaps whose days start at midnight in location, UTC
+// when nil.
+func newBlockLoadGaps(location *time.Location) *blockLoadGaps {
+	if location == nil {
+		location = time.UTC
+	}
+	return &blockLoadGaps{
+		location:     location,
+		summaries:    make(map[string]blockload.Completeness),
+		slots:        make(map[string]*daySlots),
+		lastBackfill: make(map[string]time.Time),
+	}
+}
+
+// add expands record. Records of the old layout carry no bitmask and are
+// skipped.
+func (g *blockLoadGaps) add(record tap.BlockLoadParser, now time.Time) error {
+	intervals, err := blockload.Expand(record, g.location)
+	if errors.Is(err, blockload.ErrNoBitMask) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	recordedAt, _ := time.Parse(blockload.DATE_TIME_LAYOUT, record.BlockLoadDateTime)
+	key := record.MeterIp + "|" + intervals[0].Day
+	day, ok := g.slots[key]
+	if ok && recordedAt.Before(day.recordedAt) {
+		return nil
+	}
+	if !ok || len(day.statuses) != len(intervals) {
+		day = &daySlots{statuses: make([]blockload.SlotStatus, len(intervals))}
+		g.slots[key] = day
+	}
+	for i, interval := range intervals {
+		if day.statuses[i] == interval.Status {
+			continue
+		}
+		day.statuses[i] = interval.Status
+		if data, err := json.Marshal(interval); err == nil {
+			g.intervals = append(g.intervals, data)
+		}
+	}
+	day.recordedAt = recordedAt
+	day.seenAt = now
+
+	g.summaries[key] = blockload.Summarize(intervals, recordedAt)
+	return nil
+}
+
+// backfill returns the request for the missing slots of summary, or nil when
+// the day is complete enough or was requested within minInterval.
+func (g *blockLoadGaps) backfill(summary blockload.Completeness, threshold float64, minInterval time.Duration, now time.Time) *models.BackfillRequest {
+	if summary.Missing == 0 || summary.Completeness >= threshold {
+		return nil
+	}
+	key := summary.MeterIp + "|" + summary.Day
+	if last, ok := g.lastBackfill[key]; ok && now.Sub(last) < minInterval {
+		return nil
+	}
+	g.lastBackfill[key] = now
+
+	slotLength := time.Duration(summary.IntervalMinutes) * time.Minute
+	midnight, _ := time.ParseInLocation(blockload.DAY_LAYOUT, summary.Day, g.location)
+	first, last := summary.MissingSlots[0], summary.MissingSlots[len(summary.MissingSlots)-1]
+	return &models.BackfillRequest{
+		MeterIp:      summary.MeterIp,
+		DcuNo:        summary.DcuNo,
+		Day:          summary.Day,
+		MissingSlots: summary.MissingSlots,
+		From:         midnight.Add(time.Duration(first) * slotLength),
+		To:           midnight.Add(time.Duration(last+1) * slotLength),
+		Completeness: summary.Completeness,
+		Threshold:    threshold,
+		RequestedAt:  now,
+	}
+}
+
+// publishBlockLoadGaps publishes the interval records, tagged with the field
+// catalogue, and the day summaries of the batch and the backfill requests
+// they raise.
+func (k *kafkaConusmerHandler) publishBlockLoadGaps() {
+	gaps := k.blockLoadGaps
+	blockLoadCfg := k.cfg.BlockLoadConfig
+	topics := k.cfg.KafkaTopicsConfig
+	now := time.Now()
+	minInterval := time.Duration(blockLoadCfg.BackfillMinIntervalMs) * time.Millisecond
+
+	for key, last := range gaps.lastBackfill {
+		if now.Sub(last) >= minInterval {
+			delete(gaps.lastBackfill, key)
+		}
+	}
+	for key, day := range gaps.slots {
+		if now.Sub(day.seenAt) >= slotStateRetention {
+			delete(gaps.slots, key)
+		}
+	}
+
+	if len(gaps.intervals) > 0 {
+		for i, interval := range gaps.intervals {
+			annotated, err := k.fieldCatalogue.annotate(blockload.RECORD_TYPE_INTERVAL, interval, now)
+			if err != nil {
+				k.logger.Errorf("<publishBlockLoadGaps> interval published unannotated: %v", err)
+				continue
+			}
+			gaps.intervals[i] = annotated
+		}
+		k.logger.Debugf("<publishBlockLoadGaps> %d block load intervals", len(gaps.intervals))
+		go k.KafkaProducer.ProduceMessagesInBatch(topics.PRODUCE_BLOCK_LOAD_INTERVAL_KAFKA_TOPIC_NAME, gaps.intervals)
+		gaps.intervals = nil
+	}
+	if len(gaps.summaries) == 0 {
+		return
+	}
+
+	var summaries, backfills [][]byte
+	for key, summary := range gaps.summaries {
+		if data, err := json.Marshal(summary); err == nil {
+			summaries = append(summaries, data)
+		}
+		if request := gaps.backfill(summary, blockLoadCfg.CompletenessThreshold, minInterval, now); request != nil {
+			if data, err := json.Marshal(request); err == nil {
+				backfills = append(backfills, data)
+			}
+		}
+		delete(gaps.summaries, key)
+	}
+	k.logger.Debugf("<publishBlockLoadGaps> %d block load day summaries", len(summaries))
+	go k.KafkaProducer.ProduceMessagesInBatch(topics.PRODUCE_BLOCK_LOAD_COMPLETENESS_KAFKA_TOPIC_NAME, summaries)
+	if len(backfills) > 0 {
+		k.logger.Debugf("<publishBlockLoadGaps> %d block load backfill requests", len(backfills))
+		go k.KafkaProducer.ProduceMessagesInBatch(topics.PRODUCE_BACKFILL_REQUEST_KAFKA_TOPIC_NAME, backfills)
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"parsing-service/pkg/blockload"
+	"parsing-service/pkg/tap"
+)
+
+func blockLoadRecord(dateTime string, bitMask string) tap.BlockLoadParser {
+	return tap.BlockLoadParser{MeterIp: "10.0.0.1", DcuNo: 7, BlockLoadDateTime: dateTime, BitMaskString: bitMask}
+}
+
+// queuedSlots returns the slots of the queued interval records and clears
+// them.
+func queuedSlots(t *testing.T, g *blockLoadGaps) map[int]blockload.SlotStatus {
+	t.Helper()
+	slots := make(map[int]blockload.SlotStatus)
+	for _, data := range g.intervals {
+		var interval blockload.Interval
+		if err := json.Unmarshal(data, &interval); err != nil {
+			t.Fatal(err)
+		}
+		slots[interval.Slot] = interval.Status
+	}
+	g.intervals = nil
+	return slots
+}
+
+func TestBlockLoadGapsQueueChangedSlots(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	g := newBlockLoadGaps(nil)
+
+	if err := g.add(blockLoadRecord("2024-05-01 07:30:00", "10000000"), now); err != nil {
+		t.Fatal(err)
+	}
+	if slots := queuedSlots(t, g); len(slots) != 8 {
+		t.Fatalf("queued %d slots of the first record, want all 8", len(slots))
+	}
+
+	// slot 1 was read late and slot 2 fell due
+	if err := g.add(blockLoadRecord("2024-05-01 10:30:00", "11000000"), now); err != nil {
+		t.Fatal(err)
+	}
+	slots := queuedSlots(t, g)
+	if len(slots) != 2 || slots[1] != blockload.SLOT_PRESENT || slots[2] != blockload.SLOT_MISSING {
+		t.Fatalf("queued %v, want slot 1 present and slot 2 missing", slots)
+	}
+
+	// the same record again and an older one change nothing
+	g.add(blockLoadRecord("2024-05-01 10:30:00", "11000000"), now)
+	g.add(blockLoadRecord("2024-05-01 07:30:00", "10000000"), now)
+	if slots := queuedSlots(t, g); len(slots) != 0 {
+		t.Fatalf("queued %v for records without news", slots)
+	}
+	if summary := g.summaries["10.0.0.1|2024-05-01"]; summary.Due != 3 {
+		t.Fatalf("summary %+v, want the latest record's", summary)
+	}
+
+	// another day starts with all its slots
+	g.add(blockLoadRecord("2024-05-02 01:00:00", "00000000"), now)
+	if slots := queuedSlots(t, g); len(slots) != 8 {
+		t.Fatalf("queued %d slots of a new day, want 8", len(slots))
+	}
+}
+
+func TestBlockLoadGapsSkipOldLayout(t *testing.T) {
+	g := newBlockLoadGaps(nil)
+	if err := g.add(blockLoadRecord("2024-05-01 07:30:00", ""), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.intervals) != 0 || len(g.summaries) != 0 {
+		t.Fatal("a record without a bitmask was expanded")
+	}
+}
+
+func TestBlockLoadGapsBackfill(t *testing.T) {
+	ist := time.FixedZone("IST", 5*3600+1800)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	summary := blockload.Completeness{
+		MeterIp:         "10.0.0.1",
+		Day:             "2024-05-01",
+		IntervalMinutes: 180,
+		Missing:         2,
+		MissingSlots:    []int{1, 3},
+		Completeness:    0.5,
+	}
+
+	tests := []struct {
+		name     string
+		location *time.Location
+		summary  func(blockload.Completeness) blockload.Completeness
+		wantFrom time.Time
+		wantTo   time.Time
+		wantNone bool
+	}{
+		{
+			name:     "a day below the threshold spans its missing slots",
+			location: time.UTC,
+			wantFrom: time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "the span starts at midnight in the day's zone",
+			location: ist,
+			wantFrom: time.Date(2024, 4, 30, 21, 30, 0, 0, time.UTC),
+			wantTo:   time.Date(2024, 5, 1, 6, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "a day at the threshold is complete enough",
+			location: time.UTC,
+			summary: func(s blockload.Completeness) blockload.Completeness {
+				s.Completeness = 0.9
+				return s
+			},
+			wantNone: true,
+		},
+		{
+			name:     "a day without missing slots raises nothing",
+			location: time.UTC,
+			summary: func(s blockload.Completeness) blockload.Completeness {
+				s.Missing, s.MissingSlots = 0, []int{}
+				return s
+			},
+			wantNone: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := summary
+			if tt.summary != nil {
+				s = tt.summary(s)
+			}
+			request := newBlockLoadGaps(tt.location).backfill(s, 0.9, time.Hour, now)
+			if tt.wantNone {
+				if request != nil {
+					t.Fatalf("request %+v, want none", request)
+				}
+				return
+			}
+			if request == nil {
+				t.Fatal("no backfill request")
+			}
+			if !request.From.Equal(tt.wantFrom) || !request.To.Equal(tt.wantTo) || request.Threshold != 0.9 {
+				t.Fatalf("request %v-%v, want %v-%v", request.From, request.To, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+
+	g := newBlockLoadGaps(nil)
+	if g.backfill(summary, 0.9, time.Hour, now) == nil {
+		t.Fatal("no first backfill request")
+	}
+	if g.backfill(summary, 0.9, time.Hour, now.Add(59*time.Minute)) != nil {
+		t.Fatal("requested again within the minimum interval")
+	}
+	if g.backfill(summary, 0.9, time.Hour, now.Add(time.Hour)) == nil {
+		t.Fatal("not requested again after the minimum interval")
+	}
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	captureConstants "parsing-service/apps/capture/constants"
+	captureModels "parsing-service/apps/capture/models"
+	"parsing-service/pkg/wp"
+)
+
+// recordCapture hands a consumed payload to the capture, which keeps it if
+// its DCU or one of its meters is being captured.
+func (k *kafkaConusmerHandler) recordCapture(gatewayMode string, dcuNumber string, meterIps []string, verdict string, payload []byte) {
+	if !k.capture.Active() {
+		return
+	}
+	k.capture.Record(captureModels.CapturedFrame{
+		Time:        time.Now(),
+		DcuNumber:   dcuNumber,
+		MeterIps:    meterIps,
+		GatewayMode: gatewayMode,
+		Verdict:     verdict,
+		Payload:     payload,
+	})
+}
+
+// wpDcuNumber is the DCU of a WP frame, from its trailer when the frame
+// could be parsed and from the message envelope otherwise.
+func wpDcuNumber(frame wp.Frame, msgMap map[string]interface{}) string {
+	if frame.DcuNumber != 0 {
+		return fmt.Sprint(frame.DcuNumber)
+	}
+	return irdaStreamKey(msgMap)
+}
+
+func captureVerdict(wpErr error, invalidTapPackets [][]byte) string {
+	if wpErr != nil {
+		return captureConstants.VERDICT_INVALID_WP
+	}
+	if len(invalidTapPackets) > 0 {
+		return captureConstants.VERDICT_INVALID_TAP
+	}
+	return captureConstants.VERDICT_OK
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	"parsing-service/apps/decoder/models"
+	decoderIntf "parsing-service/apps/decoder/service_interfaces"
+	"parsing-service/pkg/checksum"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/tap"
+	"time"
+)
+
+const commandChecksumsRequestID = "command-checksums"
+
+// FrameChecksums holds the checksum variants frames are validated with. Tap
+// and Wp are the defaults; command mapping rows and firmware groups can pick
+// another variant from Registry for TAP packets. A WP frame names no command
+// or group, so it is always checked with Wp.
+type FrameChecksums struct {
+	Registry *checksum.Registry
+	Tap      *checksum.Algorithm
+	Wp       *checksum.Algorithm
+}
+
+// NewFrameChecksums builds the registry from the checksum config. An unknown
+// or invalid variant is a configuration error and stops the service.
+func NewFrameChecksums(cfg *config.Configuration, logger logger.ILogger) (*FrameChecksums, error) {
+	registry := checksum.NewRegistry()
+	for _, variant := range cfg.ChecksumConfig.Variants {
+		_, err := registry.Register(checksum.Params{
+			Name:    variant.Name,
+			Width:   variant.Width,
+			Poly:    variant.Poly,
+			Init:    variant.Init,
+			RefIn:   variant.RefIn,
+			RefOut:  variant.RefOut,
+			XorOut:  variant.XorOut,
+			Padding: checksum.Padding(variant.Padding),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("registering checksum variant: %w", err)
+		}
+	}
+	for groupID, variant := range cfg.ChecksumConfig.GroupVariants {
+		if err := registry.AssignGroup(groupID, variant); err != nil {
+			return nil, fmt.Errorf("checksum of firmware group %d: %w", groupID, err)
+		}
+	}
+
+	tapAlgorithm, err := registry.Get(cfg.ChecksumConfig.TapVariant)
+	if err != nil {
+		return nil, fmt.Errorf("tap checksum: %w", err)
+	}
+	wpAlgorithm, err := registry.Get(cfg.ChecksumConfig.WpVariant)
+	if err != nil {
+		return nil, fmt.Errorf("wp checksum: %w", err)
+	}
+	logger.Infof("validating TAP frames with %s and WP frames with %s", tapAlgorithm.Name, wpAlgorithm.Name)
+
+	return &FrameChecksums{Registry: registry, Tap: tapAlgorithm, Wp: wpAlgorithm}, nil
+}
+
+// ForCommandMapping returns the variant for packets of a mapping row: the
+// row's own variant if it names one, else its firmware group's, else the TAP
+// default.
+func (c *FrameChecksums) ForCommandMapping(mapping models.CommandMapping) *checksum.Algorithm {
+	if mapping.ChecksumVariant != "" {
+		if alg, err := c.Registry.Get(mapping.ChecksumVariant); err == nil {
+			return alg
+		}
+	}
+	return c.Registry.ForGroup(int(mapping.GroupID), c.Tap)
+}
+
+// ForCommandMappings returns the variants of the mapping rows of a command,
+// each once and in row order, or the TAP default when there are no rows.
+func (c *FrameChecksums) ForCommandMappings(mappings []models.CommandMapping) []*checksum.Algorithm {
+	var algs []*checksum.Algorithm
+	for _, mapping := range mappings {
+		if alg := c.ForCommandMapping(mapping); !containsAlgorithm(algs, alg) {
+			algs = append(algs, alg)
+		}
+	}
+	if len(algs) == 0 {
+		return []*checksum.Algorithm{c.Tap}
+	}
+	return algs
+}
+
+// commandChecksums picks the checksum variants of TAP packets by the command
+// they answer: the variants of the command's mapping rows, see
+// ForCommandMapping, or the TAP default for commands without rows. The rows
+// are read again every refreshInterval.
+type commandChecksums struct {
+	checksums       *FrameChecksums
+	decoder         decoderIntf.IDecoderService
+	refreshInterval time.Duration
+	logger          logger.ILogger
+
+	byCmdID  map[int][]*checksum.Algorithm
+	loadedAt time.Time
+}
+
+func newCommandChecksums(checksums *FrameChecksums, decoder decoderIntf.IDecoderService, refreshInterval time.Duration, logger logger.ILogger) *commandChecksums {
+	return &commandChecksums{checksums: checksums, decoder: decoder, refreshInterval: refreshInterval, logger: logger}
+}
+
+// pick is a tap.ChecksumPicker. When the rows of a command name several
+// variants, for meters of different groups, a packet may check out with any
+// of them.
+func (c *commandChecksums) pick(header *tap.TAPPacket) []*checksum.Algorithm {
+	cmdID, err := header.CmdID()
+	if err != nil {
+		return []*checksum.Algorithm{c.checksums.Tap}
+	}
+	if now := time.Now(); c.byCmdID == nil || now.Sub(c.loadedAt) >= c.refreshInterval {
+		c.refresh(now)
+	}
+	if algs, ok := c.byCmdID[cmdID]; ok {
+		return algs
+	}
+	return []*checksum.Algorithm{c.checksums.Tap}
+}
+
+// refresh reads the command mapping rows, keeping the variants already known
+// when the table cannot be read.
+func (c *commandChecksums) refresh(now time.Time) {
+	c.loadedAt = now
+	defer func() {
+		if rec := recover(); rec != nil {
+			c.logger.Errorf("<commandChecksums> keeping the command checksums: %v", rec)
+			if c.byCmdID == nil {
+				c.byCmdID = make(map[int][]*checksum.Algorithm)
+			}
+		}
+	}()
+
+	mappings := make(map[int][]models.CommandMapping)
+	for _, mapping := range c.decoder.GetCommandMappings(commandChecksumsRequestID) {
+		mappings[int(mapping.CmdID)] = append(mappings[int(mapping.CmdID)], mapping)
+	}
+	byCmdID := make(map[int][]*checksum.Algorithm, len(mappings))
+	for cmdID, rows := range mappings {
+		byCmdID[cmdID] = c.checksums.ForCommandMappings(rows)
+	}
+	c.byCmdID = byCmdID
+}
+
+func containsAlgorithm(algs []*checksum.Algorithm, alg *checksum.Algorithm) bool {
+	for _, known := range algs {
+		if known == alg {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"parsing-service/apps/decoder/models"
+	decoderIntf "parsing-service/apps/decoder/service_interfaces"
+	"parsing-service/pkg/checksum"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/tap"
+)
+
+// mappingDecoder serves command mapping rows, the other methods are not
+// used by the tests.
+type mappingDecoder struct {
+	decoderIntf.IDecoderService
+	mappings []models.CommandMapping
+}
+
+func (d *mappingDecoder) GetCommandMappings(requestID string) []models.CommandMapping {
+	return d.mappings
+}
+
+func (d *mappingDecoder) GetCommandMappingFromCmdName(cmdName string, requestID string) []models.CommandMapping {
+	var mappings []models.CommandMapping
+	for _, mapping := range d.mappings {
+		if mapping.CmdName == cmdName {
+			mappings = append(mappings, mapping)
+		}
+	}
+	return mappings
+}
+
+func (d *mappingDecoder) GetDeserializeLogicsFromCmdId(cmdID int, requestID string) []models.DeserializeLogics {
+	return nil
+}
+
+func TestCommandChecksumsPickByCommand(t *testing.T) {
+	cfg := &config.Configuration{}
+	cfg.ChecksumConfig.TapVariant = checksum.CRC16_XMODEM_EVEN_NAME
+	cfg.ChecksumConfig.WpVariant = checksum.CRC16_XMODEM_EVEN_NAME
+	cfg.ChecksumConfig.GroupVariants = map[int]string{2: checksum.CRC32_NAME}
+	log := logger.NewLogger()
+	checksums, err := NewFrameChecksums(cfg, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := &mappingDecoder{mappings: []models.CommandMapping{
+		{CmdID: 0x0201, GroupID: 1, ChecksumVariant: checksum.CRC16_MODBUS_NAME},
+		{CmdID: 0x0202, GroupID: 1},
+		{CmdID: 0x0202, GroupID: 2},
+	}}
+	c := newCommandChecksums(checksums, decoder, time.Hour, log)
+
+	for _, tc := range []struct {
+		destAddr string
+		want     []*checksum.Algorithm
+	}{
+		{"0.0.2.1", []*checksum.Algorithm{checksum.CRC16_MODBUS}},
+		{"0.0.2.2", []*checksum.Algorithm{checksums.Tap, checksum.CRC32}},
+		{"0.0.2.3", []*checksum.Algorithm{checksums.Tap}},
+	} {
+		header := tap.NewTAPPacket()
+		header.DestAddr = tap.NewAddress1(tc.destAddr)
+		got := c.pick(header)
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: picked %d variant(s), want %d", tc.destAddr, len(got), len(tc.want))
+		}
+		for i := range got {
+			if got[i].Name != tc.want[i].Name {
+				t.Fatalf("%s: variant %d is %s, want %s", tc.destAddr, i, got[i].Name, tc.want[i].Name)
+			}
+		}
+	}
+}
@@ -1,19 +1,29 @@
 package services
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	captureConstants "parsing-service/apps/capture/constants"
+	captureIntf "parsing-service/apps/capture/service_interfaces"
+	clockIntf "parsing-service/apps/clock/service_interfaces"
 	"parsing-service/apps/decoder/constants"
+	decoderIntf "parsing-service/apps/decoder/service_interfaces"
+	downlinkIntf "parsing-service/apps/downlinks/service_interfaces"
 	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	otapIntf "parsing-service/apps/otap/service_interfaces"
+	presenceModels "parsing-service/apps/presence/models"
+	presenceIntf "parsing-service/apps/presence/service_interfaces"
+	sinkIntf "parsing-service/apps/sinks/service_interfaces"
+	topologyIntf "parsing-service/apps/topology/service_interfaces"
 	"parsing-service/pkg/config"
+	"parsing-service/pkg/irda"
 	"parsing-service/pkg/logger"
-	"strconv"
-	"strings"
+	"time"
 
-	// tap "parsing-service/pkg/tap"
+	"parsing-service/pkg/tap"
+	"parsing-service/pkg/wp"
 
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 )
@@ -23,6 +33,29 @@ type kafkaConusmerHandler struct {
 	ConsumerFactory kafkaIntf.IKafkaConsumerFactory
 	logger          logger.ILogger
 	KafkaProducer   kafkaIntf.IKafkaProducer
+	irdaReassembler *irda.Reassembler
+	checksums       *FrameChecksums
+	tapChecksums    *commandChecksums
+	capture         captureIntf.ICaptureService
+	otapTracker     otapIntf.IOtapTracker
+	sinkRegistry    sinkIntf.ISinkRegistry
+	topology        topologyIntf.ITopologyService
+	downlinks       downlinkIntf.IDownlinkStore
+	clockTracker    clockIntf.IClockTracker
+	presence        presenceIntf.IPresenceRegistry
+	decoder         decoderIntf.IDecoderService
+	payloadParsers  *payloadParsers
+	eventCatalogue  *eventCatalogue
+	fieldCatalogue  *fieldCatalogue
+	blockLoadGaps   *blockLoadGaps
+	validator       *ReadingValidator
+
+	segmentReassembler *tap.SegmentReassembler
+	incompleteTapSets  [][]byte
+	blockLoads         [][]byte
+	meterReadings      [][]byte
+	meterEvents        [][]byte
+	quarantined        [][]byte
 }
 
 func NewKafkaConsumerHandler(
@@ -30,12 +63,45 @@ func NewKafkaConsumerHandler(
 	ConsumerFactory kafkaIntf.IKafkaConsumerFactory,
 	logger logger.ILogger,
 	KafkaProducer kafkaIntf.IKafkaProducer,
+	checksums *FrameChecksums,
+	capture captureIntf.ICaptureService,
+	otapTracker otapIntf.IOtapTracker,
+	sinkRegistry sinkIntf.ISinkRegistry,
+	topology topologyIntf.ITopologyService,
+	downlinks downlinkIntf.IDownlinkStore,
+	clockTracker clockIntf.IClockTracker,
+	presence presenceIntf.IPresenceRegistry,
+	decoder decoderIntf.IDecoderService,
+	validator *ReadingValidator,
 ) *kafkaConusmerHandler {
+	refreshInterval := time.Duration(cfg.PayloadParserConfig.RefreshIntervalMs) * time.Millisecond
+	tapChecksums := newCommandChecksums(checksums, decoder, refreshInterval, logger)
 	return &kafkaConusmerHandler{
 		cfg:             cfg,
 		ConsumerFactory: ConsumerFactory,
 		logger:          logger,
 		KafkaProducer:   KafkaProducer,
+		irdaReassembler: irda.NewReassembler(tapChecksums.pick),
+		checksums:       checksums,
+		tapChecksums:    tapChecksums,
+		capture:         capture,
+		otapTracker:     otapTracker,
+		sinkRegistry:    sinkRegistry,
+		topology:        topology,
+		downlinks:       downlinks,
+		clockTracker:    clockTracker,
+		presence:        presence,
+		decoder:         decoder,
+		payloadParsers:  newPayloadParsers(decoder, cfg.PayloadParserConfig, logger),
+		eventCatalogue:  newEventCatalogue(decoder, refreshInterval, logger),
+		fieldCatalogue:  newFieldCatalogue(decoder, cfg.FieldCatalogueConfig, refreshInterval, logger),
+		blockLoadGaps:   newBlockLoadGaps(cfg.BlockLoadConfig.DayLocation),
+		validator:       validator,
+		segmentReassembler: tap.NewSegmentReassembler(
+			cfg.TapSegmentConfig.SegmentedCmdIDs,
+			time.Duration(cfg.TapSegmentConfig.TimeoutMs)*time.Millisecond,
+			cfg.TapSegmentConfig.MaxPendingSets,
+		),
 	}
 }
 
@@ -46,14 +112,15 @@ func (k *kafkaConusmerHandler) FetchData() {
 
 	}
 
-	fetchDataChan := make(chan []*kafka.Message, 100)
+	fetchDataChan := make(chan []*kafka.Message, k.cfg.KafkaConsumerConfig.ChannelSize)
+	batchSizer := k.ConsumerFactory.CreateBatchSizer()
 
-	go k.startFetchDataKafkaConsumer(k.cfg.KafkaTopicsConfig.FETCH_DATA_KAFKA_TOPIC_NAME, fetchDataConsumer, fetchDataChan)
-	go k.processMessages(fetchDataChan, fetchDataConsumer)
+	go k.startFetchDataKafkaConsumer(k.cfg.KafkaTopicsConfig.FETCH_DATA_KAFKA_TOPIC_NAME, fetchDataConsumer, fetchDataChan, batchSizer)
+	go k.processMessages(fetchDataChan, fetchDataConsumer, batchSizer)
 
 }
 
-func (k *kafkaConusmerHandler) startFetchDataKafkaConsumer(topic string, consumer kafkaIntf.IKafkaConsumer, msgChannel chan<- []*kafka.Message) {
+func (k *kafkaConusmerHandler) startFetchDataKafkaConsumer(topic string, consumer kafkaIntf.IKafkaConsumer, msgChannel chan<- []*kafka.Message, batchSizer kafkaIntf.IBatchSizer) {
 
 	err := consumer.Subscribe([]string{topic})
 	if err != nil {
@@ -62,8 +129,8 @@ func (k *kafkaConusmerHandler) startFetchDataKafkaConsumer(topic string, consume
 	}
 
 	for {
-
-		messages, err := consumer.PollBatch(context.Background(), 100, 2000, topic)
+		batchSize, maxWaitMs := batchSizer.Next()
+		messages, err := consumer.PollBatch(context.Background(), batchSize, maxWaitMs, topic)
 		// fmt.Println(len(messages))
 		if err != nil {
 			k.logger.Errorf(constants.ERR_CONSUMING_FROM_KAFKA, err)
@@ -75,17 +142,21 @@ func (k *kafkaConusmerHandler) startFetchDataKafkaConsumer(topic string, consume
 }
 
 var invalidWpPackets [][]byte
+var unsupportedWpPackets [][]byte
 
-func (k *kafkaConusmerHandler) processMessages(fetchDataChan <-chan []*kafka.Message, consumer kafkaIntf.IKafkaConsumer) {
+func (k *kafkaConusmerHandler) processMessages(fetchDataChan <-chan []*kafka.Message, consumer kafkaIntf.IKafkaConsumer, batchSizer kafkaIntf.IBatchSizer) {
 
 	fmt.Println(len(fetchDataChan))
 	for messages := range fetchDataChan {
+		startTime := time.Now()
 		if len(messages) > 0 {
-			unmarshalMessages := k.unmarshalKafkaMessages(messages)
+			unmarshalMessages, receivedAt := k.unmarshalKafkaMessages(messages)
+			parsedMessages := parseMessages(unmarshalMessages, k.checksums.Wp)
 
-			for i := range unmarshalMessages {
+			for i := range parsedMessages {
 				fmt.Println("message value is: ", unmarshalMessages[i])
-				k.processPackets(unmarshalMessages[i])
+				parsedMessages[i].receivedAt = receivedAt[i]
+				k.processPackets(parsedMessages[i])
 			}
 
 			offset, err := consumer.CommitSyncBatch(messages)
@@ -93,12 +164,28 @@ func (k *kafkaConusmerHandler) processMessages(fetchDataChan <-chan []*kafka.Mes
 				k.logger.Errorf(constants.ERR_COMMITTING_OFFSET_SYNC, err, offset)
 			}
 			go k.KafkaProducer.ProduceMessagesInBatch(k.cfg.KafkaTopicsConfig.PRODUCE_INVALID_WP_KAFKA_TOPIC_NAME, invalidWpPackets)
+			invalidWpPackets = nil
+			go k.KafkaProducer.ProduceMessagesInBatch(k.cfg.KafkaTopicsConfig.PRODUCE_UNSUPPORTED_WP_KAFKA_TOPIC_NAME, unsupportedWpPackets)
+			unsupportedWpPackets = nil
 		}
+		k.expireTapSegments(time.Now())
+		k.otapTracker.Flush()
+		k.sinkRegistry.Flush()
+		k.topology.Flush()
+		k.downlinks.Flush()
+		k.clockTracker.Flush()
+		k.presence.Flush()
+		k.publishDecodedPayloads()
+		k.publishBlockLoadGaps()
+		batchSizer.Observe(len(messages), time.Since(startTime), len(fetchDataChan), cap(fetchDataChan))
 	}
 }
 
-func (k *kafkaConusmerHandler) unmarshalKafkaMessages(messages []*kafka.Message) []interface{} {
+// unmarshalKafkaMessages decodes the messages of a batch, skipping those that
+// are not JSON, and returns with them the time each reached the broker.
+func (k *kafkaConusmerHandler) unmarshalKafkaMessages(messages []*kafka.Message) ([]interface{}, []time.Time) {
 	var kafkaMessages []interface{}
+	var receivedAt []time.Time
 
 	for i := range messages {
 		msg := messages[i]
@@ -109,8 +196,18 @@ func (k *kafkaConusmerHandler) unmarshalKafkaMessages(messages []*kafka.Message)
 			continue
 		}
 		kafkaMessages = append(kafkaMessages, singleMsg)
+		receivedAt = append(receivedAt, brokerTimestamp(msg))
+	}
+	return kafkaMessages, receivedAt
+}
+
+// brokerTimestamp is the timestamp the broker keeps for msg, now if it has
+// none.
+func brokerTimestamp(msg *kafka.Message) time.Time {
+	if msg.TimestampType == kafka.TimestampNotAvailable || msg.Timestamp.IsZero() {
+		return time.Now()
 	}
-	return kafkaMessages
+	return msg.Timestamp
 }
 
 func marshalPacket(packet map[string]interface{}) []byte {
@@ -144,58 +241,61 @@ func ConvertPayloadToBytes(payload interface{}) ([]byte, error) {
 	return byteSlice, nil
 }
 
-func (k *kafkaConusmerHandler) processPackets(msg interface{}) {
-	msgMap, ok := msg.(map[string]interface{})
-	if !ok {
-		fmt.Println("Error: message is not a map", ok)
+func (k *kafkaConusmerHandler) processPackets(parsed parsedMessage) {
+	if parsed.err != nil {
+		fmt.Println("Error in payload of message", parsed.err)
 		return
 	}
+	msgMap := parsed.msgMap
+	payload := parsed.payload
 
-	payload, err := ConvertPayloadToBytes(msgMap["payload"])
-	if err != nil {
-		fmt.Println("Error in byte conversion of payload", err)
-	}
-
-	fmt.Println((msgMap["payload"].([]interface{})[0].(float64)))
-
-	if payload[0] == 0xFE {
+	if parsed.isWp {
 		// return
 		var invalidTapPackets [][]byte
+		var meterIps []string
 
-		payload, err := ConvertPayloadToBytes(msgMap["payload"])
-		if err != nil {
-			fmt.Println("Error in byte conversion of payload", err)
+		if k.dissecting() {
+			k.printDissection(k.dissectWpFrame(payload))
 		}
-		wpInfoPackets, err := k.getTwUplinkPackets(payload)
-		if err != nil {
-			fmt.Println("WP Packet Issue", err)
-			invalidWpPackets = append(invalidWpPackets, payload)
+
+		if errors.Is(parsed.wpErr, wp.ErrUnsupportedVersion) {
+			// nothing in the frame can be read, park it until a parser for its version exists
+			fmt.Println("WP Packet Issue", parsed.wpErr)
+			unsupportedWpPackets = append(unsupportedWpPackets, marshalPacket(map[string]interface{}{
+				"reason":    parsed.wpErr.Error(),
+				"sinkId":    parsed.wpFrame.SinkID,
+				"DcuTime":   parsed.wpFrame.DcuTime,
+				"DcuNumber": parsed.wpFrame.DcuNumber,
+				"payload":   payload,
+			}))
+			k.recordCapture("wp", wpDcuNumber(parsed.wpFrame, msgMap), nil, captureConstants.VERDICT_UNSUPPORTED_VERSION, payload)
 			return
 		}
+		if parsed.wpErr != nil {
+			// sub-messages parsed before or after a broken one are still processed
+			fmt.Println("WP Packet Issue", parsed.wpErr)
+			invalidWpPackets = append(invalidWpPackets, payload)
+		}
+		k.clockTracker.Observe(parsed.wpFrame, parsed.receivedAt)
+		dcuID := wpDcuNumber(parsed.wpFrame, msgMap)
+		sinkID := int16(parsed.wpFrame.SinkID)
+		k.presence.SeeDcu(presenceModels.DcuSighting{DcuID: dcuID, GatewayMode: "wp", SinkID: &sinkID, At: parsed.receivedAt})
+		wpInfoPackets := k.getTwUplinkPackets(parsed.wpFrame)
 
 		fmt.Println("Total No of tap packets found from WP_UNWRAP", len(wpInfoPackets))
 		if len(wpInfoPackets) > 0 {
-			for _, tempPacket := range wpInfoPackets {
-				wpTapPacket := tempPacket["TAP"].([]byte)
-				// wpTapPacketBytes, err := ConvertPayloadToBytes(wpTapPacket)
-				// if err != nil {
-				// 	fmt.Println("Error in byte conversion of wpTapPackets", err)
-				// 	continue
-				// }
+			for _, uplink := range wpInfoPackets {
+				wpTapPacket := uplink.Uplink.Payload
 
 				//handling meta-data
 				msgMap["gatewayMode"] = "wp"
-				if sinkID, ok := tempPacket["sinkId"]; ok {
-					msgMap["sinkId"] = sinkID
-				} else {
-					msgMap["sinkId"] = 1
-				}
-				// dcuTime := tempPacket["DcuTime"]
+				msgMap["sinkId"] = parsed.wpFrame.SinkID
+				// dcuTime := parsed.wpFrame.DcuTime
 
 				offset := 0
-				dcuPort := int(tempPacket["DcuNumber"].(uint8))
+				dcuPort := int(parsed.wpFrame.DcuNumber)
 
-				packetIntegrityFlag, err := checkPacketIntegrity(wpTapPacket, offset, dcuPort)
+				packetIntegrityFlag, err := checkPacketIntegrity(wpTapPacket, offset, dcuPort, k.tapChecksums.pick)
 
 				if packetIntegrityFlag == true && err == nil {
 					if len(wpTapPacket) > 0 {
@@ -206,10 +306,11 @@ func (k *kafkaConusmerHandler) processPackets(msg interface{}) {
 						fmt.Printf("Error in getting tap packet: %v", err)
 						continue
 					}
+					meterIps = append(meterIps, myTapPacket.SrcAddr.String())
+					k.seeWpMeter(myTapPacket, parsed.wpFrame, uplink, dcuID, parsed.receivedAt)
 
-					ok := getCmdIDAndMeterIp(myTapPacket)
-					if ok != nil {
-						fmt.Println("Error in getting CmdID and MeterIp", ok)
+					if err := k.handleTapPacket(myTapPacket, parsed.wpFrame.DcuNumber); err != nil {
+						invalidTapPackets = append(invalidTapPackets, uplink.Uplink.Payload)
 					}
 				} else {
 					invalidTapPackets = append(invalidTapPackets, wpTapPacket)
@@ -218,134 +319,136 @@ func (k *kafkaConusmerHandler) processPackets(msg interface{}) {
 			}
 			go k.KafkaProducer.ProduceMessagesInBatch(k.cfg.KafkaTopicsConfig.PRODUCE_INVALID_TAP_KAFKA_TOPIC_NAME, invalidTapPackets)
 		}
+		k.recordCapture("wp", wpDcuNumber(parsed.wpFrame, msgMap), meterIps, captureVerdict(parsed.wpErr, invalidTapPackets), payload)
 	} else { // irda gateway mode
-		// return
-		// payload, err := GetBytes(msgMap["payload"])
-
 		var invalidTapPackets [][]byte
-		payload, err := ConvertPayloadToBytes(msgMap["payload"])
-		if err != nil {
-			fmt.Println("Error in byte conversion of payload", err)
+		var meterIps []string
+
+		// the payload is a chunk of the dcu's irda stream, frames may continue in its next message
+		// unless its dcu is unknown, then it is scanned on its own
+		dcuID := irdaStreamKey(msgMap)
+		var scanned irda.ScanResult
+		if dcuID == "" {
+			scanned = k.irdaReassembler.FeedUnkeyed(payload)
+		} else {
+			k.presence.SeeDcu(presenceModels.DcuSighting{DcuID: dcuID, GatewayMode: "irda", At: parsed.receivedAt})
+			scanned = k.irdaReassembler.Feed(dcuID, payload, time.Now())
 		}
-		splittedPayload := bytes.Split(payload, []byte("RECT"))
-		fmt.Println("-----------------")
-		fmt.Println(len(splittedPayload))
-		for _, part := range splittedPayload {
-			// dcuPort, offset := getDcuPortAndOffset(part)
-			dcuPort := 0
-			offset := 4
-			packetIntegrityFlag, err := checkPacketIntegrity(part, offset, dcuPort)
-
-			if packetIntegrityFlag == true && err == nil {
-				if len(part) > 0 {
-					part = part[1:]
-				}
-				myTapPacket, err := getMyTapPacket(part, offset)
-				if err != nil {
-					fmt.Printf("Error in getting tap packet: %v", err)
-					continue
-				}
+		k.logger.Debugf("<processPackets> %d TAP frame(s) scanned from dcu %q", len(scanned.Frames), dcuID)
+		invalidTapPackets = append(invalidTapPackets, scanned.Invalid...)
+		for _, frame := range scanned.Frames {
+			if k.dissecting() {
+				k.printDissection(k.dissectTapPacket(frame.Raw))
+			}
+			msgMap["gatewayMode"] = "irda"
+			msgMap["dcuPort"] = frame.DcuPort
 
-				ok := getCmdIDAndMeterIp(myTapPacket)
-				if ok != nil {
-					fmt.Println("Error in getting CmdID and MeterIp", ok)
-				}
-			} else {
-				// publish on Invalid TAP packet topic
-				invalidTapPackets = append(invalidTapPackets, part)
-				fmt.Println("TAP Packet Integrity fail!", err)
+			offset := 4
+			myTapPacket, err := getMyTapPacket(frame.Raw[1:], offset)
+			if err != nil {
+				fmt.Printf("Error in getting tap packet: %v", err)
+				continue
 			}
+			meterIps = append(meterIps, myTapPacket.SrcAddr.String())
+			k.seeIrdaMeter(myTapPacket, dcuID, parsed.receivedAt)
 
+			if err := k.handleTapPacket(myTapPacket, irdaDcuNumber(dcuID)); err != nil {
+				invalidTapPackets = append(invalidTapPackets, frame.Raw)
+			}
+		}
+		if len(scanned.Invalid) > 0 {
+			k.logger.Errorf("<processPackets> TAP packet integrity fail, %d run(s) of bytes outside a valid frame", len(scanned.Invalid))
 		}
 		go k.KafkaProducer.ProduceMessagesInBatch(k.cfg.KafkaTopicsConfig.PRODUCE_INVALID_TAP_KAFKA_TOPIC_NAME, invalidTapPackets)
+		k.recordCapture("irda", irdaStreamKey(msgMap), meterIps, captureVerdict(nil, invalidTapPackets), payload)
 	}
 
 }
 
-func getCmdIDAndMeterIp(packet *TAPPacket) error {
-	meterIp := packet.SrcAddr
-
-	parts := strings.Split(packet.DestAddr.String(), ".")
-	if len(parts) < 3 {
-		return errors.New("invalid address format")
-	}
-	fmt.Println(parts)
-	part0, err0 := strconv.Atoi(parts[0])
-	part1, err1 := strconv.Atoi(parts[1])
-	part2, err2 := strconv.Atoi(parts[2])
-	part3, err3 := strconv.Atoi(parts[3])
-	var cmdIDBytes []byte
-
-	if packet.DestPort == 4 {
-		if err1 != nil || err2 != nil {
-			return errors.New("Error converting parts to integers")
+// irdaStreamKey names the dcu a message came from, partial frames are only
+// ever joined with the next message of the same dcu.
+func irdaStreamKey(msgMap map[string]interface{}) string {
+	for _, key := range constants.IRDA_DCU_KEYS {
+		if value, ok := msgMap[key]; ok && value != nil {
+			return fmt.Sprint(value)
 		}
-		cmdIDBytes = []byte{byte(part1), byte(part2)}
-	} else if packet.DestPort == 219 {
-		if err1 != nil || err2 != nil || err0 != nil || err3 != nil {
-			return errors.New("Error converting parts to integers")
-		}
-		cmdIDBytes = []byte{byte(part0), byte(part1), byte(part2), byte(part3)}
-	} else {
-		if err2 != nil || err3 != nil {
-			return errors.New("Error converting parts to integers")
-		}
-		cmdIDBytes = []byte{byte(part2), byte(part3)}
 	}
+	return ""
+}
 
-	cmdID, err := DeserializeUInt16(cmdIDBytes, "normal")
+func getCmdIDAndMeterIp(packet *tap.TAPPacket) (string, int, error) {
+	meterIp := packet.SrcAddr.String()
+
+	cmdID, err := packet.CmdID()
 	if err != nil {
 		fmt.Println("Error in deserializing command ID")
-		return err
+		return "", 0, err
 	}
 
 	fmt.Printf("Meter-Ip is: %v\n", meterIp)
 	fmt.Printf("Command Id is: %v\n", cmdID)
 
+	return meterIp, cmdID, nil
+
+}
+
+// handleTapPacket passes the data of a validated TAP packet from DCU
+// dcuNumber on to decoding. Packets of segmented responses are held back
+// until the whole response is in. The error is set when the packet cannot be
+// used at all.
+func (k *kafkaConusmerHandler) handleTapPacket(packet *tap.TAPPacket, dcuNumber uint32) error {
+	meterIp, cmdID, err := getCmdIDAndMeterIp(packet)
+	if err != nil {
+		fmt.Println("Error in getting CmdID and MeterIp", err)
+		return err
+	}
+
+	if !k.segmentReassembler.IsSegmented(cmdID) {
+		return k.decodeTapPayload(meterIp, cmdID, packet.SrcPort, dcuNumber, packet.Data)
+	}
+
+	payload, complete, dropped, err := k.segmentReassembler.Add(tap.SegmentKey{MeterIp: meterIp, CmdID: cmdID}, packet.Data, time.Now())
+	k.queueIncompleteTapSets(dropped)
+	if err != nil {
+		k.logger.Errorf("<handleTapPacket> TAP segment of meter %s command %d: %v", meterIp, cmdID, err)
+		return err
+	}
+	if complete {
+		return k.decodeTapPayload(meterIp, cmdID, packet.SrcPort, dcuNumber, payload)
+	}
 	return nil
+}
 
+// decodeTapPayload receives the complete data of a response, joined back
+// together when it spanned several packets, and decodes it with the parser
+// bound to its command.
+func (k *kafkaConusmerHandler) decodeTapPayload(meterIp string, cmdID int, srcPort uint8, dcuNumber uint32, payload []byte) error {
+	k.logger.Debugf("<decodeTapPayload> meter %s command %d payload of %d byte(s)", meterIp, cmdID, len(payload))
+	return k.decodePayload(meterIp, cmdID, srcPort, dcuNumber, payload)
 }
 
-func (k *kafkaConusmerHandler) getTwUplinkPackets(data []byte) ([]map[string]interface{}, error) {
-	twPacket := NewTapWrapperPacket()
-	var tapPackets []map[string]interface{}
-	var dcuDiagnosticPackets [][]byte
-	var downAckPackets [][]byte
-	var sinkChangePackets [][]byte
-
-	isValid := twPacket.validateUplinkPacket(data)
-
-	if isValid {
-		dataLen := len(data) - 10 // dcu time, dcu serial and CRC length removed
-		index := 4                // wp header length
-		// dataLen := 2
-		// index :=0
-
-		for dataLen > index {
-			newIndex, tempPacket := twPacket.deframeUplinkPacket(data, index)
-			index += newIndex
-			if tempPacket["MessageType"] == "Uplink_Msg" {
-				tapPackets = append(tapPackets, tempPacket)
-			} else if tempPacket["MessageType"] == "DCU_Diag" {
-				dcuDiagnosticPackets = append(dcuDiagnosticPackets, marshalPacket(tempPacket))
-			} else if tempPacket["MessageType"] == "Down_Ack" {
-				downAckPackets = append(downAckPackets, marshalPacket(tempPacket))
-			} else if tempPacket["MessageType"] == "Sink_Change" {
-				sinkChangePackets = append(sinkChangePackets, marshalPacket(tempPacket))
-			}
-			st := fmt.Sprintf("dataLen %d, Index %d  info %v", dataLen, index, tempPacket)
-			fmt.Println(st)
-		}
-		//1
-		go k.KafkaProducer.ProduceMessagesInBatch(k.cfg.KafkaTopicsConfig.PRODUCE_DCU_DIAGNOSTIC_KAFKA_TOPIC_NAME, dcuDiagnosticPackets)
-		//2
-		go k.KafkaProducer.ProduceMessagesInBatch(k.cfg.KafkaTopicsConfig.PRODUCE_DOWN_ACK_KAFKA_TOPIC_NAME, downAckPackets)
-		//3
-		go k.KafkaProducer.ProduceMessagesInBatch(k.cfg.KafkaTopicsConfig.PRODUCE_SINK_CHANGE_KAFKA_TOPIC_NAME, sinkChangePackets)
-
-		fmt.Println(len(tapPackets))
-		return tapPackets, nil
+// expireTapSegments publishes the responses that stopped receiving packets,
+// together with the ones dropped while processing the batch.
+func (k *kafkaConusmerHandler) expireTapSegments(now time.Time) {
+	k.queueIncompleteTapSets(k.segmentReassembler.Expire(now))
+	if len(k.incompleteTapSets) == 0 {
+		return
 	}
-	return nil, errors.New("invalid wp packet")
+	k.logger.Debugf("<expireTapSegments> publishing %d incomplete TAP response(s)", len(k.incompleteTapSets))
+	go k.KafkaProducer.ProduceMessagesInBatch(k.cfg.KafkaTopicsConfig.PRODUCE_INCOMPLETE_TAP_KAFKA_TOPIC_NAME, k.incompleteTapSets)
+	k.incompleteTapSets = nil
+}
 
+func (k *kafkaConusmerHandler) queueIncompleteTapSets(sets []tap.IncompleteSegmentSet) {
+	for _, set := range sets {
+		k.incompleteTapSets = append(k.incompleteTapSets, marshalPacket(map[string]interface{}{
+			"meterIp":          set.Key.MeterIp,
+			"cmdId":            set.Key.CmdID,
+			"totalSegments":    set.Total,
+			"receivedSegments": set.Received,
+			"segments":         set.Segments,
+			"firstSeen":        set.FirstSeen,
+			"reason":           set.Reason,
+		}))
+	}
 }
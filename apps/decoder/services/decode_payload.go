@@ -0,0 +1,120 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"parsing-service/apps/decoder/models"
+	"parsing-service/pkg/payload"
+	"parsing-service/pkg/tap"
+)
+
+// decodePayload parses the response of a meter with the parser of its
+// command, validates the record and tags its catalogued fields with their
+// OBIS codes. Valid block loads are queued for the block-load topic and
+// their slots for the interval topic, other valid records for the meter
+// reading topic; the rest are quarantined. Event logs
+// are not readings and skip validation, a tamper logged by a meter with a
+// wrong clock still has to reach revenue protection; each of their entries
+// is queued for the meter event topic. Responses no parser is bound to are
+// left alone. The parser is picked for the firmware group of the meter; for
+// a meter without one the group of the binding found is used, and a
+// response the groups decode differently is an error. Validation only ever
+// sees the meter's own group.
+func (k *kafkaConusmerHandler) decodePayload(meterIp string, cmdID int, srcPort uint8, dcuNumber uint32, data []byte) error {
+	meterGroupID := k.meterGroup(meterIp)
+	groupID := meterGroupID
+	parser, boundGroupID, err := k.payloadParsers.lookup(cmdID, srcPort, meterGroupID, time.Now())
+	if errors.Is(err, payload.ErrNotBound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("payload of meter %s: %w", meterIp, err)
+	}
+	if groupID == payload.ANY_GROUP {
+		groupID = boundGroupID
+	}
+	record, err := parser.Parse(data, payload.Context{MeterIp: meterIp, DcuNumber: dcuNumber, CmdID: cmdID, SrcPort: srcPort})
+	if err != nil {
+		return fmt.Errorf("%s payload of meter %s: %w", parser.Profile(), meterIp, err)
+	}
+	if eventLog, isEventLog := record.Data.(*payload.EventLog); isEventLog {
+		return k.queueMeterEvents(eventLog, groupID)
+	}
+	encoded, err := json.Marshal(record.Data)
+	if err != nil {
+		return err
+	}
+
+	// records that carry no time are taken as read when they arrive
+	takenAt := record.TakenAt
+	if takenAt.IsZero() {
+		takenAt = time.Now()
+	}
+	category, violations := k.validator.Validate(meterIp, meterGroupID, takenAt, encoded)
+	encoded, err = k.fieldCatalogue.annotate(record.Type, encoded, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		failedRules := make([]string, len(violations))
+		for i, violation := range violations {
+			failedRules[i] = violation.RuleID
+		}
+		quarantined, err := json.Marshal(models.QuarantinedReading{
+			RecordType:    record.Type,
+			MeterIp:       meterIp,
+			DcuNo:         uint64(dcuNumber),
+			Category:      category,
+			GroupID:       meterGroupID,
+			FailedRules:   failedRules,
+			Violations:    violations,
+			Record:        encoded,
+			QuarantinedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		k.quarantined = append(k.quarantined, quarantined)
+		return nil
+	}
+
+	blockLoad, isBlockLoad := record.Data.(*tap.BlockLoadParser)
+	if !isBlockLoad {
+		k.meterReadings = append(k.meterReadings, encoded)
+		return nil
+	}
+	k.blockLoads = append(k.blockLoads, encoded)
+	if err := k.blockLoadGaps.add(*blockLoad, time.Now()); err != nil {
+		k.logger.Errorf("<decodePayload> intervals of meter %s: %v", meterIp, err)
+	}
+	return nil
+}
+
+// publishDecodedPayloads publishes the records decoded in the batch, and the
+// quarantined ones.
+func (k *kafkaConusmerHandler) publishDecodedPayloads() {
+	topics := k.cfg.KafkaTopicsConfig
+	if len(k.quarantined) > 0 {
+		k.logger.Debugf("<publishDecodedPayloads> %d quarantined readings", len(k.quarantined))
+		go k.KafkaProducer.ProduceMessagesInBatch(topics.PRODUCE_QUARANTINE_KAFKA_TOPIC_NAME, k.quarantined)
+		k.quarantined = nil
+	}
+	if len(k.meterEvents) > 0 {
+		k.logger.Debugf("<publishDecodedPayloads> %d meter events", len(k.meterEvents))
+		go k.KafkaProducer.ProduceMessagesInBatch(topics.PRODUCE_METER_EVENT_KAFKA_TOPIC_NAME, k.meterEvents)
+		k.meterEvents = nil
+	}
+	if len(k.meterReadings) > 0 {
+		k.logger.Debugf("<publishDecodedPayloads> %d meter readings", len(k.meterReadings))
+		go k.KafkaProducer.ProduceMessagesInBatch(topics.PRODUCE_METER_READING_KAFKA_TOPIC_NAME, k.meterReadings)
+		k.meterReadings = nil
+	}
+	if len(k.blockLoads) > 0 {
+		k.logger.Debugf("<publishDecodedPayloads> %d block load records", len(k.blockLoads))
+		go k.KafkaProducer.ProduceMessagesInBatch(topics.PRODUCE_BLOCK_LOAD_KAFKA_TOPIC_NAME, k.blockLoads)
+		k.blockLoads = nil
+	}
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"parsing-service/apps/decoder/models"
+	"parsing-service/pkg/checksum"
+	"parsing-service/pkg/dissect"
+	"parsing-service/pkg/tap"
+	"parsing-service/pkg/wp"
+
+	"github.com/jackc/pgtype"
+)
+
+const (
+	DISSECTOR_MODE_OFF  = "off"
+	DISSECTOR_MODE_TEXT = "text"
+	DISSECTOR_MODE_JSON = "json"
+
+	dissectorRequestID = "dissector"
+)
+
+// argumentKey is the argument_key column of a deserialize logic row. It is
+// either a plain name or an object that also says how to read the bytes.
+type argumentKey struct {
+	Name  string `json:"name"`
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	Order string `json:"order"`
+}
+
+func parseArgumentKey(raw pgtype.JSONB, id int32) argumentKey {
+	key := argumentKey{}
+	if raw.Status == pgtype.Present {
+		var name string
+		if err := json.Unmarshal(raw.Bytes, &name); err == nil {
+			key.Name = name
+		} else if err := json.Unmarshal(raw.Bytes, &key); err != nil {
+			key.Name = string(raw.Bytes)
+		}
+	}
+	if key.Name == "" {
+		key.Name = key.Key
+	}
+	if key.Name == "" {
+		key.Name = fmt.Sprintf("argument_%d", id)
+	}
+	if key.Type == "" {
+		key.Type = dissect.TYPE_UINT
+	}
+	// meter payloads are little endian, as BlockLoadParser reads them
+	if key.Order == "" {
+		key.Order = dissect.ORDER_REVERSE
+	}
+	return key
+}
+
+// payloadFields turns the deserialize logic rows of a command into the
+// payload fields of packets from srcPort. Rows without a source port apply
+// to every port.
+func payloadFields(logics []models.DeserializeLogics, srcPort uint8) []dissect.PayloadField {
+	fields := make([]dissect.PayloadField, 0, len(logics))
+	for _, logic := range logics {
+		if logic.SourcePort != 0 && logic.SourcePort != int32(srcPort) {
+			continue
+		}
+		key := parseArgumentKey(logic.ArgumentKey, logic.ID)
+		fields = append(fields, dissect.PayloadField{
+			Name:   key.Name,
+			Index:  int(logic.IndexNo),
+			Length: int(logic.Length),
+			Type:   key.Type,
+			Order:  key.Order,
+		})
+	}
+	return fields
+}
+
+// DissectTapPacket dissects a TAP packet, start byte included, with the crc
+// variant and deserialize logics of its command. Of the variants of the
+// command's mapping rows, the one the packet checks out with is shown, the
+// first one when it checks out with none.
+func (s *DecoderService) DissectTapPacket(raw []byte, requestID string) *dissect.Node {
+	if len(raw) <= tap.TAP_HEADER_LEN {
+		return tap.Dissect(raw, s.checksums.Tap)
+	}
+	packet, err := tap.DecodeUnchecked(raw[1:])
+	if err != nil {
+		return tap.Dissect(raw, s.checksums.Tap)
+	}
+	cmdID, err := packet.CmdID()
+	if err != nil {
+		return tap.Dissect(raw, s.checksums.Tap)
+	}
+
+	algs := s.checksums.ForCommandMappings(s.GetCommandMappingFromCmdID(cmdID, requestID))
+	alg := algs[0]
+	if _, picked, err := tap.DecodePicked(raw, func(*tap.TAPPacket) []*checksum.Algorithm { return algs }); err == nil {
+		alg = picked
+	}
+	fields := payloadFields(s.GetDeserializeLogicsFromCmdId(cmdID, requestID), packet.SrcPort)
+	return tap.Dissect(raw, alg, fields...)
+}
+
+// printDissection prints the dissected frame in the configured dissector
+// mode.
+func (k *kafkaConusmerHandler) printDissection(node *dissect.Node) {
+	switch k.cfg.DissectorConfig.Mode {
+	case DISSECTOR_MODE_TEXT:
+		fmt.Print(node.Text())
+	case DISSECTOR_MODE_JSON:
+		out, err := node.JSON()
+		if err != nil {
+			fmt.Println("Error in rendering dissection", err)
+			return
+		}
+		fmt.Println(string(out))
+	}
+}
+
+func (k *kafkaConusmerHandler) dissecting() bool {
+	mode := k.cfg.DissectorConfig.Mode
+	return mode == DISSECTOR_MODE_TEXT || mode == DISSECTOR_MODE_JSON
+}
+
+// dissectTapPacket dissects a TAP packet with the crc variant and payload
+// fields of its command.
+func (k *kafkaConusmerHandler) dissectTapPacket(raw []byte) *dissect.Node {
+	return k.decoder.DissectTapPacket(raw, dissectorRequestID)
+}
+
+// dissectWpFrame dissects a WP frame down to the payload fields of the TAP
+// packets its uplinks carry.
+func (k *kafkaConusmerHandler) dissectWpFrame(payload []byte) *dissect.Node {
+	return wp.Dissect(payload, k.checksums.Wp, func(message wp.Message, uplink []byte, base int) *dissect.Node {
+		if len(uplink) == 0 || uplink[0] != tap.TAP_START_BYTE {
+			return nil
+		}
+		return k.dissectTapPacket(uplink).Rebase(base)
+	})
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"testing"
+
+	daoInterfaces "parsing-service/apps/decoder/dao_interfaces"
+	"parsing-service/apps/decoder/models"
+	"parsing-service/pkg/checksum"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/tap"
+
+	"github.com/jackc/pgtype"
+)
+
+type commandMappingDAO struct {
+	daoInterfaces.ICommandMappingDAO
+	mappings []models.CommandMapping
+}
+
+func (d *commandMappingDAO) GetCommandMappingByCmdID(cmdID int, requestID string) []models.CommandMapping {
+	var rows []models.CommandMapping
+	for _, mapping := range d.mappings {
+		if int(mapping.CmdID) == cmdID {
+			rows = append(rows, mapping)
+		}
+	}
+	return rows
+}
+
+type deserializeLogicsDAO struct {
+	daoInterfaces.IDeserializeLogicsDAO
+	logics []models.DeserializeLogics
+}
+
+func (d *deserializeLogicsDAO) GetDeserializeLogicsByCmdId(cmdID int, requestID string) []models.DeserializeLogics {
+	return d.logics
+}
+
+func TestDissectTapPacketUsesCommandMapping(t *testing.T) {
+	cfg := &config.Configuration{}
+	cfg.ChecksumConfig.TapVariant = checksum.CRC16_XMODEM_EVEN_NAME
+	cfg.ChecksumConfig.WpVariant = checksum.CRC16_XMODEM_EVEN_NAME
+	checksums, err := NewFrameChecksums(cfg, logger.NewLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &DecoderService{
+		CommandMappingDAO: &commandMappingDAO{mappings: []models.CommandMapping{
+			{CmdID: 0x0202, GroupID: 1},
+			{CmdID: 0x0202, GroupID: 2, ChecksumVariant: checksum.CRC32_NAME},
+		}},
+		DeserializeLogicsDAO: &deserializeLogicsDAO{logics: []models.DeserializeLogics{
+			{ID: 1, CmdID: 0x0202, ArgumentKey: pgtype.JSONB{Bytes: []byte(`"voltage"`), Status: pgtype.Present}, IndexNo: 0, Length: 2},
+		}},
+		checksums: checksums,
+	}
+	raw, err := tap.EncodeWith(&tap.TAPPacket{
+		SrcAddr:  tap.NewAddress1("10.0.1.2"),
+		DestAddr: tap.NewAddress1("0.0.2.2"),
+		SrcPort:  1,
+		DestPort: 2,
+		Data:     []byte{0x10, 0x20},
+	}, checksum.CRC32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := s.DissectTapPacket(raw, "test")
+	if !node.Valid {
+		t.Fatalf("dissection invalid: %s", node.Text())
+	}
+	if crc := node.Find("crc"); crc == nil || crc.Length != checksum.CRC32.Size() {
+		t.Fatalf("crc node %+v, want the crc32 of the second mapping row", crc)
+	}
+	if node.Find("voltage") == nil {
+		t.Fatalf("no payload field in %s", node.Text())
+	}
+}
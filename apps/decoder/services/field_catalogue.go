@@ -0,0 +1,91 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	decoderIntf "parsing-service/apps/decoder/service_interfaces"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/obis"
+)
+
+const fieldCatalogueRequestID = "field-catalogue"
+
+// fieldCatalogue caches the field_catalogue table, read again every
+// refreshInterval, and tags decoded records with it.
+type fieldCatalogue struct {
+	decoder         decoderIntf.IDecoderService
+	cfg             config.FieldCatalogueConfig
+	refreshInterval time.Duration
+	logger          logger.ILogger
+
+	catalogue *obis.Catalogue
+	loadedAt  time.Time
+}
+
+func newFieldCatalogue(decoder decoderIntf.IDecoderService, cfg config.FieldCatalogueConfig, refreshInterval time.Duration, logger logger.ILogger) *fieldCatalogue {
+	for unit, outputUnit := range cfg.OutputUnits {
+		if _, err := obis.Convert(1, unit, outputUnit); err != nil {
+			logger.Errorf("values in %s are published unconverted: %v", unit, err)
+		}
+	}
+	return &fieldCatalogue{decoder: decoder, cfg: cfg, refreshInterval: refreshInterval, logger: logger}
+}
+
+// annotate adds the OBIS-tagged values of a decoded record of recordType
+// to its JSON as obis_values. The record is returned as it is when none of
+// its fields is catalogued.
+func (c *fieldCatalogue) annotate(recordType string, record []byte, now time.Time) ([]byte, error) {
+	if c.catalogue == nil || now.Sub(c.loadedAt) >= c.refreshInterval {
+		c.refresh(now)
+	}
+
+	values := c.catalogue.Annotate(recordType, numericFields(record))
+	record = bytes.TrimSpace(record)
+	if len(values) == 0 || len(record) < 2 || record[len(record)-1] != '}' {
+		return record, nil
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	annotated := make([]byte, 0, len(record)+len(encoded)+16)
+	annotated = append(annotated, record[:len(record)-1]...)
+	if len(bytes.TrimSpace(record[1:len(record)-1])) > 0 {
+		annotated = append(annotated, ',')
+	}
+	annotated = append(annotated, `"obis_values":`...)
+	annotated = append(annotated, encoded...)
+	return append(annotated, '}'), nil
+}
+
+// refresh reads the catalogue, keeping the fields already known when the
+// table cannot be read.
+func (c *fieldCatalogue) refresh(now time.Time) {
+	c.loadedAt = now
+	defer func() {
+		if rec := recover(); rec != nil {
+			c.logger.Errorf("<fieldCatalogue> keeping the field catalogue: %v", rec)
+			if c.catalogue == nil {
+				c.catalogue = obis.NewCatalogue(nil, c.cfg.OutputUnits)
+			}
+		}
+	}()
+
+	rows := c.decoder.GetFieldCatalogue(fieldCatalogueRequestID)
+	fields := make([]obis.Field, 0, len(rows))
+	for _, row := range rows {
+		fields = append(fields, obis.Field{
+			RecordType:   row.RecordType,
+			Name:         row.FieldName,
+			ObisCode:     row.ObisCode,
+			Unit:         row.Unit,
+			Scaler:       int(row.Scaler),
+			QuantityType: row.QuantityType,
+		})
+	}
+	c.catalogue = obis.NewCatalogue(fields, c.cfg.OutputUnits)
+}
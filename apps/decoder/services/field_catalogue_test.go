@@ -0,0 +1,102 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"parsing-service/apps/decoder/models"
+	decoderIntf "parsing-service/apps/decoder/service_interfaces"
+	"parsing-service/pkg/blockload"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/obis"
+	"parsing-service/pkg/payload"
+)
+
+// catalogueDecoder serves the event and field catalogues, the other methods
+// are not used by the tests.
+type catalogueDecoder struct {
+	decoderIntf.IDecoderService
+	fields []models.FieldCatalogue
+}
+
+func (d *catalogueDecoder) GetEventCatalogue(requestID string) []models.EventCatalogue {
+	return nil
+}
+
+func (d *catalogueDecoder) GetFieldCatalogue(requestID string) []models.FieldCatalogue {
+	return d.fields
+}
+
+func obisValues(t *testing.T, record []byte) []obis.Value {
+	t.Helper()
+	var annotated struct {
+		ObisValues []obis.Value `json:"obis_values"`
+	}
+	if err := json.Unmarshal(record, &annotated); err != nil {
+		t.Fatal(err)
+	}
+	return annotated.ObisValues
+}
+
+func annotatingHandler(fields []models.FieldCatalogue) *kafkaConusmerHandler {
+	cfg := &config.Configuration{}
+	log := logger.NewLogger()
+	decoder := &catalogueDecoder{fields: fields}
+	return &kafkaConusmerHandler{
+		cfg:            cfg,
+		logger:         log,
+		eventCatalogue: newEventCatalogue(decoder, time.Hour, log),
+		fieldCatalogue: newFieldCatalogue(decoder, cfg.FieldCatalogueConfig, time.Hour, log),
+		blockLoadGaps:  newBlockLoadGaps(cfg.BlockLoadConfig.DayLocation),
+	}
+}
+
+func TestMeterEventsAreAnnotated(t *testing.T) {
+	k := annotatingHandler([]models.FieldCatalogue{
+		{RecordType: payload.RECORD_TYPE_EVENT_LOG, FieldName: "vendor_code", ObisCode: "0.0.96.11.0.255"},
+	})
+	log := &payload.EventLog{MeterIp: "10.0.0.1", DcuNo: 7, CmdID: 0x0301, Events: []payload.EventEntry{
+		{Code: 101, OccurredAt: time.Now()},
+	}}
+	if err := k.queueMeterEvents(log, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(k.meterEvents) != 1 {
+		t.Fatalf("queued %d events, want 1", len(k.meterEvents))
+	}
+	values := obisValues(t, k.meterEvents[0])
+	if len(values) != 1 || values[0].ObisCode != "0.0.96.11.0.255" || values[0].Value != 101 {
+		t.Fatalf("obis values %+v", values)
+	}
+}
+
+func TestBlockLoadIntervalsAreAnnotated(t *testing.T) {
+	k := annotatingHandler([]models.FieldCatalogue{
+		{RecordType: blockload.RECORD_TYPE_INTERVAL, FieldName: "slot", ObisCode: "1.0.0.8.4.255"},
+	})
+	producer := &batchProducer{batches: make(chan producedBatch, 1)}
+	k.KafkaProducer = producer
+	k.cfg.KafkaTopicsConfig.PRODUCE_BLOCK_LOAD_INTERVAL_KAFKA_TOPIC_NAME = "block.load.interval"
+	interval, err := json.Marshal(blockload.Interval{MeterIp: "10.0.0.1", Slot: 5, Status: blockload.SLOT_PRESENT})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.blockLoadGaps.intervals = [][]byte{interval}
+
+	k.publishBlockLoadGaps()
+	var batch producedBatch
+	select {
+	case batch = <-producer.batches:
+	case <-time.After(time.Second):
+		t.Fatal("interval records not published")
+	}
+	if batch.topic != "block.load.interval" || len(batch.messages) != 1 {
+		t.Fatalf("published %d record(s) on %q", len(batch.messages), batch.topic)
+	}
+	values := obisValues(t, batch.messages[0])
+	if len(values) != 1 || values[0].ObisCode != "1.0.0.8.4.255" || values[0].Value != 5 {
+		t.Fatalf("obis values %+v", values)
+	}
+}
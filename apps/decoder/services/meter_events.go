@@ -0,0 +1,106 @@
+package services
+
+import (
+	"encoding/json"
+	"time"
+
+	"parsing-service/apps/decoder/models"
+	decoderIntf "parsing-service/apps/decoder/service_interfaces"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/payload"
+)
+
+const eventCatalogueRequestID = "event-catalogue"
+
+type eventCatalogueKey struct {
+	vendorCode int
+	groupID    int
+}
+
+// eventCatalogue caches the event_catalogue table, read again every
+// refreshInterval.
+type eventCatalogue struct {
+	decoder         decoderIntf.IDecoderService
+	refreshInterval time.Duration
+	logger          logger.ILogger
+
+	entries  map[eventCatalogueKey]models.EventCatalogue
+	loadedAt time.Time
+}
+
+func newEventCatalogue(decoder decoderIntf.IDecoderService, refreshInterval time.Duration, logger logger.ILogger) *eventCatalogue {
+	return &eventCatalogue{decoder: decoder, refreshInterval: refreshInterval, logger: logger}
+}
+
+// lookup returns the canonical event of a vendor code logged by a meter of
+// groupID, preferring the group's own row.
+func (c *eventCatalogue) lookup(vendorCode int, groupID int, now time.Time) (models.EventCatalogue, bool) {
+	if c.entries == nil || now.Sub(c.loadedAt) >= c.refreshInterval {
+		c.refresh(now)
+	}
+	if entry, ok := c.entries[eventCatalogueKey{vendorCode, groupID}]; ok {
+		return entry, true
+	}
+	entry, ok := c.entries[eventCatalogueKey{vendorCode, 0}]
+	return entry, ok
+}
+
+// refresh reads the catalogue, keeping the entries already known when the
+// table cannot be read.
+func (c *eventCatalogue) refresh(now time.Time) {
+	c.loadedAt = now
+	defer func() {
+		if rec := recover(); rec != nil {
+			c.logger.Errorf("<eventCatalogue> keeping the event catalogue: %v", rec)
+			if c.entries == nil {
+				c.entries = make(map[eventCatalogueKey]models.EventCatalogue)
+			}
+		}
+	}()
+
+	entries := make(map[eventCatalogueKey]models.EventCatalogue)
+	for _, entry := range c.decoder.GetEventCatalogue(eventCatalogueRequestID) {
+		entries[eventCatalogueKey{int(entry.VendorCode), int(entry.GroupID)}] = entry
+	}
+	c.entries = entries
+}
+
+// queueMeterEvents queues every entry of an event log as a meter event of
+// its own. Codes missing from the catalogue are published as unknown events
+// so they are not lost. Events carry the catalogued fields of the event log
+// record type.
+func (k *kafkaConusmerHandler) queueMeterEvents(log *payload.EventLog, groupID int) error {
+	now := time.Now()
+	for _, entry := range log.Events {
+		event := models.MeterEvent{
+			MeterIp:    log.MeterIp,
+			DcuNo:      uint64(log.DcuNo),
+			CmdID:      log.CmdID,
+			GroupID:    groupID,
+			VendorCode: entry.Code,
+			EventCode:  models.EVENT_CODE_UNKNOWN,
+			Category:   models.EVENT_CATEGORY_UNKNOWN,
+			OccurredAt: entry.OccurredAt,
+			ReceivedAt: now,
+		}
+		if canonical, ok := k.eventCatalogue.lookup(entry.Code, groupID, now); ok {
+			event.EventCode = canonical.EventCode
+			event.State = canonical.State
+			event.Category = canonical.Category
+			event.Description = canonical.Description
+		} else {
+			k.logger.Errorf("<queueMeterEvents> meter %s logged event code %d missing from the catalogue", log.MeterIp, entry.Code)
+		}
+
+		encoded, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		encoded, err = k.fieldCatalogue.annotate(payload.RECORD_TYPE_EVENT_LOG, encoded, now)
+		if err != nil {
+			return err
+		}
+		k.meterEvents = append(k.meterEvents, encoded)
+	}
+	return nil
+}
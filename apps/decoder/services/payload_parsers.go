@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"parsing-service/apps/decoder/models"
+	decoderIntf "parsing-service/apps/decoder/service_interfaces"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/payload"
+)
+
+const payloadParsersRequestID = "payload-parsers"
+
+// payloadParsers picks the parser of a response. The registered profiles are
+// bound to the command ids, source ports and firmware groups of their
+// command_mapping rows; commands bound to none are decoded with their
+// deserialize_logics rows if they have any. Both are read again every
+// RefreshIntervalMs so new mappings are picked up without a restart.
+type payloadParsers struct {
+	decoder decoderIntf.IDecoderService
+	cfg     config.PayloadParserConfig
+	logger  logger.ILogger
+
+	registry *payload.Registry
+	// deserialize logic rows by command id, nil for commands without any
+	logics   map[int][]models.DeserializeLogics
+	loadedAt time.Time
+}
+
+func newPayloadParsers(decoder decoderIntf.IDecoderService, cfg config.PayloadParserConfig, logger logger.ILogger) *payloadParsers {
+	return &payloadParsers{decoder: decoder, cfg: cfg, logger: logger}
+}
+
+// lookup returns the parser of responses to cmdID from srcPort of a meter
+// of groupID, payload.ANY_GROUP when its group is unknown, and the firmware
+// group it was bound for. It fails with payload.ErrNotBound when no parser
+// is bound, and with payload.ErrGroupConflict when the group is unknown and
+// the groups bound decode the responses differently.
+func (p *payloadParsers) lookup(cmdID int, srcPort uint8, groupID int, now time.Time) (payload.PayloadParser, int, error) {
+	if p.registry == nil || now.Sub(p.loadedAt) >= time.Duration(p.cfg.RefreshIntervalMs)*time.Millisecond {
+		p.refresh(now)
+	}
+
+	parser, key, err := p.registry.Lookup(payload.Key{CmdID: cmdID, Port: srcPort, GroupID: groupID})
+	if !errors.Is(err, payload.ErrNotBound) {
+		return parser, key.GroupID, err
+	}
+
+	groupLogics, logicsGroup, err := logicsOfGroup(p.deserializeLogics(cmdID), int32(groupID))
+	if err != nil {
+		return nil, 0, fmt.Errorf("deserialize logics of command %d: %w", cmdID, err)
+	}
+	fields := payloadFields(groupLogics, srcPort)
+	if len(fields) == 0 {
+		return nil, 0, payload.ErrNotBound
+	}
+	return &payload.Generic{Fields: fields}, int(logicsGroup), nil
+}
+
+// logicsOfGroup picks the deserialize logic rows of a meter of groupID: the
+// group's own rows, else the rows for any group. For a meter whose group is
+// unknown the rows for any group are used, else those of the only group
+// that has rows.
+func logicsOfGroup(logics []models.DeserializeLogics, groupID int32) ([]models.DeserializeLogics, int32, error) {
+	byGroup := make(map[int32][]models.DeserializeLogics)
+	for _, logic := range logics {
+		byGroup[logic.GroupID] = append(byGroup[logic.GroupID], logic)
+	}
+	if rows, ok := byGroup[groupID]; ok {
+		return rows, groupID, nil
+	}
+	if rows, ok := byGroup[payload.ANY_GROUP]; ok {
+		return rows, payload.ANY_GROUP, nil
+	}
+	if groupID != payload.ANY_GROUP || len(byGroup) == 0 {
+		return nil, 0, nil
+	}
+	if len(byGroup) > 1 {
+		return nil, 0, payload.ErrGroupConflict
+	}
+	for group, rows := range byGroup {
+		return rows, group, nil
+	}
+	return nil, 0, nil
+}
+
+// refresh binds the registered profiles again. When the table cannot be
+// read the bindings already known are kept until the next refresh.
+func (p *payloadParsers) refresh(now time.Time) {
+	p.loadedAt = now
+	p.logics = make(map[int][]models.DeserializeLogics)
+	defer func() {
+		if rec := recover(); rec != nil {
+			p.logger.Errorf("<payloadParsers> keeping the parser bindings: %v", rec)
+			if p.registry == nil {
+				p.registry = payload.NewRegistry()
+			}
+		}
+	}()
+
+	registry := payload.NewRegistry()
+	for _, profile := range payload.Profiles() {
+		parser, _ := payload.Parser(profile)
+		cmdNames, ok := p.cfg.CmdNames[profile]
+		if !ok {
+			if !payload.BoundByDefault(parser) {
+				continue
+			}
+			cmdNames = []string{strings.ToUpper(profile)}
+		}
+		for _, cmdName := range cmdNames {
+			for _, mapping := range p.decoder.GetCommandMappingFromCmdName(cmdName, payloadParsersRequestID) {
+				registry.Bind(payload.Key{CmdID: int(mapping.CmdID), Port: uint8(mapping.SP), GroupID: int(mapping.GroupID)}, parser)
+			}
+		}
+	}
+	p.registry = registry
+	p.logger.Infof("bound %d payload parser(s) to %d command mapping(s)", len(payload.Profiles()), registry.Len())
+}
+
+// deserializeLogics returns the deserialize logic rows of cmdID, read once
+// per refresh.
+func (p *payloadParsers) deserializeLogics(cmdID int) (logics []models.DeserializeLogics) {
+	if logics, ok := p.logics[cmdID]; ok {
+		return logics
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			p.logger.Errorf("<payloadParsers> deserialize logics of command %d: %v", cmdID, rec)
+			logics = nil
+		}
+		p.logics[cmdID] = logics
+	}()
+	return p.decoder.GetDeserializeLogicsFromCmdId(cmdID, payloadParsersRequestID)
+}
+
+// irdaDcuNumber reads the DCU number of an IRDA stream key, 0 when the DCU is
+// named some other way.
+func irdaDcuNumber(dcuID string) uint32 {
+	dcuNumber, err := strconv.ParseUint(dcuID, 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(dcuNumber)
+}
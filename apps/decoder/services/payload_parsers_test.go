@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"parsing-service/apps/decoder/models"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/payload"
+)
+
+func TestAssumedLayoutsBindOnlyWhenNamed(t *testing.T) {
+	decoder := &mappingDecoder{mappings: []models.CommandMapping{
+		{CmdName: "DAILY_PROFILE", CmdID: 0x0101, SP: 5},
+		{CmdName: "INSTANTANEOUS", CmdID: 0x0102, SP: 5},
+		{CmdName: "BILLING", CmdID: 0x0103, SP: 5},
+		{CmdName: "BILLING_HISTORY", CmdID: 0x0104, SP: 5},
+		{CmdName: "EVENT_LOG", CmdID: 0x0105, SP: 5},
+		{CmdName: "EVENTS", CmdID: 0x0106, SP: 5},
+	}}
+	cfg := config.PayloadParserConfig{
+		CmdNames:          map[string][]string{"billing": {"BILLING_HISTORY"}, "event_log": {"EVENTS"}},
+		RefreshIntervalMs: int(time.Hour / time.Millisecond),
+	}
+	p := newPayloadParsers(decoder, cfg, logger.NewLogger())
+	now := time.Now()
+
+	for _, tc := range []struct {
+		cmdID       int
+		wantProfile string
+	}{
+		{0x0101, "daily_profile"},
+		// instantaneous is not named in the config
+		{0x0102, ""},
+		// billing is bound to the rows named, not to the row named after it
+		{0x0103, ""},
+		{0x0104, "billing"},
+		// nor is the event log
+		{0x0105, ""},
+		{0x0106, "event_log"},
+	} {
+		parser, _, err := p.lookup(tc.cmdID, 5, payload.ANY_GROUP, now)
+		if tc.wantProfile == "" {
+			if !errors.Is(err, payload.ErrNotBound) {
+				t.Fatalf("command %#x bound to %v, %v", tc.cmdID, parser, err)
+			}
+			continue
+		}
+		if err != nil || parser.Profile() != tc.wantProfile {
+			t.Fatalf("command %#x: %v, %v, want %s", tc.cmdID, parser, err, tc.wantProfile)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"time"
+
+	presenceModels "parsing-service/apps/presence/models"
+	"parsing-service/pkg/payload"
+	"parsing-service/pkg/tap"
+	"parsing-service/pkg/wp"
+)
+
+// meterGroup returns the firmware group provisioned for a meter in the
+// last-seen registry, payload.ANY_GROUP when it has none or cannot be read.
+func (k *kafkaConusmerHandler) meterGroup(meterIp string) int {
+	groupID, err := k.presence.GetMeterGroup(meterIp, time.Now())
+	if err != nil {
+		k.logger.Errorf("<meterGroup> %s: %v", meterIp, err)
+		return payload.ANY_GROUP
+	}
+	if groupID == nil {
+		return payload.ANY_GROUP
+	}
+	return int(*groupID)
+}
+
+// seeWpMeter records a TAP packet of a meter carried by uplink of a WP frame
+// in the last-seen registry.
+func (k *kafkaConusmerHandler) seeWpMeter(packet *tap.TAPPacket, frame wp.Frame, uplink wp.Message, dcuID string, at time.Time) {
+	cmdID, err := packet.CmdID()
+	if err != nil {
+		return
+	}
+	sinkID := int16(frame.SinkID)
+	hopCount := int16(uplink.Uplink.HopCount)
+	nodeAddress := int64(uplink.Uplink.SrcAddress)
+	k.presence.SeeMeter(presenceModels.MeterSighting{
+		MeterIp:     packet.SrcAddr.String(),
+		GatewayMode: "wp",
+		DcuID:       dcuID,
+		CmdID:       cmdID,
+		SinkID:      &sinkID,
+		HopCount:    &hopCount,
+		NodeAddress: &nodeAddress,
+		At:          at,
+	})
+}
+
+// seeIrdaMeter records a TAP packet of a meter read over IRDA in the
+// last-seen registry.
+func (k *kafkaConusmerHandler) seeIrdaMeter(packet *tap.TAPPacket, dcuID string, at time.Time) {
+	cmdID, err := packet.CmdID()
+	if err != nil {
+		return
+	}
+	k.presence.SeeMeter(presenceModels.MeterSighting{
+		MeterIp:     packet.SrcAddr.String(),
+		GatewayMode: "irda",
+		DcuID:       dcuID,
+		CmdID:       cmdID,
+		At:          at,
+	})
+}
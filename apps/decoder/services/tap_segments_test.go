@@ -0,0 +1,87 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"parsing-service/apps/kafka/models"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/tap"
+)
+
+type batchProducer struct {
+	batches chan producedBatch
+}
+
+type producedBatch struct {
+	topic    string
+	messages [][]byte
+}
+
+func (p *batchProducer) ProduceMessage(topic string, message []byte) error {
+	return p.ProduceMessagesInBatch(topic, [][]byte{message})
+}
+
+func (p *batchProducer) ProduceMessagesInBatch(topic string, messages [][]byte) error {
+	p.batches <- producedBatch{topic: topic, messages: messages}
+	return nil
+}
+
+func (p *batchProducer) StartDeliveryReportsHandler()                {}
+func (p *batchProducer) ClusterHealth() []models.ClusterHealthStatus { return nil }
+func (p *batchProducer) Close()                                      {}
+func (p *batchProducer) Flush(timeInSeconds uint)                    {}
+
+func TestExpiredTapSegmentsGoToIncompleteTopic(t *testing.T) {
+	cfg := &config.Configuration{}
+	cfg.KafkaTopicsConfig.PRODUCE_INCOMPLETE_TAP_KAFKA_TOPIC_NAME = "incomplete.tap"
+	producer := &batchProducer{batches: make(chan producedBatch, 1)}
+	k := &kafkaConusmerHandler{
+		cfg:                cfg,
+		logger:             logger.NewLogger(),
+		KafkaProducer:      producer,
+		segmentReassembler: tap.NewSegmentReassembler([]int{300}, time.Minute, 0),
+	}
+	start := time.Now()
+	key := tap.SegmentKey{MeterIp: "10.0.0.1", CmdID: 300}
+	if _, _, _, err := k.segmentReassembler.Add(key, []byte{1, 2, 0xAB}, start); err != nil {
+		t.Fatal(err)
+	}
+
+	k.expireTapSegments(start.Add(time.Second))
+	select {
+	case batch := <-producer.batches:
+		t.Fatalf("published %d record(s) before the timeout", len(batch.messages))
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	k.expireTapSegments(start.Add(2 * time.Minute))
+	var batch producedBatch
+	select {
+	case batch = <-producer.batches:
+	case <-time.After(time.Second):
+		t.Fatal("the timed out response was not published")
+	}
+	if batch.topic != "incomplete.tap" || len(batch.messages) != 1 {
+		t.Fatalf("published %d record(s) to %q", len(batch.messages), batch.topic)
+	}
+	var record struct {
+		MeterIp          string `json:"meterIp"`
+		CmdId            int    `json:"cmdId"`
+		TotalSegments    int    `json:"totalSegments"`
+		ReceivedSegments []int  `json:"receivedSegments"`
+		Reason           string `json:"reason"`
+	}
+	if err := json.Unmarshal(batch.messages[0], &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.MeterIp != key.MeterIp || record.CmdId != 300 || record.TotalSegments != 2 ||
+		len(record.ReceivedSegments) != 1 || record.ReceivedSegments[0] != 1 || record.Reason != tap.SEGMENT_DROP_TIMEOUT {
+		t.Fatalf("incomplete record = %+v", record)
+	}
+	if len(k.incompleteTapSets) != 0 {
+		t.Fatal("incomplete sets were kept after publishing")
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/validation"
+)
+
+// ReadingValidator runs the configured validation rules on decoded readings.
+type ReadingValidator struct {
+	engine     *validation.Engine
+	categories *validation.Categories
+}
+
+// NewReadingValidator builds the rules from the validation config. A rule
+// that cannot be checked is a configuration error and stops the service.
+func NewReadingValidator(cfg *config.Configuration, logger logger.ILogger) (*ReadingValidator, error) {
+	var rules []validation.Rule
+	for _, ruleCfg := range cfg.ValidationConfig.Rules {
+		rule := validation.Rule{
+			ID:         ruleCfg.ID,
+			Kind:       ruleCfg.Kind,
+			Field:      ruleCfg.Field,
+			Min:        ruleCfg.Min,
+			Max:        ruleCfg.Max,
+			MaxPerHour: ruleCfg.MaxPerHour,
+			MaxFuture:  time.Duration(ruleCfg.MaxFutureMs) * time.Millisecond,
+			MaxAge:     time.Duration(ruleCfg.MaxAgeMs) * time.Millisecond,
+			Categories: ruleCfg.Categories,
+			Groups:     ruleCfg.Groups,
+		}
+		if err := checkRule(rule); err != nil {
+			return nil, fmt.Errorf("validation rule %s: %w", rule.ID, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	var entries []validation.CategoryEntry
+	for _, category := range cfg.ValidationConfig.MeterCategories {
+		entries = append(entries, validation.CategoryEntry{Meters: category.Meters, Category: category.Category})
+	}
+	categories, err := validation.NewCategories(entries)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("validating readings with %d rule(s)", len(rules))
+
+	reset := validation.BaselineReset{
+		AfterFailures: cfg.ValidationConfig.BaselineResetFailures,
+		MaxAge:        time.Duration(cfg.ValidationConfig.BaselineMaxAgeMs) * time.Millisecond,
+	}
+	return &ReadingValidator{engine: validation.NewEngine(rules, reset), categories: categories}, nil
+}
+
+func checkRule(rule validation.Rule) error {
+	switch rule.Kind {
+	case validation.KIND_TIMESTAMP:
+		if rule.MaxFuture <= 0 && rule.MaxAge <= 0 {
+			return fmt.Errorf("timestamp rule needs a max future or max age")
+		}
+		return nil
+	case validation.KIND_RANGE:
+		if rule.Min == nil && rule.Max == nil {
+			return fmt.Errorf("range rule needs a min or max")
+		}
+	case validation.KIND_RATE:
+		if rule.MaxPerHour <= 0 {
+			return fmt.Errorf("rate rule needs a max per hour")
+		}
+	case validation.KIND_MONOTONIC:
+	default:
+		return fmt.Errorf("unknown kind %q", rule.Kind)
+	}
+	if rule.Field == "" {
+		return fmt.Errorf("%s rule needs a field", rule.Kind)
+	}
+	return nil
+}
+
+// Validate checks a record of meterIp taken at, whose numeric fields are
+// read from its JSON, and returns the meter's category with the rules the
+// record failed. groupID is the meter's firmware group, payload.ANY_GROUP
+// when unknown, in which case only rules for every group apply.
+func (v *ReadingValidator) Validate(meterIp string, groupID int, at time.Time, record []byte) (string, []validation.Violation) {
+	values := numericFields(record)
+	category := v.categories.Of(meterIp)
+	return category, v.engine.Validate(validation.Reading{
+		MeterIp:  meterIp,
+		Category: category,
+		GroupID:  groupID,
+		At:       at,
+		Values:   values,
+	}, time.Now())
+}
+
+// numericFields reads the top-level numeric fields of a record's JSON.
+func numericFields(record []byte) map[string]float64 {
+	var fields map[string]interface{}
+	json.Unmarshal(record, &fields)
+	values := make(map[string]float64, len(fields))
+	for field, value := range fields {
+		if number, ok := value.(float64); ok {
+			values[field] = number
+		}
+	}
+	return values
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	downlinkIntf "parsing-service/apps/downlinks/service_interfaces"
+	otapIntf "parsing-service/apps/otap/service_interfaces"
+	sinkIntf "parsing-service/apps/sinks/service_interfaces"
+	topologyIntf "parsing-service/apps/topology/service_interfaces"
+	"parsing-service/pkg/checksum"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/wp"
+)
+
+// the sub-message observers of the handler, ignoring every sub-message
+type (
+	ignoreOtap      struct{ otapIntf.IOtapTracker }
+	ignoreSinks     struct{ sinkIntf.ISinkRegistry }
+	ignoreTopology  struct{ topologyIntf.ITopologyService }
+	ignoreDownlinks struct{ downlinkIntf.IDownlinkStore }
+)
+
+func (ignoreOtap) Track(wp.Frame, wp.Message, time.Time) bool       { return false }
+func (ignoreSinks) Observe(wp.Frame, wp.Message, time.Time) bool    { return false }
+func (ignoreTopology) Observe(wp.Frame, wp.Message, time.Time) bool { return false }
+func (ignoreDownlinks) Match(wp.Frame, wp.Message, time.Time) bool  { return false }
+
+func TestDcuDiagnosticsArePublished(t *testing.T) {
+	cfg := &config.Configuration{}
+	cfg.KafkaTopicsConfig.PRODUCE_DCU_DIAGNOSTIC_KAFKA_TOPIC_NAME = "dcu.diagnostic"
+	producer := &batchProducer{batches: make(chan producedBatch, 1)}
+	k := &kafkaConusmerHandler{
+		cfg:           cfg,
+		logger:        logger.NewLogger(),
+		KafkaProducer: producer,
+		otapTracker:   ignoreOtap{},
+		sinkRegistry:  ignoreSinks{},
+		topology:      ignoreTopology{},
+		downlinks:     ignoreDownlinks{},
+	}
+	// response head, no payload, response tail
+	body := make([]byte, 14)
+	data := wp.Build(1, 256, 0, checksum.FRAME_DEFAULT, wp.OutgoingMessage{Type: wp.DcuDiagRespMsg, Body: body})
+	frame, err := wp.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tapPackets := k.getTwUplinkPackets(frame); len(tapPackets) != 0 {
+		t.Fatalf("%d diagnostic response(s) taken for TAP uplinks", len(tapPackets))
+	}
+	select {
+	case batch := <-producer.batches:
+		if batch.topic != "dcu.diagnostic" || len(batch.messages) != 1 {
+			t.Fatalf("published %d record(s) on %q", len(batch.messages), batch.topic)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("diagnostic response not published")
+	}
+}
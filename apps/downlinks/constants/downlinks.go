@@ -0,0 +1,28 @@
+package constants
+
+// sent status of a Downlink_Sent_Status_Msg that means the sink sent the
+// downlink into the mesh
+const SENT_STATUS_OK = uint8(0)
+
+const (
+	EVENT_ACK     = "ack"
+	EVENT_NACK    = "nack"
+	EVENT_TIMEOUT = "timeout"
+)
+
+// why a downlink timed out
+const (
+	REASON_NO_STATUS  = "no sent status within %v"
+	REASON_SUPERSEDED = "message id reused by a newer downlink"
+	REASON_EVICTED    = "too many pending downlinks"
+)
+
+const (
+	ERR_INVALID_DOWNLINK       = "invalid downlink: %v"
+	ERR_INVALID_QUERY_PARAM    = "invalid %s %q"
+	ERR_CREATING_CONSUMER      = "error occurred while creating downlink consumer: %v"
+	ERR_SUBSCRIBING_TO_TOPIC   = "error occurred while subscribing to topic: %v || Error: %v"
+	ERR_CONSUMING_FROM_KAFKA   = "error consuming downlinks from Kafka: %v"
+	ERR_UNMARSHALING_DOWNLINK  = "error unmarshaling downlink: %v"
+	ERR_COMMITTING_OFFSET_SYNC = "error occurred while committing downlink offset sync: %v, offset: %v"
+)
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	downlinkConstants "parsing-service/apps/downlinks/constants"
+	"parsing-service/apps/downlinks/models"
+	services "parsing-service/apps/downlinks/service_interfaces"
+	"parsing-service/constants"
+	"parsing-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DownlinkController struct {
+	downlinkStore services.IDownlinkStore
+	logger        logger.ILogger
+}
+
+func NewDownlinkController(
+	downlinkStoreIntf services.IDownlinkStore,
+	logger logger.ILogger,
+) *DownlinkController {
+	return &DownlinkController{
+		downlinkStore: downlinkStoreIntf,
+		logger:        logger,
+	}
+}
+
+func badRequest(ctx *gin.Context, message string) {
+	ctx.JSON(http.StatusBadRequest, gin.H{constants.ERROR_STRING: message, constants.ERROR_CODE_STRING: constants.INVALID_REQ_PARAMS_CODE})
+}
+
+// RecordDownlink records a sent downlink so its sent status can be matched.
+func (c *DownlinkController) RecordDownlink(ctx *gin.Context) {
+	var request models.DownlinkRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil {
+		badRequest(ctx, fmt.Sprintf(downlinkConstants.ERR_INVALID_DOWNLINK, err))
+		return
+	}
+	downlink, err := request.Downlink(time.Now())
+	if err != nil {
+		badRequest(ctx, fmt.Sprintf(downlinkConstants.ERR_INVALID_DOWNLINK, err))
+		return
+	}
+
+	c.downlinkStore.Record(downlink)
+	ctx.JSON(http.StatusAccepted, downlink)
+}
+
+// ListPending returns the downlinks awaiting their sent status, filtered by
+// the dcuNumber and sinkId query params.
+func (c *DownlinkController) ListPending(ctx *gin.Context) {
+	var filter models.PendingFilter
+
+	if value := ctx.Query("dcuNumber"); value != "" {
+		dcuNumber, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			badRequest(ctx, fmt.Sprintf(downlinkConstants.ERR_INVALID_QUERY_PARAM, "dcuNumber", value))
+			return
+		}
+		dcu := uint32(dcuNumber)
+		filter.DcuNumber = &dcu
+	}
+	if value := ctx.Query("sinkId"); value != "" {
+		sinkID, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			badRequest(ctx, fmt.Sprintf(downlinkConstants.ERR_INVALID_QUERY_PARAM, "sinkId", value))
+			return
+		}
+		sink := uint8(sinkID)
+		filter.SinkID = &sink
+	}
+
+	ctx.JSON(http.StatusOK, c.downlinkStore.Pending(filter))
+}
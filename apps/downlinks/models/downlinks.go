@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Downlink is a message sent to sink SinkID of a DCU, to be acknowledged by
+// a Downlink_Sent_Status_Msg carrying the same MessageID. Reference is the
+// sender's own id of the downlink, handed back in its events.
+type Downlink struct {
+	DcuNumber uint32    `json:"dcuNumber"`
+	SinkID    uint8     `json:"sinkId"`
+	MessageID uint8     `json:"messageId"`
+	Reference string    `json:"reference,omitempty"`
+	Payload   string    `json:"payload,omitempty"`
+	SentAt    time.Time `json:"sentAt"`
+}
+
+// DownlinkEvent is published on the down ack topic when a sent status comes
+// in or a downlink times out. Downlink is nil for a sent status that matches
+// no recorded downlink.
+type DownlinkEvent struct {
+	Event     string    `json:"event"`
+	DcuNumber uint32    `json:"dcuNumber"`
+	SinkID    uint8     `json:"sinkId"`
+	MessageID uint8     `json:"messageId"`
+	Matched   bool      `json:"matched"`
+	Downlink  *Downlink `json:"downlink,omitempty"`
+	Status    *uint8    `json:"status,omitempty"`
+	At        time.Time `json:"at"`
+	DcuTime   uint32    `json:"dcuTime,omitempty"`
+	LatencyMs *int64    `json:"latencyMs,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// PendingFilter narrows the pending downlink listing; zero values match
+// everything.
+type PendingFilter struct {
+	DcuNumber *uint32
+	SinkID    *uint8
+}
+
+// DownlinkRequest records a downlink, over HTTP or the downlink topic.
+// SentAt defaults to the time it is recorded.
+type DownlinkRequest struct {
+	DcuNumber *uint32    `json:"dcuNumber"`
+	SinkID    *uint8     `json:"sinkId"`
+	MessageID *uint8     `json:"messageId"`
+	Reference string     `json:"reference"`
+	Payload   string     `json:"payload"`
+	SentAt    *time.Time `json:"sentAt"`
+}
+
+// Downlink checks that the request names its DCU, sink and message id and
+// returns the downlink it records.
+func (r DownlinkRequest) Downlink(now time.Time) (Downlink, error) {
+	switch {
+	case r.DcuNumber == nil:
+		return Downlink{}, errors.New("dcuNumber is required")
+	case r.SinkID == nil:
+		return Downlink{}, errors.New("sinkId is required")
+	case r.MessageID == nil:
+		return Downlink{}, errors.New("messageId is required")
+	}
+	downlink := Downlink{
+		DcuNumber: *r.DcuNumber,
+		SinkID:    *r.SinkID,
+		MessageID: *r.MessageID,
+		Reference: r.Reference,
+		Payload:   r.Payload,
+		SentAt:    now,
+	}
+	if r.SentAt != nil {
+		downlink.SentAt = *r.SentAt
+	}
+	return downlink, nil
+}
@@ -0,0 +1,18 @@
+package serviceinterfaces
+
+import (
+	"parsing-service/apps/downlinks/models"
+	"parsing-service/pkg/wp"
+	"time"
+)
+
+type IDownlinkStore interface {
+	Record(downlink models.Downlink)
+	Match(frame wp.Frame, message wp.Message, now time.Time) bool
+	Flush()
+	Pending(filter models.PendingFilter) []models.Downlink
+}
+
+type IDownlinkConsumerService interface {
+	FetchDownlinks()
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"parsing-service/apps/downlinks/constants"
+	"parsing-service/apps/downlinks/models"
+	serviceInterfaces "parsing-service/apps/downlinks/service_interfaces"
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+)
+
+// DownlinkConsumer records the downlinks the command side publishes on the
+// downlink topic.
+type DownlinkConsumer struct {
+	cfg             *config.Configuration
+	ConsumerFactory kafkaIntf.IKafkaConsumerFactory
+	logger          logger.ILogger
+	store           serviceInterfaces.IDownlinkStore
+}
+
+func NewDownlinkConsumer(
+	cfg *config.Configuration,
+	ConsumerFactory kafkaIntf.IKafkaConsumerFactory,
+	logger logger.ILogger,
+	store serviceInterfaces.IDownlinkStore,
+) *DownlinkConsumer {
+	return &DownlinkConsumer{
+		cfg:             cfg,
+		ConsumerFactory: ConsumerFactory,
+		logger:          logger,
+		store:           store,
+	}
+}
+
+// FetchDownlinks consumes the downlink topic in the background. Without a
+// topic configured, downlinks can only be recorded over HTTP.
+func (c *DownlinkConsumer) FetchDownlinks() {
+	topic := c.cfg.KafkaTopicsConfig.FETCH_DOWNLINK_KAFKA_TOPIC_NAME
+	if topic == "" {
+		return
+	}
+	consumer, err := c.ConsumerFactory.CreateConsumer(c.cfg.KafkaTopicsConfig.FETCH_DOWNLINK_KAFKA_TOPIC_GROUP_ID)
+	if err != nil {
+		c.logger.Errorf(constants.ERR_CREATING_CONSUMER, err)
+		return
+	}
+	if err := consumer.Subscribe([]string{topic}); err != nil {
+		c.logger.Errorf(constants.ERR_SUBSCRIBING_TO_TOPIC, topic, err)
+		return
+	}
+	go c.consume(consumer, topic)
+}
+
+func (c *DownlinkConsumer) consume(consumer kafkaIntf.IKafkaConsumer, topic string) {
+	for {
+		messages, err := consumer.PollBatch(context.Background(), c.cfg.KafkaConsumerConfig.PollBatchSize, c.cfg.KafkaConsumerConfig.PollMaxWaitMs, topic)
+		if err != nil {
+			c.logger.Errorf(constants.ERR_CONSUMING_FROM_KAFKA, err)
+			continue
+		}
+		if len(messages) == 0 {
+			continue
+		}
+
+		now := time.Now()
+		for _, message := range messages {
+			var request models.DownlinkRequest
+			if err := json.Unmarshal(message.Value, &request); err != nil {
+				c.logger.Errorf(constants.ERR_UNMARSHALING_DOWNLINK, err)
+				continue
+			}
+			downlink, err := request.Downlink(now)
+			if err != nil {
+				c.logger.Errorf(constants.ERR_INVALID_DOWNLINK, err)
+				continue
+			}
+			c.store.Record(downlink)
+		}
+
+		if offset, err := consumer.CommitSyncBatch(messages); err != nil {
+			c.logger.Errorf(constants.ERR_COMMITTING_OFFSET_SYNC, err, offset)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"parsing-service/apps/downlinks/constants"
+	"parsing-service/apps/downlinks/models"
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/wp"
+)
+
+type downlinkKey struct {
+	dcuNumber uint32
+	sinkID    uint8
+	messageID uint8
+}
+
+func keyOf(downlink *models.Downlink) downlinkKey {
+	return downlinkKey{dcuNumber: downlink.DcuNumber, sinkID: downlink.SinkID, messageID: downlink.MessageID}
+}
+
+// DownlinkStore correlates recorded downlinks with the sent statuses their
+// sinks answer with. Events are collected in memory and published by Flush,
+// which also times out the downlinks left without a status.
+type DownlinkStore struct {
+	KafkaProducer kafkaIntf.IKafkaProducer
+	cfg           *config.Configuration
+	logger        logger.ILogger
+	timeout       time.Duration
+	maxPending    int
+
+	mu      sync.Mutex
+	pending map[downlinkKey]*models.Downlink
+	events  []models.DownlinkEvent
+}
+
+func NewDownlinkStore(
+	kafkaProducer kafkaIntf.IKafkaProducer,
+	cfg *config.Configuration,
+	logger logger.ILogger,
+) *DownlinkStore {
+	return &DownlinkStore{
+		KafkaProducer: kafkaProducer,
+		cfg:           cfg,
+		logger:        logger,
+		timeout:       time.Duration(cfg.DownlinkConfig.AckTimeoutMs) * time.Millisecond,
+		maxPending:    cfg.DownlinkConfig.MaxPending,
+		pending:       make(map[downlinkKey]*models.Downlink),
+	}
+}
+
+// Record adds a downlink awaiting its sent status. A pending downlink with
+// the same DCU, sink and message id times out, as the sink's status could
+// no longer be told apart; so does the oldest one when the store is full.
+func (s *DownlinkStore) Record(downlink models.Downlink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := keyOf(&downlink)
+	if previous, ok := s.pending[key]; ok {
+		s.timeoutEvent(previous, downlink.SentAt, constants.REASON_SUPERSEDED)
+		delete(s.pending, key)
+	}
+	if s.maxPending > 0 && len(s.pending) >= s.maxPending {
+		var oldest *models.Downlink
+		for _, pending := range s.pending {
+			if oldest == nil || pending.SentAt.Before(oldest.SentAt) {
+				oldest = pending
+			}
+		}
+		s.timeoutEvent(oldest, downlink.SentAt, constants.REASON_EVICTED)
+		delete(s.pending, keyOf(oldest))
+	}
+	s.pending[key] = &downlink
+}
+
+func (s *DownlinkStore) timeoutEvent(downlink *models.Downlink, at time.Time, reason string) {
+	s.events = append(s.events, models.DownlinkEvent{
+		Event:     constants.EVENT_TIMEOUT,
+		DcuNumber: downlink.DcuNumber,
+		SinkID:    downlink.SinkID,
+		MessageID: downlink.MessageID,
+		Matched:   true,
+		Downlink:  downlink,
+		At:        at,
+		Reason:    reason,
+	})
+}
+
+// Match acks or nacks the pending downlink a sent status of frame answers
+// and reports whether message was a sent status. Statuses matching no
+// downlink are still published, unmatched.
+func (s *DownlinkStore) Match(frame wp.Frame, message wp.Message, now time.Time) bool {
+	if message.Type != wp.DownlinkSentStatusMsg {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := message.Status
+	event := models.DownlinkEvent{
+		Event:     constants.EVENT_ACK,
+		DcuNumber: frame.DcuNumber,
+		SinkID:    frame.SinkID,
+		MessageID: message.MessageID,
+		Status:    &status,
+		At:        now,
+		DcuTime:   frame.DcuTime,
+	}
+	if status != constants.SENT_STATUS_OK {
+		event.Event = constants.EVENT_NACK
+	}
+
+	key := downlinkKey{dcuNumber: frame.DcuNumber, sinkID: frame.SinkID, messageID: message.MessageID}
+	if downlink, ok := s.pending[key]; ok {
+		latencyMs := now.Sub(downlink.SentAt).Milliseconds()
+		event.Matched = true
+		event.Downlink = downlink
+		event.LatencyMs = &latencyMs
+		delete(s.pending, key)
+	}
+	s.events = append(s.events, event)
+
+	return true
+}
+
+// Flush times out the downlinks pending for longer than the ack timeout and
+// publishes the events since the last flush.
+func (s *DownlinkStore) Flush() {
+	s.flush(time.Now())
+}
+
+func (s *DownlinkStore) flush(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timeout > 0 {
+		for key, downlink := range s.pending {
+			if now.Sub(downlink.SentAt) > s.timeout {
+				s.timeoutEvent(downlink, now, fmt.Sprintf(constants.REASON_NO_STATUS, s.timeout))
+				delete(s.pending, key)
+			}
+		}
+	}
+
+	if len(s.events) == 0 {
+		return
+	}
+	events := make([][]byte, 0, len(s.events))
+	for _, event := range s.events {
+		if data, err := json.Marshal(event); err == nil {
+			events = append(events, data)
+		}
+	}
+	s.events = nil
+	s.logger.Debugf("<flush> %d downlink events", len(events))
+	go s.KafkaProducer.ProduceMessagesInBatch(s.cfg.KafkaTopicsConfig.PRODUCE_DOWN_ACK_KAFKA_TOPIC_NAME, events)
+}
+
+// Pending lists the downlinks still awaiting their sent status, oldest
+// first.
+func (s *DownlinkStore) Pending(filter models.PendingFilter) []models.Downlink {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	downlinks := []models.Downlink{}
+	for _, downlink := range s.pending {
+		if filter.DcuNumber != nil && downlink.DcuNumber != *filter.DcuNumber {
+			continue
+		}
+		if filter.SinkID != nil && downlink.SinkID != *filter.SinkID {
+			continue
+		}
+		downlinks = append(downlinks, *downlink)
+	}
+	sort.Slice(downlinks, func(i, j int) bool {
+		a, b := downlinks[i], downlinks[j]
+		if !a.SentAt.Equal(b.SentAt) {
+			return a.SentAt.Before(b.SentAt)
+		}
+		if a.DcuNumber != b.DcuNumber {
+			return a.DcuNumber < b.DcuNumber
+		}
+		if a.SinkID != b.SinkID {
+			return a.SinkID < b.SinkID
+		}
+		return a.MessageID < b.MessageID
+	})
+	return downlinks
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"parsing-service/apps/downlinks/constants"
+	"parsing-service/apps/downlinks/models"
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/wp"
+)
+
+// eventProducer hands the published batches over on a channel, the other
+// methods are not used by the tests.
+type eventProducer struct {
+	kafkaIntf.IKafkaProducer
+	batches chan [][]byte
+}
+
+func (p *eventProducer) ProduceMessagesInBatch(topic string, messages [][]byte) error {
+	p.batches <- messages
+	return nil
+}
+
+func newTestStore(timeout time.Duration, maxPending int) (*DownlinkStore, *eventProducer) {
+	cfg := &config.Configuration{}
+	cfg.DownlinkConfig.AckTimeoutMs = int(timeout / time.Millisecond)
+	cfg.DownlinkConfig.MaxPending = maxPending
+	producer := &eventProducer{batches: make(chan [][]byte, 8)}
+	return NewDownlinkStore(producer, cfg, logger.NewLogger()), producer
+}
+
+func flushedEvents(t *testing.T, store *DownlinkStore, producer *eventProducer, now time.Time) []models.DownlinkEvent {
+	t.Helper()
+	store.flush(now)
+	select {
+	case batch := <-producer.batches:
+		events := make([]models.DownlinkEvent, len(batch))
+		for i, data := range batch {
+			if err := json.Unmarshal(data, &events[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return events
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
+
+var downlinkFrame = wp.Frame{DcuNumber: 300, SinkID: 1}
+
+func sentStatus(messageID, status uint8) wp.Message {
+	return wp.Message{Type: wp.DownlinkSentStatusMsg, MessageID: messageID, Status: status}
+}
+
+func downlink(messageID uint8, reference string, sentAt time.Time) models.Downlink {
+	return models.Downlink{DcuNumber: 300, SinkID: 1, MessageID: messageID, Reference: reference, SentAt: sentAt}
+}
+
+func TestDownlinkAckAndNack(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	store, producer := newTestStore(time.Minute, 0)
+
+	store.Record(downlink(1, "a", start))
+	store.Record(downlink(2, "b", start))
+	if store.Match(downlinkFrame, wp.Message{Type: wp.UplinkMsg}, start) {
+		t.Fatal("an uplink was matched as a sent status")
+	}
+	store.Match(downlinkFrame, sentStatus(1, constants.SENT_STATUS_OK), start.Add(2*time.Second))
+	store.Match(downlinkFrame, sentStatus(2, 3), start.Add(3*time.Second))
+	store.Match(downlinkFrame, sentStatus(9, constants.SENT_STATUS_OK), start.Add(3*time.Second))
+
+	events := flushedEvents(t, store, producer, start.Add(3*time.Second))
+	if len(events) != 3 {
+		t.Fatalf("events %+v, want an ack, a nack and an unmatched status", events)
+	}
+	if ack := events[0]; ack.Event != constants.EVENT_ACK || !ack.Matched || ack.Downlink.Reference != "a" || *ack.LatencyMs != 2000 {
+		t.Fatalf("ack %+v", ack)
+	}
+	if nack := events[1]; nack.Event != constants.EVENT_NACK || !nack.Matched || *nack.Status != 3 || nack.Downlink.Reference != "b" {
+		t.Fatalf("nack %+v", nack)
+	}
+	if unmatched := events[2]; unmatched.Event != constants.EVENT_ACK || unmatched.Matched || unmatched.Downlink != nil {
+		t.Fatalf("unmatched status %+v", unmatched)
+	}
+	if pending := store.Pending(models.PendingFilter{}); len(pending) != 0 {
+		t.Fatalf("still pending %+v", pending)
+	}
+}
+
+func TestDownlinkTimeout(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	store, producer := newTestStore(time.Minute, 0)
+
+	store.Record(downlink(1, "a", start))
+	store.Record(downlink(2, "b", start.Add(30*time.Second)))
+	if events := flushedEvents(t, store, producer, start.Add(time.Minute)); len(events) != 0 {
+		t.Fatalf("timed out inside the ack timeout: %+v", events)
+	}
+
+	events := flushedEvents(t, store, producer, start.Add(time.Minute+time.Second))
+	if len(events) != 1 || events[0].Event != constants.EVENT_TIMEOUT || events[0].Downlink.Reference != "a" {
+		t.Fatalf("events %+v, want downlink a timed out", events)
+	}
+	if pending := store.Pending(models.PendingFilter{}); len(pending) != 1 || pending[0].Reference != "b" {
+		t.Fatalf("pending %+v, want b", pending)
+	}
+}
+
+func TestDownlinkSupersededAndEvicted(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	store, producer := newTestStore(0, 2)
+
+	store.Record(downlink(1, "a", start))
+	store.Record(downlink(1, "b", start.Add(time.Second)))
+	store.Record(downlink(2, "c", start.Add(2*time.Second)))
+	store.Record(downlink(3, "d", start.Add(3*time.Second)))
+
+	events := flushedEvents(t, store, producer, start.Add(time.Hour))
+	if len(events) != 2 {
+		t.Fatalf("events %+v, want a superseded and an evicted downlink", events)
+	}
+	if events[0].Downlink.Reference != "a" || events[0].Reason != constants.REASON_SUPERSEDED {
+		t.Fatalf("first event %+v, want a superseded", events[0])
+	}
+	if events[1].Downlink.Reference != "b" || events[1].Reason != constants.REASON_EVICTED {
+		t.Fatalf("second event %+v, want b evicted", events[1])
+	}
+
+	pending := store.Pending(models.PendingFilter{})
+	if len(pending) != 2 || pending[0].Reference != "c" || pending[1].Reference != "d" {
+		t.Fatalf("pending %+v, want c and d", pending)
+	}
+}
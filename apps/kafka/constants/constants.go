@@ -5,4 +5,8 @@ const (
 	ERROR_WHILE_SUBSCRIBING_TO_TOPICS = "error while subscribing to kafka topic: %v"
 
 	KAFKA_TOPIC_SUBSCRIBE_SUCCESS = "successfully subscribed to kafka topic: %v"
+	KAFKA_CLUSTER_RECOVERED       = "kafka cluster %v is healthy again"
+
+	// consecutive delivery failures after which an output cluster is reported unhealthy
+	KAFKA_CLUSTER_UNHEALTHY_THRESHOLD = 3
 )
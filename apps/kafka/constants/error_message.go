@@ -5,4 +5,8 @@ const (
 	KAFKA_ASYNC_COMMIT_ERROR = "kafka Async commit error: %v"
 	KAFKA_PRODUCER_CREATION_ERROR = "failed to create Kafka producer: %v"
 	ERROR_WHILE_PRODUCING_KAFKA_MSG = "error while producing msg to tropic: %v || Error: %v"
-)
\ No newline at end of file
+	KAFKA_CLUSTER_CLIENT_ERROR = "kafka cluster %v client error: %v"
+	KAFKA_CLUSTER_UNHEALTHY = "kafka cluster %v marked unhealthy: %v"
+	KAFKA_DUPLICATE_CLUSTER = "kafka output cluster %v configured more than once, ignoring duplicate"
+	KAFKA_TOPIC_ASSIGNED_TWICE = "kafka topic %v already assigned to cluster %v, ignoring assignment to %v"
+)
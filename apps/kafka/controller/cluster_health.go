@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"net/http"
+
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type KafkaController struct {
+	producer kafkaIntf.IKafkaProducer
+	logger   logger.ILogger
+}
+
+func NewKafkaController(
+	producerIntf kafkaIntf.IKafkaProducer,
+	logger logger.ILogger,
+) *KafkaController {
+	return &KafkaController{
+		producer: producerIntf,
+		logger:   logger,
+	}
+}
+
+// ClusterHealth returns the delivery health of every output cluster,
+// answering 503 while any of them is unhealthy.
+func (c *KafkaController) ClusterHealth(ctx *gin.Context) {
+	clusters := c.producer.ClusterHealth()
+	healthy := true
+	for _, cluster := range clusters {
+		healthy = healthy && cluster.Healthy
+	}
+
+	status := http.StatusOK
+	if !healthy {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, gin.H{"healthy": healthy, "clusters": clusters})
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+// ClusterHealthStatus is a point-in-time view of a Kafka cluster's health.
+type ClusterHealthStatus struct {
+	Cluster             string    `json:"cluster"`
+	Healthy             bool      `json:"healthy"`
+	Delivered           uint64    `json:"delivered"`
+	Failed              uint64    `json:"failed"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	LastDeliveryAt      time.Time `json:"lastDeliveryAt"`
+	LastError           string    `json:"lastError,omitempty"`
+	LastErrorAt         time.Time `json:"lastErrorAt"`
+}
+
+// ClusterHealth tracks delivery outcomes for one cluster. A cluster turns
+// unhealthy after unhealthyThreshold consecutive failures and healthy again
+// on the next successful delivery.
+type ClusterHealth struct {
+	mu                 sync.RWMutex
+	status             ClusterHealthStatus
+	unhealthyThreshold int
+}
+
+func NewClusterHealth(cluster string, unhealthyThreshold int) *ClusterHealth {
+	return &ClusterHealth{
+		status:             ClusterHealthStatus{Cluster: cluster, Healthy: true},
+		unhealthyThreshold: unhealthyThreshold,
+	}
+}
+
+// RecordSuccess registers a delivered message and reports whether the cluster recovered.
+func (h *ClusterHealth) RecordSuccess() (recovered bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	recovered = !h.status.Healthy
+	h.status.Delivered++
+	h.status.ConsecutiveFailures = 0
+	h.status.LastDeliveryAt = time.Now()
+	h.status.Healthy = true
+	return recovered
+}
+
+// RecordFailure registers a failed delivery or client error and reports whether the cluster just became unhealthy.
+func (h *ClusterHealth) RecordFailure(err error) (becameUnhealthy bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.status.Failed++
+	h.status.ConsecutiveFailures++
+	h.status.LastError = err.Error()
+	h.status.LastErrorAt = time.Now()
+
+	if h.status.Healthy && h.status.ConsecutiveFailures >= h.unhealthyThreshold {
+		h.status.Healthy = false
+		return true
+	}
+	return false
+}
+
+func (h *ClusterHealth) Status() ClusterHealthStatus {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.status
+}
@@ -0,0 +1,101 @@
+package serviceimpl
+
+import (
+	"parsing-service/pkg/config"
+	"sync"
+	"time"
+)
+
+const (
+	// channel occupancy above which processing is treated as the bottleneck
+	channelHighWatermark = 0.75
+	// channel occupancy below which the consumer may grow its batches
+	channelLowWatermark = 0.25
+)
+
+// BatchSizer hands out the PollBatch parameters. In static mode it always
+// returns the configured values. In adaptive mode it doubles the batch size
+// while full batches are processed within the latency target and the channel
+// is draining, halves it when processing is slow or the channel backs up, and
+// shortens the poll wait while traffic is light so idle messages are not held
+// back waiting for a batch to fill.
+type BatchSizer struct {
+	mu sync.Mutex
+
+	adaptive      bool
+	batchSize     int
+	maxWaitMs     int
+	minBatchSize  int
+	maxBatchSize  int
+	minWaitMs     int
+	ceilingWaitMs int
+	targetLatency time.Duration
+}
+
+func NewBatchSizer(cfg config.KafkaConsumerConfig) *BatchSizer {
+	s := &BatchSizer{
+		adaptive:      cfg.AdaptivePoll,
+		batchSize:     cfg.PollBatchSize,
+		maxWaitMs:     cfg.PollMaxWaitMs,
+		minBatchSize:  cfg.MinPollBatchSize,
+		maxBatchSize:  cfg.MaxPollBatchSize,
+		minWaitMs:     cfg.MinPollWaitMs,
+		ceilingWaitMs: cfg.PollMaxWaitMs,
+		targetLatency: time.Duration(cfg.TargetLatencyMs) * time.Millisecond,
+	}
+
+	if s.minBatchSize < 1 {
+		s.minBatchSize = 1
+	}
+	if s.maxBatchSize < s.minBatchSize {
+		s.maxBatchSize = s.minBatchSize
+	}
+	if s.minWaitMs > s.ceilingWaitMs {
+		s.minWaitMs = s.ceilingWaitMs
+	}
+	if s.adaptive {
+		s.batchSize = clamp(s.batchSize, s.minBatchSize, s.maxBatchSize)
+	}
+
+	return s
+}
+
+func (s *BatchSizer) Next() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batchSize, s.maxWaitMs
+}
+
+func (s *BatchSizer) Observe(batchLen int, processing time.Duration, channelLen int, channelCap int) {
+	if !s.adaptive {
+		return
+	}
+
+	occupancy := 0.0
+	if channelCap > 0 {
+		occupancy = float64(channelLen) / float64(channelCap)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case processing > s.targetLatency || occupancy >= channelHighWatermark:
+		s.batchSize = clamp(s.batchSize/2, s.minBatchSize, s.maxBatchSize)
+	case batchLen >= s.batchSize && occupancy <= channelLowWatermark:
+		s.batchSize = clamp(s.batchSize*2, s.minBatchSize, s.maxBatchSize)
+		s.maxWaitMs = clamp(s.maxWaitMs*2, s.minWaitMs, s.ceilingWaitMs)
+	case batchLen < s.batchSize/2:
+		s.maxWaitMs = clamp(s.maxWaitMs/2, s.minWaitMs, s.ceilingWaitMs)
+	}
+}
+
+func clamp(value int, low int, high int) int {
+	if value < low {
+		return low
+	}
+	if value > high {
+		return high
+	}
+	return value
+}
@@ -0,0 +1,117 @@
+package serviceimpl
+
+import (
+	"testing"
+	"time"
+
+	"parsing-service/pkg/config"
+)
+
+func adaptiveSizer() *BatchSizer {
+	return NewBatchSizer(config.KafkaConsumerConfig{
+		PollBatchSize:    100,
+		PollMaxWaitMs:    2000,
+		AdaptivePoll:     true,
+		MinPollBatchSize: 10,
+		MaxPollBatchSize: 400,
+		MinPollWaitMs:    100,
+		TargetLatencyMs:  1000,
+	})
+}
+
+func TestBatchSizerStatic(t *testing.T) {
+	s := NewBatchSizer(config.KafkaConsumerConfig{PollBatchSize: 100, PollMaxWaitMs: 2000})
+	s.Observe(100, time.Millisecond, 0, 100)
+	s.Observe(1, time.Minute, 100, 100)
+	if batchSize, maxWaitMs := s.Next(); batchSize != 100 || maxWaitMs != 2000 {
+		t.Fatalf("static sizer moved to %d/%dms", batchSize, maxWaitMs)
+	}
+}
+
+func TestBatchSizerAdaptive(t *testing.T) {
+	type observation struct {
+		batchLen   int
+		processing time.Duration
+		channelLen int
+	}
+	tests := []struct {
+		name          string
+		observations  []observation
+		wantBatchSize int
+		wantMaxWaitMs int
+	}{
+		{
+			name:          "full batches on an empty channel grow to the maximum",
+			observations:  []observation{{100, 10 * time.Millisecond, 0}, {200, 10 * time.Millisecond, 0}, {400, 10 * time.Millisecond, 0}},
+			wantBatchSize: 400,
+			wantMaxWaitMs: 2000,
+		},
+		{
+			name:          "a full channel shrinks the batch",
+			observations:  []observation{{100, 10 * time.Millisecond, 80}},
+			wantBatchSize: 50,
+			wantMaxWaitMs: 2000,
+		},
+		{
+			name:          "a full channel does not shrink below the minimum",
+			observations:  []observation{{100, 0, 100}, {50, 0, 100}, {25, 0, 100}, {12, 0, 100}},
+			wantBatchSize: 10,
+			wantMaxWaitMs: 2000,
+		},
+		{
+			name:          "slow batches shrink on an empty channel",
+			observations:  []observation{{100, 2 * time.Second, 0}},
+			wantBatchSize: 50,
+			wantMaxWaitMs: 2000,
+		},
+		{
+			name:          "light traffic shortens the wait down to the minimum",
+			observations:  []observation{{1, 0, 0}, {1, 0, 0}, {1, 0, 0}, {1, 0, 0}, {1, 0, 0}},
+			wantBatchSize: 100,
+			wantMaxWaitMs: 100,
+		},
+		{
+			name:          "full batches bring the wait back up",
+			observations:  []observation{{1, 0, 0}, {1, 0, 0}, {100, 0, 0}},
+			wantBatchSize: 200,
+			wantMaxWaitMs: 1000,
+		},
+		{
+			name:          "a half full channel holds the batch",
+			observations:  []observation{{100, 10 * time.Millisecond, 50}},
+			wantBatchSize: 100,
+			wantMaxWaitMs: 2000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := adaptiveSizer()
+			for _, o := range tt.observations {
+				s.Observe(o.batchLen, o.processing, o.channelLen, 100)
+			}
+			if batchSize, maxWaitMs := s.Next(); batchSize != tt.wantBatchSize || maxWaitMs != tt.wantMaxWaitMs {
+				t.Fatalf("sizer at %d/%dms, want %d/%dms", batchSize, maxWaitMs, tt.wantBatchSize, tt.wantMaxWaitMs)
+			}
+		})
+	}
+}
+
+func TestBatchSizerClampsConfig(t *testing.T) {
+	s := NewBatchSizer(config.KafkaConsumerConfig{
+		PollBatchSize:    5000,
+		PollMaxWaitMs:    500,
+		AdaptivePoll:     true,
+		MinPollBatchSize: 0,
+		MaxPollBatchSize: 1000,
+		MinPollWaitMs:    800,
+	})
+	if batchSize, _ := s.Next(); batchSize != 1000 {
+		t.Fatalf("batch size %d, want it clamped to 1000", batchSize)
+	}
+	for i := 0; i < 20; i++ {
+		s.Observe(0, 0, 100, 100)
+	}
+	if batchSize, maxWaitMs := s.Next(); batchSize != 1 || maxWaitMs != 500 {
+		t.Fatalf("sizer at %d/%dms, want 1/500ms", batchSize, maxWaitMs)
+	}
+}
@@ -0,0 +1,39 @@
+package serviceimpl
+
+import (
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// setClusterSecurity copies the security and SASL settings of a cluster into configMap.
+func setClusterSecurity(configMap *kafka.ConfigMap, cluster config.KafkaClusterConfig, logger logger.ILogger) {
+	if cluster.KafkaSecurityProtocol != "" {
+		err := configMap.SetKey("security.protocol", cluster.KafkaSecurityProtocol)
+		if err != nil {
+			logger.Fatalf("failed to set KafkaSecurityProtocol for cluster %v: %v", cluster.Name, err)
+		}
+	}
+
+	if cluster.KafkaSaslUsername != "" {
+		err := configMap.SetKey("sasl.username", cluster.KafkaSaslUsername)
+		if err != nil {
+			logger.Fatalf("failed to set KafkaSaslUsername for cluster %v: %v", cluster.Name, err)
+		}
+	}
+
+	if cluster.KafkaSaslPassword != "" {
+		err := configMap.SetKey("sasl.password", cluster.KafkaSaslPassword)
+		if err != nil {
+			logger.Fatalf("failed to set KafkaSaslPassword for cluster %v: %v", cluster.Name, err)
+		}
+	}
+
+	if cluster.KafkaSaslMechanism != "" {
+		err := configMap.SetKey("sasl.mechanism", cluster.KafkaSaslMechanism)
+		if err != nil {
+			logger.Fatalf("failed to set KafkaSaslMechanism for cluster %v: %v", cluster.Name, err)
+		}
+	}
+}
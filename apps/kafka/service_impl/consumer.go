@@ -10,6 +10,9 @@ import (
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 )
 
+// upper bound for a single Poll inside PollBatch, so short batch waits are honoured
+const maxPollTimeoutMs = 500
+
 type KafkaConsumer struct {
 	consumer *kafka.Consumer
 	logger   logger.ILogger
@@ -98,7 +101,11 @@ func (kc *KafkaConsumer) PollBatch(ctx context.Context, batchSize int, maxWaitMs
 	endTime := time.Now().Add(time.Duration(maxWaitMs) * time.Millisecond)
 
 	for len(msgs) < batchSize && time.Now().Before(endTime) {
-		ev := kc.consumer.Poll(500)
+		pollTimeoutMs := int(time.Until(endTime).Milliseconds())
+		if pollTimeoutMs > maxPollTimeoutMs {
+			pollTimeoutMs = maxPollTimeoutMs
+		}
+		ev := kc.consumer.Poll(pollTimeoutMs)
 		if ev == nil {
 			continue
 		}
@@ -18,44 +18,16 @@ func NewKafkaConsumerFactory(cfg *config.Configuration, logger logger.ILogger) *
 }
 
 func (f *KafkaConsumerFactory) CreateConsumer(consumerGroupID string) (kafkaIntf.IKafkaConsumer, error) {
+	cluster := f.cfg.KafkaConfig.InputCluster
 	configMap := &kafka.ConfigMap{
-		"bootstrap.servers": f.cfg.KafkaConfig.KafkaBootstrapServers,
+		"bootstrap.servers": cluster.KafkaBootstrapServers,
 		"group.id": consumerGroupID,
 		"auto.offset.reset": "earliest",
-		"session.timeout.ms": 30000,
-		"heartbeat.interval.ms": 10000,
+		"session.timeout.ms": f.cfg.KafkaConsumerConfig.SessionTimeoutMs,
+		"heartbeat.interval.ms": f.cfg.KafkaConsumerConfig.HeartbeatIntervalMs,
 		// "metadata.request.timeout.ms": 30000,
 	}
-
-	if f.cfg.KafkaConfig.KafkaSecurityProtocol != "" {
-		err := configMap.SetKey("security.protocol", f.cfg.KafkaConfig.KafkaSecurityProtocol)
-		if err != nil {
-			f.logger.Fatalf("failed to set KafkaSecurityProtocol: %v", err)
-		}
-	}
-
-	if f.cfg.KafkaConfig.KafkaSaslUsername != "" {
-		err := configMap.SetKey("sasl.username", f.cfg.KafkaConfig.KafkaSaslUsername)
-		if err != nil {
-			f.logger.Fatalf("failed to set KafkaSaslUsername: %v", err)
-		}
-	}
-
-
-	if f.cfg.KafkaConfig.KafkaSaslPassword != "" {
-		err := configMap.SetKey("sasl.password", f.cfg.KafkaConfig.KafkaSaslPassword )
-		if err != nil {
-			f.logger.Fatalf("failed to set KafkaSaslPassword: %v", err)
-		}
-	}
-
-	if f.cfg.KafkaConfig.KafkaSaslMechanism != "" {
-		err := configMap.SetKey("sasl.mechanism", f.cfg.KafkaConfig.KafkaSaslMechanism)
-		if err != nil {
-			f.logger.Fatalf("failed to set KafkaSaslMechanism")
-		} 
-	}
-
+	setClusterSecurity(configMap, cluster, f.logger)
 
 	consumer, err := kafka.NewConsumer(configMap)
 	if err != nil {
@@ -76,6 +48,10 @@ func (f *KafkaConsumerFactory) CreateConsumer(consumerGroupID string) (kafkaIntf
 		return nil, err
 	}
 
-	return &KafkaConsumer{consumer: consumer}, nil
+	return NewKafkaConsumer(consumer, f.logger), nil
+
+}
 
+func (f *KafkaConsumerFactory) CreateBatchSizer() kafkaIntf.IBatchSizer {
+	return NewBatchSizer(f.cfg.KafkaConsumerConfig)
 }
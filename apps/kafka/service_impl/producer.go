@@ -3,6 +3,7 @@ package serviceimpl
 import (
 	"fmt"
 	"parsing-service/apps/kafka/constants"
+	"parsing-service/apps/kafka/models"
 	"parsing-service/pkg/config"
 	"parsing-service/pkg/logger"
 
@@ -12,11 +13,13 @@ import (
 type KafkaProducer struct {
 	producer *kafka.Producer
 	logger   logger.ILogger
+	cluster  string
+	health   *models.ClusterHealth
 }
 
-func NewKafkaProducer(cfg *config.Configuration, logger logger.ILogger) (*KafkaProducer, error) {
+func NewKafkaProducer(cluster config.KafkaClusterConfig, logger logger.ILogger) (*KafkaProducer, error) {
 	configMap := &kafka.ConfigMap{
-		"bootstrap.servers":  cfg.KafkaConfig.KafkaBootstrapServers,
+		"bootstrap.servers":  cluster.KafkaBootstrapServers,
 		"batch.size":         10000,
 		"linger.ms":          100,
 		"compression.type":   "gzip",
@@ -26,40 +29,19 @@ func NewKafkaProducer(cfg *config.Configuration, logger logger.ILogger) (*KafkaP
 		"request.timeout.ms": 30000,
 		"enable.idempotence": true,
 	}
-	if cfg.KafkaConfig.KafkaSecurityProtocol != "" {
-		err := configMap.SetKey("security.protocol", cfg.KafkaConfig.KafkaSecurityProtocol)
-		if err != nil {
-			logger.Fatalf("failed to set KafkaSecurityProtocol: %v", err)
-		}
-	}
-
-	if cfg.KafkaConfig.KafkaSaslUsername != "" {
-		err := configMap.SetKey("sasl.username", cfg.KafkaConfig.KafkaSaslUsername)
-		if err != nil {
-			logger.Fatalf("failed to set KafkaSaslUSername: %v", err)
-		}
-	}
-
-	if cfg.KafkaConfig.KafkaSaslPassword != "" {
-		err := configMap.SetKey("sasl.password", cfg.KafkaConfig.KafkaSaslPassword)
-		if err != nil {
-			logger.Fatalf("failed to set KafkaSaslPassword: %v", err)
-		}
-	}
-
-	if cfg.KafkaConfig.KafkaSaslMechanism != "" {
-		err := configMap.SetKey("sasl.mechanism", cfg.KafkaConfig.KafkaSaslMechanism)
-		if err != nil {
-			logger.Fatalf("failed to set KafkaSaslMechanism: %v", err)
-		}
-	}
+	setClusterSecurity(configMap, cluster, logger)
 
 	producer, err := kafka.NewProducer(configMap)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create producer: %w", err)
+		return nil, fmt.Errorf("failed to create producer for cluster %v: %w", cluster.Name, err)
 	}
 
-	return &KafkaProducer{producer: producer, logger: logger}, nil
+	return &KafkaProducer{
+		producer: producer,
+		logger:   logger,
+		cluster:  cluster.Name,
+		health:   models.NewClusterHealth(cluster.Name, constants.KAFKA_CLUSTER_UNHEALTHY_THRESHOLD),
+	}, nil
 
 }
 
@@ -74,7 +56,8 @@ func (p *KafkaProducer) ProduceMessage(topic string, message []byte) error {
 
 	err := p.producer.Produce(&msg, nil)
 	if err != nil {
-		p.logger.Fatalf(constants.ERROR_WHILE_SENDING_MSG, err)
+		p.logger.Errorf(constants.ERROR_WHILE_SENDING_MSG, err)
+		p.recordFailure(err)
 		return err
 	}
 	p.logger.Debugf("Produced msg on Kafka Topic: %v", topic)
@@ -82,7 +65,8 @@ func (p *KafkaProducer) ProduceMessage(topic string, message []byte) error {
 	return nil
 }
 
-func (p *KafkaProducer) ProduceMessageInBatch(topic string, messages [][]byte) error {
+func (p *KafkaProducer) ProduceMessagesInBatch(topic string, messages [][]byte) error {
+	var produceErr error
 	for i := range messages {
 		message := messages[i]
 		msg := kafka.Message{
@@ -95,12 +79,14 @@ func (p *KafkaProducer) ProduceMessageInBatch(topic string, messages [][]byte) e
 
 		err := p.producer.Produce(&msg, nil)
 		if err != nil {
-			p.logger.Fatalf(constants.ERROR_WHILE_PRODUCING_KAFKA_MSG, topic, err)
+			p.logger.Errorf(constants.ERROR_WHILE_PRODUCING_KAFKA_MSG, topic, err)
+			p.recordFailure(err)
+			produceErr = err
 		}
 	}
 	p.logger.Debugf("Produced msg on Kafka Topic: %v. Len:%v", topic, len(messages))
 
-	return nil
+	return produceErr
 }
 
 func (p *KafkaProducer) StartDeliveryReportsHandler() {
@@ -110,14 +96,31 @@ func (p *KafkaProducer) StartDeliveryReportsHandler() {
 			case *kafka.Message:
 				if ev.TopicPartition.Error != nil {
 					p.logger.Errorf("Delivery failed: %v\n", ev.TopicPartition.Error)
+					p.recordFailure(ev.TopicPartition.Error)
 				} else {
-					p.logger.Infof("Delivered message to %v\n", ev.TopicPartition)
+					p.logger.Debugf("Delivered message to %v\n", ev.TopicPartition)
+					if p.health.RecordSuccess() {
+						p.logger.Infof(constants.KAFKA_CLUSTER_RECOVERED, p.cluster)
+					}
 				}
+			case kafka.Error:
+				p.logger.Errorf(constants.KAFKA_CLUSTER_CLIENT_ERROR, p.cluster, ev)
+				p.recordFailure(ev)
 			}
 		}
 	}()
 }
 
+func (p *KafkaProducer) recordFailure(err error) {
+	if p.health.RecordFailure(err) {
+		p.logger.Errorf(constants.KAFKA_CLUSTER_UNHEALTHY, p.cluster, err)
+	}
+}
+
+func (p *KafkaProducer) ClusterHealth() []models.ClusterHealthStatus {
+	return []models.ClusterHealthStatus{p.health.Status()}
+}
+
 func (p *KafkaProducer) Close() {
 	p.producer.Close()
 }
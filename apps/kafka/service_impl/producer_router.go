@@ -0,0 +1,92 @@
+package serviceimpl
+
+import (
+	"parsing-service/apps/kafka/constants"
+	"parsing-service/apps/kafka/models"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+)
+
+// KafkaProducerRouter owns one producer per output cluster and sends every
+// topic to the cluster it is assigned to. Unassigned topics go to the
+// default cluster.
+type KafkaProducerRouter struct {
+	producers     map[string]*KafkaProducer
+	topicClusters map[string]string
+	clusterOrder  []string
+	logger        logger.ILogger
+}
+
+func NewKafkaProducerRouter(cfg *config.Configuration, logger logger.ILogger) (*KafkaProducerRouter, error) {
+	router := &KafkaProducerRouter{
+		producers:     make(map[string]*KafkaProducer),
+		topicClusters: make(map[string]string),
+		logger:        logger,
+	}
+
+	for _, cluster := range cfg.KafkaConfig.OutputClusters {
+		if _, exists := router.producers[cluster.Name]; exists {
+			logger.Errorf(constants.KAFKA_DUPLICATE_CLUSTER, cluster.Name)
+			continue
+		}
+
+		producer, err := NewKafkaProducer(cluster, logger)
+		if err != nil {
+			router.Close()
+			return nil, err
+		}
+		producer.StartDeliveryReportsHandler()
+
+		router.producers[cluster.Name] = producer
+		router.clusterOrder = append(router.clusterOrder, cluster.Name)
+
+		for _, topic := range cluster.Topics {
+			if assigned, exists := router.topicClusters[topic]; exists {
+				logger.Errorf(constants.KAFKA_TOPIC_ASSIGNED_TWICE, topic, assigned, cluster.Name)
+				continue
+			}
+			router.topicClusters[topic] = cluster.Name
+		}
+	}
+
+	return router, nil
+}
+
+func (r *KafkaProducerRouter) producerFor(topic string) *KafkaProducer {
+	if cluster, ok := r.topicClusters[topic]; ok {
+		return r.producers[cluster]
+	}
+	return r.producers[config.DEFAULT_KAFKA_CLUSTER_NAME]
+}
+
+func (r *KafkaProducerRouter) ProduceMessage(topic string, message []byte) error {
+	return r.producerFor(topic).ProduceMessage(topic, message)
+}
+
+func (r *KafkaProducerRouter) ProduceMessagesInBatch(topic string, messages [][]byte) error {
+	return r.producerFor(topic).ProduceMessagesInBatch(topic, messages)
+}
+
+// StartDeliveryReportsHandler is a no-op: the router starts the handler of
+// every cluster producer when it is created.
+func (r *KafkaProducerRouter) StartDeliveryReportsHandler() {}
+
+func (r *KafkaProducerRouter) ClusterHealth() []models.ClusterHealthStatus {
+	statuses := make([]models.ClusterHealthStatus, 0, len(r.clusterOrder))
+	for _, name := range r.clusterOrder {
+		statuses = append(statuses, r.producers[name].ClusterHealth()...)
+	}
+	return statuses
+}
+
+func (r *KafkaProducerRouter) Close() {
+	for _, producer := range r.producers {
+		producer.Close()
+	}
+}
+
+func (r *KafkaProducerRouter) Flush(timeInSeconds uint) {
+	for _, producer := range r.producers {
+		producer.Flush(timeInSeconds)
+	}
+}
@@ -0,0 +1,10 @@
+package serviceinterfaces
+
+import "time"
+
+type IBatchSizer interface {
+	// Next returns the batch size and maximum wait to use for the next poll.
+	Next() (batchSize int, maxWaitMs int)
+	// Observe reports how a polled batch was handled so the next poll can adapt.
+	Observe(batchLen int, processing time.Duration, channelLen int, channelCap int)
+}
@@ -2,4 +2,5 @@ package serviceinterfaces
 
 type IKafkaConsumerFactory interface {
 	CreateConsumer(consumerGroupID string) (IKafkaConsumer, error)
+	CreateBatchSizer() IBatchSizer
 }
\ No newline at end of file
@@ -1,9 +1,12 @@
 package serviceinterfaces
 
+import "parsing-service/apps/kafka/models"
+
 type IKafkaProducer interface {
 	ProduceMessage(topic string, message []byte) error
 	ProduceMessagesInBatch(topic string, messages [][]byte) error
 	StartDeliveryReportsHandler()
+	ClusterHealth() []models.ClusterHealthStatus
 	Close()
 	Flush(timeInSeconds uint)
 }
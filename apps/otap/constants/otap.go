@@ -0,0 +1,35 @@
+package constants
+
+// session states. An upload runs uploading -> processed -> completed; failed
+// and abandoned end it early.
+const (
+	STATE_UPLOADING = "uploading"
+	STATE_PROCESSED = "processed"
+	STATE_COMPLETED = "completed"
+	STATE_FAILED    = "failed"
+	STATE_ABANDONED = "abandoned"
+)
+
+// status the sink answers a successful request with
+const OTAP_STATUS_OK = uint8(0)
+
+// events published on the otap session topic
+const (
+	EVENT_SESSION_STARTED = "session_started"
+	EVENT_STATE_CHANGED   = "state_changed"
+	EVENT_PROGRESS        = "progress"
+)
+
+const (
+	DEFAULT_SESSION_LIST_LIMIT = 100
+	MAX_SESSION_LIST_LIMIT     = 1000
+)
+
+const (
+	ERR_PROCESS_SCRATCHPAD  = "process scratchpad failed with status %d"
+	ERR_SET_OTAP_ACTION     = "set otap action failed with status %d"
+	ERR_SAVING_OTAP_SESSION = "error occurred while saving otap sessions, retrying next batch: %v"
+	ERR_INVALID_SESSION_ID  = "invalid otap session id %q"
+	ERR_SESSION_NOT_FOUND   = "otap session %d not found"
+	ERR_INVALID_QUERY_PARAM = "invalid query param %s: %q"
+)
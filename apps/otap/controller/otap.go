@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	otapConstants "parsing-service/apps/otap/constants"
+	"parsing-service/apps/otap/models"
+	services "parsing-service/apps/otap/service_interfaces"
+	"parsing-service/constants"
+	"parsing-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OtapController struct {
+	otapTracker services.IOtapTracker
+	logger      logger.ILogger
+}
+
+func NewOtapController(
+	otapTrackerIntf services.IOtapTracker,
+	logger logger.ILogger,
+) *OtapController {
+	return &OtapController{
+		otapTracker: otapTrackerIntf,
+		logger:      logger,
+	}
+}
+
+func badRequest(ctx *gin.Context, message string) {
+	ctx.JSON(http.StatusBadRequest, gin.H{constants.ERROR_STRING: message, constants.ERROR_CODE_STRING: constants.INVALID_REQ_PARAMS_CODE})
+}
+
+// ListSessions returns the otap sessions matching the dcuNumber, sinkId,
+// state and limit query params, newest first.
+func (c *OtapController) ListSessions(ctx *gin.Context) {
+	filter := models.OtapSessionFilter{State: ctx.Query("state")}
+
+	if value := ctx.Query("dcuNumber"); value != "" {
+		dcuNumber, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			badRequest(ctx, fmt.Sprintf(otapConstants.ERR_INVALID_QUERY_PARAM, "dcuNumber", value))
+			return
+		}
+		filter.DcuNumber = &dcuNumber
+	}
+	if value := ctx.Query("sinkId"); value != "" {
+		sinkID, err := strconv.ParseInt(value, 10, 16)
+		if err != nil {
+			badRequest(ctx, fmt.Sprintf(otapConstants.ERR_INVALID_QUERY_PARAM, "sinkId", value))
+			return
+		}
+		sink := int16(sinkID)
+		filter.SinkID = &sink
+	}
+	if value := ctx.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			badRequest(ctx, fmt.Sprintf(otapConstants.ERR_INVALID_QUERY_PARAM, "limit", value))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	ctx.JSON(http.StatusOK, c.otapTracker.ListSessions(filter, ctx.GetString(constants.REQUEST_ID)))
+}
+
+func (c *OtapController) GetSession(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		badRequest(ctx, fmt.Sprintf(otapConstants.ERR_INVALID_SESSION_ID, ctx.Param("id")))
+		return
+	}
+
+	session := c.otapTracker.GetSession(id, ctx.GetString(constants.REQUEST_ID))
+	if session == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{constants.ERROR_STRING: fmt.Sprintf(otapConstants.ERR_SESSION_NOT_FOUND, id), constants.ERROR_CODE_STRING: constants.RECORD_NOT_FOUND_ERROR_CODE})
+		return
+	}
+	ctx.JSON(http.StatusOK, session)
+}
@@ -0,0 +1,100 @@
+package daoimpl
+
+import (
+	"errors"
+	"net/http"
+	"parsing-service/apps/otap/models"
+	"parsing-service/constants"
+	customErrorPkg "parsing-service/pkg/custom_error"
+	"parsing-service/pkg/database"
+	"parsing-service/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+type OtapSessionImpl struct {
+	logger logger.ILogger
+}
+
+func NewOtapSessionDAO(logger logger.ILogger) *OtapSessionImpl {
+	return &OtapSessionImpl{logger: logger}
+}
+
+func processingError() *customErrorPkg.CustomError {
+	return customErrorPkg.NewCustomError(
+		errors.New(constants.PROCESSING_ERROR),
+		constants.INTERNAL_SERVER_ERROR_CODE,
+		http.StatusInternalServerError,
+	)
+}
+
+// GetLatestSession returns the most recently started session of a sink, nil
+// if it never had one.
+func (otapSessionDao *OtapSessionImpl) GetLatestSession(dcuNumber int64, sinkID int16, requestID string) *models.OtapSession {
+	var session models.OtapSession
+
+	query := database.DB.Model(&models.OtapSession{}).
+		Where("otap_session.dcu_number = ? AND otap_session.sink_id = ?", dcuNumber, sinkID).
+		Order("otap_session.started_at DESC, otap_session.id DESC")
+
+	err := query.First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		otapSessionDao.logger.Errorf("<GetLatestSession> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+
+	return &session
+}
+
+func (otapSessionDao *OtapSessionImpl) GetSessionByID(id int64, requestID string) *models.OtapSession {
+	var session models.OtapSession
+
+	err := database.DB.Model(&models.OtapSession{}).Where("otap_session.id = ?", id).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		otapSessionDao.logger.Errorf("<GetSessionByID> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+
+	return &session
+}
+
+// ListSessions returns the sessions matching filter, newest first.
+func (otapSessionDao *OtapSessionImpl) ListSessions(filter models.OtapSessionFilter, requestID string) []models.OtapSession {
+	var sessions []models.OtapSession
+
+	query := database.DB.Model(&models.OtapSession{})
+	if filter.DcuNumber != nil {
+		query = query.Where("otap_session.dcu_number = ?", *filter.DcuNumber)
+	}
+	if filter.SinkID != nil {
+		query = query.Where("otap_session.sink_id = ?", *filter.SinkID)
+	}
+	if filter.State != "" {
+		query = query.Where("otap_session.state = ?", filter.State)
+	}
+	query = query.Order("otap_session.started_at DESC, otap_session.id DESC").Limit(filter.Limit)
+
+	err := query.Find(&sessions).Error
+	if err != nil {
+		otapSessionDao.logger.Errorf("<ListSessions> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+
+	return sessions
+}
+
+// SaveSession inserts a new session, setting its ID, or updates an existing
+// one.
+func (otapSessionDao *OtapSessionImpl) SaveSession(session *models.OtapSession, requestID string) {
+	err := database.DB.Save(session).Error
+	if err != nil {
+		otapSessionDao.logger.Errorf("<SaveSession> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+}
@@ -0,0 +1,10 @@
+package daointerfaces
+
+import "parsing-service/apps/otap/models"
+
+type IOtapSessionDAO interface {
+	GetLatestSession(dcuNumber int64, sinkID int16, requestID string) *models.OtapSession
+	GetSessionByID(id int64, requestID string) *models.OtapSession
+	ListSessions(filter models.OtapSessionFilter, requestID string) []models.OtapSession
+	SaveSession(session *models.OtapSession, requestID string)
+}
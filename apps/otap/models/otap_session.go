@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// OtapSession is one scratchpad upload to the sink SinkID of a DCU, as far as
+// the sink's responses tell: how many chunks it took or refused, whether it
+// processed the scratchpad and whether the otap action was set.
+type OtapSession struct {
+	ID        int64  `gorm:"primaryKey" json:"id"`
+	DcuNumber int64  `gorm:"index:idx_otap_session_dcu_sink" json:"dcuNumber"`
+	SinkID    int16  `gorm:"index:idx_otap_session_dcu_sink" json:"sinkId"`
+	State     string `gorm:"type:varchar(20);index" json:"state"`
+
+	ChunksAcked     int32  `json:"chunksAcked"`
+	ChunksFailed    int32  `json:"chunksFailed"`
+	LastChunkStatus *int16 `json:"lastChunkStatus"`
+	ProcessStatus   *int16 `json:"processStatus"`
+	ActionStatus    *int16 `json:"actionStatus"`
+	// raw Get_Otap_Action_Resp body, hex
+	OtapAction    string `gorm:"type:varchar(20)" json:"otapAction,omitempty"`
+	FailureReason string `gorm:"type:varchar(100)" json:"failureReason,omitempty"`
+
+	StartedAt      time.Time  `json:"startedAt"`
+	LastResponseAt time.Time  `json:"lastResponseAt"`
+	CompletedAt    *time.Time `json:"completedAt,omitempty"`
+	// dcu clock of the last response
+	LastDcuTime int64 `json:"lastDcuTime"`
+}
+
+func (OtapSession) TableName() string {
+	return "otap_session"
+}
+
+// OtapSessionEvent is published on the otap session topic whenever a batch
+// changed a session.
+type OtapSessionEvent struct {
+	Event         string      `json:"event"`
+	PreviousState string      `json:"previousState,omitempty"`
+	Session       OtapSession `json:"session"`
+}
+
+// OtapSessionFilter narrows a session listing; zero values match everything.
+type OtapSessionFilter struct {
+	DcuNumber *int64
+	SinkID    *int16
+	State     string
+	Limit     int
+}
@@ -0,0 +1,14 @@
+package serviceinterfaces
+
+import (
+	"parsing-service/apps/otap/models"
+	"parsing-service/pkg/wp"
+	"time"
+)
+
+type IOtapTracker interface {
+	Track(frame wp.Frame, message wp.Message, now time.Time) bool
+	Flush()
+	GetSession(id int64, requestID string) *models.OtapSession
+	ListSessions(filter models.OtapSessionFilter, requestID string) []models.OtapSession
+}
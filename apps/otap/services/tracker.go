@@ -0,0 +1,292 @@
+package services
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/apps/otap/constants"
+	daoInterfaces "parsing-service/apps/otap/dao_interfaces"
+	"parsing-service/apps/otap/models"
+	"parsing-service/pkg/config"
+	customErrorPkg "parsing-service/pkg/custom_error"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/wp"
+)
+
+const trackerRequestID = "otap-tracker"
+
+type sessionKey struct {
+	dcuNumber int64
+	sinkID    int16
+}
+
+// dirtySession is a session changed since the last flush and what it looked
+// like before.
+type dirtySession struct {
+	session       *models.OtapSession
+	previousState string
+	started       bool
+}
+
+// OtapTracker correlates the scratchpad and otap action responses of each
+// DCU sink into sessions. Responses update the sessions in memory; Flush
+// saves the sessions a batch changed and publishes one event per session.
+type OtapTracker struct {
+	dao           daoInterfaces.IOtapSessionDAO
+	KafkaProducer kafkaIntf.IKafkaProducer
+	cfg           *config.Configuration
+	logger        logger.ILogger
+	idleTimeout   time.Duration
+
+	mu sync.Mutex
+	// latest session of every sink seen, nil for sinks without one
+	sessions map[sessionKey]*models.OtapSession
+	dirty    []*dirtySession
+	dirtyIdx map[*models.OtapSession]int
+}
+
+func NewOtapTracker(
+	dao daoInterfaces.IOtapSessionDAO,
+	kafkaProducer kafkaIntf.IKafkaProducer,
+	cfg *config.Configuration,
+	logger logger.ILogger,
+) *OtapTracker {
+	return &OtapTracker{
+		dao:           dao,
+		KafkaProducer: kafkaProducer,
+		cfg:           cfg,
+		logger:        logger,
+		idleTimeout:   time.Duration(cfg.OtapConfig.SessionIdleTimeoutMs) * time.Millisecond,
+		sessions:      make(map[sessionKey]*models.OtapSession),
+		dirtyIdx:      make(map[*models.OtapSession]int),
+	}
+}
+
+func isOtapResponse(messageType wp.MessageType) bool {
+	switch messageType {
+	case wp.SetOtapActionRespMsg, wp.GetOtapActionRespMsg, wp.UploadScratchPadChunkRespMsg, wp.ProcessScratchPadRespMsg:
+		return true
+	}
+	return false
+}
+
+func isTerminal(state string) bool {
+	return state == constants.STATE_COMPLETED || state == constants.STATE_FAILED || state == constants.STATE_ABANDONED
+}
+
+// Track applies an otap response of frame to its sink's session and reports
+// whether message was one. Only a chunk response starts a session, the first
+// of a sink or one after the upload phase. A process response without an
+// open session, or an otap action response of a sink that never had one, is
+// not applied to any.
+func (t *OtapTracker) Track(frame wp.Frame, message wp.Message, now time.Time) bool {
+	if !isOtapResponse(message.Type) {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := sessionKey{dcuNumber: int64(frame.DcuNumber), sinkID: int16(frame.SinkID)}
+	session := t.latestSession(key)
+	if session != nil && t.idle(session, now) {
+		t.abandon(session)
+	}
+
+	var startsNew bool
+	switch message.Type {
+	case wp.UploadScratchPadChunkRespMsg:
+		startsNew = session == nil || session.State != constants.STATE_UPLOADING
+	case wp.ProcessScratchPadRespMsg:
+		if session == nil || isTerminal(session.State) {
+			t.logger.Debugf("<Track> dcu %d sink %d: %s without an open session", key.dcuNumber, key.sinkID, message.Name())
+			return true
+		}
+	default:
+		if session == nil {
+			t.logger.Debugf("<Track> dcu %d sink %d: %s without a session", key.dcuNumber, key.sinkID, message.Name())
+			return true
+		}
+	}
+	if startsNew {
+		session = &models.OtapSession{
+			DcuNumber: key.dcuNumber,
+			SinkID:    key.sinkID,
+			StartedAt: now,
+		}
+		t.sessions[key] = session
+		t.markDirty(session)
+		session.State = constants.STATE_UPLOADING
+	}
+	t.markDirty(session)
+
+	status := int16(message.Status)
+	switch message.Type {
+	case wp.UploadScratchPadChunkRespMsg:
+		if message.Status == constants.OTAP_STATUS_OK {
+			session.ChunksAcked++
+		} else {
+			session.ChunksFailed++
+		}
+		session.LastChunkStatus = &status
+	case wp.ProcessScratchPadRespMsg:
+		session.ProcessStatus = &status
+		if message.Status == constants.OTAP_STATUS_OK {
+			session.State = constants.STATE_PROCESSED
+		} else {
+			session.State = constants.STATE_FAILED
+			session.FailureReason = fmt.Sprintf(constants.ERR_PROCESS_SCRATCHPAD, message.Status)
+		}
+	case wp.SetOtapActionRespMsg:
+		session.ActionStatus = &status
+		if message.Status == constants.OTAP_STATUS_OK {
+			session.State = constants.STATE_COMPLETED
+			completedAt := now
+			session.CompletedAt = &completedAt
+		} else {
+			session.State = constants.STATE_FAILED
+			session.FailureReason = fmt.Sprintf(constants.ERR_SET_OTAP_ACTION, message.Status)
+		}
+	case wp.GetOtapActionRespMsg:
+		session.OtapAction = hex.EncodeToString(message.Body)
+	}
+	session.LastResponseAt = now
+	session.LastDcuTime = int64(frame.DcuTime)
+
+	return true
+}
+
+// latestSession returns the cached session of key, loading it on first use.
+func (t *OtapTracker) latestSession(key sessionKey) (session *models.OtapSession) {
+	if session, ok := t.sessions[key]; ok {
+		return session
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			// the next response of the sink tries again
+			t.logger.Errorf("<latestSession> dcu %d sink %d: %v", key.dcuNumber, key.sinkID, r)
+			session = nil
+		}
+	}()
+	session = t.dao.GetLatestSession(key.dcuNumber, key.sinkID, trackerRequestID)
+	t.sessions[key] = session
+	return session
+}
+
+// idle reports whether an open session has gone without a response for
+// longer than the idle timeout.
+func (t *OtapTracker) idle(session *models.OtapSession, now time.Time) bool {
+	return !isTerminal(session.State) && t.idleTimeout > 0 && now.Sub(session.LastResponseAt) > t.idleTimeout
+}
+
+func (t *OtapTracker) abandon(session *models.OtapSession) {
+	t.markDirty(session)
+	session.State = constants.STATE_ABANDONED
+	session.FailureReason = fmt.Sprintf("no response for %v", t.idleTimeout)
+}
+
+func (t *OtapTracker) markDirty(session *models.OtapSession) {
+	if _, ok := t.dirtyIdx[session]; ok {
+		return
+	}
+	t.dirtyIdx[session] = len(t.dirty)
+	t.dirty = append(t.dirty, &dirtySession{
+		session:       session,
+		previousState: session.State,
+		started:       session.ID == 0,
+	})
+}
+
+// Flush abandons the open sessions whose sink went silent, saves the
+// sessions changed since the last flush and publishes their events. Sessions
+// that fail to save stay dirty for the next flush.
+func (t *OtapTracker) Flush() {
+	t.flush(time.Now())
+}
+
+func (t *OtapTracker) flush(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, session := range t.sessions {
+		if session != nil && t.idle(session, now) {
+			t.abandon(session)
+		}
+	}
+	if len(t.dirty) == 0 {
+		return
+	}
+
+	var events [][]byte
+	var failed []*dirtySession
+	for _, dirty := range t.dirty {
+		if err := t.save(dirty.session); err != nil {
+			t.logger.Errorf(constants.ERR_SAVING_OTAP_SESSION, err)
+			failed = append(failed, dirty)
+			continue
+		}
+
+		event := models.OtapSessionEvent{Session: *dirty.session}
+		switch {
+		case dirty.started:
+			event.Event = constants.EVENT_SESSION_STARTED
+		case dirty.previousState != dirty.session.State:
+			event.Event = constants.EVENT_STATE_CHANGED
+			event.PreviousState = dirty.previousState
+		default:
+			event.Event = constants.EVENT_PROGRESS
+		}
+		if data, err := json.Marshal(event); err == nil {
+			events = append(events, data)
+		}
+
+		// ended sessions are read back from the database if ever needed
+		key := sessionKey{dcuNumber: dirty.session.DcuNumber, sinkID: dirty.session.SinkID}
+		if isTerminal(dirty.session.State) && t.sessions[key] == dirty.session {
+			delete(t.sessions, key)
+		}
+	}
+
+	t.dirty = failed
+	t.dirtyIdx = make(map[*models.OtapSession]int, len(failed))
+	for i, dirty := range failed {
+		t.dirtyIdx[dirty.session] = i
+	}
+
+	if len(events) > 0 {
+		t.logger.Debugf("<Flush> publishing %d otap session event(s)", len(events))
+		go t.KafkaProducer.ProduceMessagesInBatch(t.cfg.KafkaTopicsConfig.PRODUCE_OTAP_SESSION_KAFKA_TOPIC_NAME, events)
+	}
+}
+
+func (t *OtapTracker) save(session *models.OtapSession) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if customError, ok := r.(*customErrorPkg.CustomError); ok {
+				err = customError.GetErrorField()
+				return
+			}
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	t.dao.SaveSession(session, trackerRequestID)
+	return nil
+}
+
+func (t *OtapTracker) GetSession(id int64, requestID string) *models.OtapSession {
+	return t.dao.GetSessionByID(id, requestID)
+}
+
+func (t *OtapTracker) ListSessions(filter models.OtapSessionFilter, requestID string) []models.OtapSession {
+	if filter.Limit <= 0 {
+		filter.Limit = constants.DEFAULT_SESSION_LIST_LIMIT
+	}
+	if filter.Limit > constants.MAX_SESSION_LIST_LIMIT {
+		filter.Limit = constants.MAX_SESSION_LIST_LIMIT
+	}
+	return t.dao.ListSessions(filter, requestID)
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/apps/otap/constants"
+	"parsing-service/apps/otap/models"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/wp"
+)
+
+// memorySessions keeps saved sessions in memory.
+type memorySessions struct {
+	saved  []models.OtapSession
+	nextID int64
+}
+
+func (m *memorySessions) GetLatestSession(dcuNumber int64, sinkID int16, requestID string) *models.OtapSession {
+	return nil
+}
+
+func (m *memorySessions) GetSessionByID(id int64, requestID string) *models.OtapSession {
+	return nil
+}
+
+func (m *memorySessions) ListSessions(filter models.OtapSessionFilter, requestID string) []models.OtapSession {
+	return nil
+}
+
+func (m *memorySessions) SaveSession(session *models.OtapSession, requestID string) {
+	if session.ID == 0 {
+		m.nextID++
+		session.ID = m.nextID
+	}
+	m.saved = append(m.saved, *session)
+}
+
+// eventProducer hands the published batches over on a channel, the other
+// methods are not used by the tests.
+type eventProducer struct {
+	kafkaIntf.IKafkaProducer
+	batches chan [][]byte
+}
+
+func (p *eventProducer) ProduceMessagesInBatch(topic string, messages [][]byte) error {
+	p.batches <- messages
+	return nil
+}
+
+func newTestTracker(idleTimeout time.Duration) (*OtapTracker, *eventProducer) {
+	cfg := &config.Configuration{}
+	cfg.OtapConfig.SessionIdleTimeoutMs = int(idleTimeout / time.Millisecond)
+	producer := &eventProducer{batches: make(chan [][]byte, 8)}
+	return NewOtapTracker(&memorySessions{}, producer, cfg, logger.NewLogger()), producer
+}
+
+func flushedEvents(t *testing.T, tracker *OtapTracker, producer *eventProducer, now time.Time) []models.OtapSessionEvent {
+	t.Helper()
+	tracker.flush(now)
+	select {
+	case batch := <-producer.batches:
+		events := make([]models.OtapSessionEvent, len(batch))
+		for i, data := range batch {
+			if err := json.Unmarshal(data, &events[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return events
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
+
+var otapFrame = wp.Frame{DcuNumber: 300, SinkID: 1}
+
+func TestOtapSilentSinkIsAbandoned(t *testing.T) {
+	tracker, producer := newTestTracker(time.Hour)
+	start := time.Date(2024, 10, 5, 10, 0, 0, 0, time.UTC)
+
+	tracker.Track(otapFrame, wp.Message{Type: wp.UploadScratchPadChunkRespMsg}, start)
+	events := flushedEvents(t, tracker, producer, start)
+	if len(events) != 1 || events[0].Event != constants.EVENT_SESSION_STARTED || events[0].Session.State != constants.STATE_UPLOADING {
+		t.Fatalf("events %+v", events)
+	}
+
+	if events := flushedEvents(t, tracker, producer, start.Add(time.Hour)); len(events) != 0 {
+		t.Fatalf("session changed before the idle timeout: %+v", events)
+	}
+
+	events = flushedEvents(t, tracker, producer, start.Add(time.Hour+time.Second))
+	if len(events) != 1 || events[0].Event != constants.EVENT_STATE_CHANGED || events[0].PreviousState != constants.STATE_UPLOADING || events[0].Session.State != constants.STATE_ABANDONED {
+		t.Fatalf("events %+v", events)
+	}
+	if dao := tracker.dao.(*memorySessions); dao.saved[len(dao.saved)-1].State != constants.STATE_ABANDONED {
+		t.Fatalf("saved %+v", dao.saved[len(dao.saved)-1])
+	}
+
+	// the abandoned session is forgotten, the next chunk starts a new one
+	if events := flushedEvents(t, tracker, producer, start.Add(2*time.Hour)); len(events) != 0 {
+		t.Fatalf("abandoned session published again: %+v", events)
+	}
+	tracker.Track(otapFrame, wp.Message{Type: wp.UploadScratchPadChunkRespMsg}, start.Add(3*time.Hour))
+	events = flushedEvents(t, tracker, producer, start.Add(3*time.Hour))
+	if len(events) != 1 || events[0].Event != constants.EVENT_SESSION_STARTED || events[0].Session.ID != 2 {
+		t.Fatalf("events %+v", events)
+	}
+}
+
+func TestOtapSessionsStartWithAChunk(t *testing.T) {
+	now := time.Date(2024, 10, 5, 10, 0, 0, 0, time.UTC)
+	for _, messageType := range []wp.MessageType{wp.SetOtapActionRespMsg, wp.GetOtapActionRespMsg, wp.ProcessScratchPadRespMsg} {
+		tracker, producer := newTestTracker(time.Hour)
+		if !tracker.Track(otapFrame, wp.Message{Type: messageType}, now) {
+			t.Fatalf("%s not taken for an otap response", messageType)
+		}
+		if events := flushedEvents(t, tracker, producer, now); len(events) != 0 {
+			t.Fatalf("%s without a session: events %+v", messageType, events)
+		}
+	}
+}
+
+func TestOtapSessionRunsToCompletion(t *testing.T) {
+	tracker, producer := newTestTracker(time.Hour)
+	now := time.Date(2024, 10, 5, 10, 0, 0, 0, time.UTC)
+
+	for _, message := range []wp.Message{
+		{Type: wp.UploadScratchPadChunkRespMsg},
+		{Type: wp.UploadScratchPadChunkRespMsg, Status: 3},
+		{Type: wp.UploadScratchPadChunkRespMsg},
+		{Type: wp.ProcessScratchPadRespMsg},
+		{Type: wp.SetOtapActionRespMsg},
+	} {
+		now = now.Add(time.Minute)
+		tracker.Track(otapFrame, message, now)
+	}
+	events := flushedEvents(t, tracker, producer, now)
+	if len(events) != 1 {
+		t.Fatalf("events %+v", events)
+	}
+	session := events[0].Session
+	if session.State != constants.STATE_COMPLETED || session.ChunksAcked != 2 || session.ChunksFailed != 1 || session.CompletedAt == nil {
+		t.Fatalf("session %+v", session)
+	}
+	if len(tracker.sessions) != 0 {
+		t.Fatalf("completed session still cached")
+	}
+}
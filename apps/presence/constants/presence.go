@@ -0,0 +1,20 @@
+package constants
+
+const (
+	KIND_METER = "meter"
+	KIND_DCU   = "dcu"
+)
+
+const (
+	EVENT_OFFLINE = "offline"
+	EVENT_ONLINE  = "online"
+)
+
+const (
+	ERR_SAVING_PRESENCE   = "error occurred while saving last-seen records, retrying next flush: %v"
+	ERR_CHECKING_PRESENCE = "error occurred while checking for silent nodes: %v"
+	ERR_LOOKING_UP_NODE   = "error occurred while looking up %s %s, skipping its sighting: %v"
+	ERR_INVALID_METER_IP  = "invalid meter ip %q"
+	ERR_METER_NOT_FOUND   = "meter %s has never been seen"
+	ERR_DCU_NOT_FOUND     = "dcu %s has never been seen"
+)
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	presenceConstants "parsing-service/apps/presence/constants"
+	services "parsing-service/apps/presence/service_interfaces"
+	"parsing-service/constants"
+	"parsing-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PresenceController struct {
+	presenceRegistry services.IPresenceRegistry
+	logger           logger.ILogger
+}
+
+func NewPresenceController(
+	presenceRegistryIntf services.IPresenceRegistry,
+	logger logger.ILogger,
+) *PresenceController {
+	return &PresenceController{
+		presenceRegistry: presenceRegistryIntf,
+		logger:           logger,
+	}
+}
+
+func notFound(ctx *gin.Context, message string) {
+	ctx.JSON(http.StatusNotFound, gin.H{constants.ERROR_STRING: message, constants.ERROR_CODE_STRING: constants.RECORD_NOT_FOUND_ERROR_CODE})
+}
+
+// GetMeter returns when the meter with IP :ip was first and last heard, and
+// through which DCU and sink.
+func (c *PresenceController) GetMeter(ctx *gin.Context) {
+	meterIp := ctx.Param("ip")
+	if net.ParseIP(meterIp) == nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{constants.ERROR_STRING: fmt.Sprintf(presenceConstants.ERR_INVALID_METER_IP, meterIp), constants.ERROR_CODE_STRING: constants.INVALID_REQ_PARAMS_CODE})
+		return
+	}
+
+	meter := c.presenceRegistry.GetMeter(meterIp, ctx.GetString(constants.REQUEST_ID))
+	if meter == nil {
+		notFound(ctx, fmt.Sprintf(presenceConstants.ERR_METER_NOT_FOUND, meterIp))
+		return
+	}
+	ctx.JSON(http.StatusOK, meter)
+}
+
+// GetDcu returns when the DCU :dcuId was first and last heard.
+func (c *PresenceController) GetDcu(ctx *gin.Context) {
+	dcuID := ctx.Param("dcuId")
+
+	dcu := c.presenceRegistry.GetDcu(dcuID, ctx.GetString(constants.REQUEST_ID))
+	if dcu == nil {
+		notFound(ctx, fmt.Sprintf(presenceConstants.ERR_DCU_NOT_FOUND, dcuID))
+		return
+	}
+	ctx.JSON(http.StatusOK, dcu)
+}
@@ -0,0 +1,130 @@
+package daoimpl
+
+import (
+	"errors"
+	"net/http"
+	"parsing-service/apps/presence/models"
+	"parsing-service/constants"
+	customErrorPkg "parsing-service/pkg/custom_error"
+	"parsing-service/pkg/database"
+	"parsing-service/pkg/logger"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// columns a sighting updates; group_id is provisioned and first_seen_at set
+// once, so an upsert leaves both alone
+var meterSightingColumns = []string{
+	"gateway_mode", "dcu_id", "sink_id", "hop_count", "node_address", "last_cmd_id",
+	"last_seen_at", "online", "status_changed_at",
+}
+
+type PresenceImpl struct {
+	logger logger.ILogger
+}
+
+func NewPresenceDAO(logger logger.ILogger) *PresenceImpl {
+	return &PresenceImpl{logger: logger}
+}
+
+func processingError() *customErrorPkg.CustomError {
+	return customErrorPkg.NewCustomError(
+		errors.New(constants.PROCESSING_ERROR),
+		constants.INTERNAL_SERVER_ERROR_CODE,
+		http.StatusInternalServerError,
+	)
+}
+
+func (presenceDao *PresenceImpl) GetMeter(meterIp string, requestID string) *models.MeterPresence {
+	var meter models.MeterPresence
+
+	err := database.DB.Model(&models.MeterPresence{}).Where("meter_presence.meter_ip = ?", meterIp).First(&meter).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		presenceDao.logger.Errorf("<GetMeter> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+
+	return &meter
+}
+
+func (presenceDao *PresenceImpl) GetDcu(dcuID string, requestID string) *models.DcuPresence {
+	var dcu models.DcuPresence
+
+	err := database.DB.Model(&models.DcuPresence{}).Where("dcu_presence.dcu_id = ?", dcuID).First(&dcu).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		presenceDao.logger.Errorf("<GetDcu> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+
+	return &dcu
+}
+
+// SaveMeters upserts meters in one statement. Existing rows only get their
+// sighting columns updated.
+func (presenceDao *PresenceImpl) SaveMeters(meters []models.MeterPresence, requestID string) {
+	if len(meters) == 0 {
+		return
+	}
+	err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "meter_ip"}},
+		DoUpdates: clause.AssignmentColumns(meterSightingColumns),
+	}).Create(&meters).Error
+	if err != nil {
+		presenceDao.logger.Errorf("<SaveMeters> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+}
+
+// SaveDcus upserts dcus in one statement.
+func (presenceDao *PresenceImpl) SaveDcus(dcus []models.DcuPresence, requestID string) {
+	if len(dcus) == 0 {
+		return
+	}
+	err := database.DB.Save(&dcus).Error
+	if err != nil {
+		presenceDao.logger.Errorf("<SaveDcus> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+}
+
+// ListSilentMeters returns the meters still online but not heard since
+// lastSeenBefore.
+func (presenceDao *PresenceImpl) ListSilentMeters(lastSeenBefore time.Time, requestID string) []models.MeterPresence {
+	var meters []models.MeterPresence
+
+	query := database.DB.Model(&models.MeterPresence{}).
+		Where("meter_presence.online = ? AND meter_presence.last_seen_at < ?", true, lastSeenBefore)
+
+	err := query.Find(&meters).Error
+	if err != nil {
+		presenceDao.logger.Errorf("<ListSilentMeters> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+
+	return meters
+}
+
+// ListSilentDcus returns the dcus still online but not heard since
+// lastSeenBefore.
+func (presenceDao *PresenceImpl) ListSilentDcus(lastSeenBefore time.Time, requestID string) []models.DcuPresence {
+	var dcus []models.DcuPresence
+
+	query := database.DB.Model(&models.DcuPresence{}).
+		Where("dcu_presence.online = ? AND dcu_presence.last_seen_at < ?", true, lastSeenBefore)
+
+	err := query.Find(&dcus).Error
+	if err != nil {
+		presenceDao.logger.Errorf("<ListSilentDcus> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+
+	return dcus
+}
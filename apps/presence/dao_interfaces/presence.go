@@ -0,0 +1,15 @@
+package daointerfaces
+
+import (
+	"parsing-service/apps/presence/models"
+	"time"
+)
+
+type IPresenceDAO interface {
+	GetMeter(meterIp string, requestID string) *models.MeterPresence
+	GetDcu(dcuID string, requestID string) *models.DcuPresence
+	SaveMeters(meters []models.MeterPresence, requestID string)
+	SaveDcus(dcus []models.DcuPresence, requestID string)
+	ListSilentMeters(lastSeenBefore time.Time, requestID string) []models.MeterPresence
+	ListSilentDcus(lastSeenBefore time.Time, requestID string) []models.DcuPresence
+}
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// MeterPresence is when a meter was first and last heard and what it last
+// sent. SinkID, HopCount and NodeAddress are only known for meters behind a
+// WP gateway. GroupID is the firmware group of the meter; it is provisioned
+// in the table, sightings never change it.
+type MeterPresence struct {
+	MeterIp     string `gorm:"primaryKey;type:varchar(15)" json:"meterIp"`
+	GatewayMode string `gorm:"type:varchar(10)" json:"gatewayMode"`
+	DcuID       string `gorm:"type:varchar(30);index" json:"dcuId"`
+	SinkID      *int16 `json:"sinkId,omitempty"`
+	HopCount    *int16 `json:"hopCount,omitempty"`
+	NodeAddress *int64 `json:"nodeAddress,omitempty"`
+	LastCmdID   int32  `json:"lastCmdId"`
+	GroupID     *int32 `json:"groupId,omitempty"`
+
+	FirstSeenAt time.Time `json:"firstSeenAt"`
+	LastSeenAt  time.Time `gorm:"index" json:"lastSeenAt"`
+	Online      bool      `gorm:"index" json:"online"`
+	// when Online last flipped
+	StatusChangedAt *time.Time `json:"statusChangedAt,omitempty"`
+}
+
+func (MeterPresence) TableName() string {
+	return "meter_presence"
+}
+
+// DcuPresence is when a DCU was first and last heard from. DcuID is the WP
+// DCU number, or the DCU key of an IRDA message.
+type DcuPresence struct {
+	DcuID       string `gorm:"primaryKey;type:varchar(30)" json:"dcuId"`
+	GatewayMode string `gorm:"type:varchar(10)" json:"gatewayMode"`
+	SinkID      *int16 `json:"sinkId,omitempty"`
+	LastCmdID   *int32 `json:"lastCmdId,omitempty"`
+
+	FirstSeenAt     time.Time  `json:"firstSeenAt"`
+	LastSeenAt      time.Time  `gorm:"index" json:"lastSeenAt"`
+	Online          bool       `gorm:"index" json:"online"`
+	StatusChangedAt *time.Time `json:"statusChangedAt,omitempty"`
+}
+
+func (DcuPresence) TableName() string {
+	return "dcu_presence"
+}
+
+// MeterSighting is one TAP packet of a meter.
+type MeterSighting struct {
+	MeterIp     string
+	GatewayMode string
+	DcuID       string
+	CmdID       int
+	SinkID      *int16
+	HopCount    *int16
+	NodeAddress *int64
+	At          time.Time
+}
+
+// DcuSighting is one message from a DCU.
+type DcuSighting struct {
+	DcuID       string
+	GatewayMode string
+	SinkID      *int16
+	At          time.Time
+}
+
+// PresenceEvent is published on the presence topic when a meter or DCU goes
+// offline after staying silent past its window, or is heard again.
+type PresenceEvent struct {
+	Event      string    `json:"event"`
+	Kind       string    `json:"kind"`
+	ID         string    `json:"id"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	At         time.Time `json:"at"`
+	// how long the node was silent when it went offline or came back
+	SilentForMs int64 `json:"silentForMs"`
+}
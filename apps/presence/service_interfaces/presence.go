@@ -0,0 +1,16 @@
+package serviceinterfaces
+
+import (
+	"parsing-service/apps/presence/models"
+	"time"
+)
+
+type IPresenceRegistry interface {
+	SeeMeter(sighting models.MeterSighting)
+	SeeDcu(sighting models.DcuSighting)
+	Flush()
+	StartScheduler()
+	GetMeter(meterIp string, requestID string) *models.MeterPresence
+	GetMeterGroup(meterIp string, now time.Time) (*int32, error)
+	GetDcu(dcuID string, requestID string) *models.DcuPresence
+}
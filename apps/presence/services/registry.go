@@ -0,0 +1,407 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/apps/presence/constants"
+	daoInterfaces "parsing-service/apps/presence/dao_interfaces"
+	"parsing-service/apps/presence/models"
+	"parsing-service/pkg/config"
+	customErrorPkg "parsing-service/pkg/custom_error"
+	"parsing-service/pkg/logger"
+)
+
+const registryRequestID = "presence-registry"
+
+// PresenceRegistry remembers when every meter and DCU was last heard.
+// Sightings update it in memory and are written behind to Postgres, at most
+// every FlushIntervalMs or once MaxBuffered nodes are waiting. A scheduler
+// takes nodes silent past their window offline; hearing them again brings
+// them back online.
+type PresenceRegistry struct {
+	dao           daoInterfaces.IPresenceDAO
+	KafkaProducer kafkaIntf.IKafkaProducer
+	cfg           *config.Configuration
+	logger        logger.ILogger
+
+	mu sync.Mutex
+	// every node looked up so far, nil for nodes never seen before
+	meters      map[string]*models.MeterPresence
+	dcus        map[string]*models.DcuPresence
+	dirtyMeters map[string]*models.MeterPresence
+	dirtyDcus   map[string]*models.DcuPresence
+	lastWrite   time.Time
+	events      []models.PresenceEvent
+	groups      map[string]meterGroup
+
+	startScheduler sync.Once
+}
+
+func NewPresenceRegistry(
+	dao daoInterfaces.IPresenceDAO,
+	kafkaProducer kafkaIntf.IKafkaProducer,
+	cfg *config.Configuration,
+	logger logger.ILogger,
+) *PresenceRegistry {
+	return &PresenceRegistry{
+		dao:           dao,
+		KafkaProducer: kafkaProducer,
+		cfg:           cfg,
+		logger:        logger,
+		meters:        make(map[string]*models.MeterPresence),
+		dcus:          make(map[string]*models.DcuPresence),
+		dirtyMeters:   make(map[string]*models.MeterPresence),
+		dirtyDcus:     make(map[string]*models.DcuPresence),
+		lastWrite:     time.Now(),
+		groups:        make(map[string]meterGroup),
+	}
+}
+
+// meterGroup is the provisioned group of a meter as last read.
+type meterGroup struct {
+	groupID *int32
+	readAt  time.Time
+}
+
+func onlineEvent(kind string, id string, lastSeenAt time.Time, at time.Time) models.PresenceEvent {
+	return models.PresenceEvent{
+		Event:       constants.EVENT_ONLINE,
+		Kind:        kind,
+		ID:          id,
+		LastSeenAt:  lastSeenAt,
+		At:          at,
+		SilentForMs: at.Sub(lastSeenAt).Milliseconds(),
+	}
+}
+
+// SeeMeter records a TAP packet of a meter, and its command id on the DCU it
+// came through. A sighting whose record cannot be looked up is skipped
+// rather than taken for a first sighting.
+func (r *PresenceRegistry) SeeMeter(sighting models.MeterSighting) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seeMeter(sighting)
+	if sighting.DcuID == "" {
+		return
+	}
+	dcu := r.seeDcu(models.DcuSighting{DcuID: sighting.DcuID, GatewayMode: sighting.GatewayMode, SinkID: sighting.SinkID, At: sighting.At})
+	if dcu == nil {
+		return
+	}
+	cmdID := int32(sighting.CmdID)
+	dcu.LastCmdID = &cmdID
+}
+
+func (r *PresenceRegistry) seeMeter(sighting models.MeterSighting) {
+	meter, err := r.meter(sighting.MeterIp)
+	if err != nil {
+		r.logger.Errorf(constants.ERR_LOOKING_UP_NODE, constants.KIND_METER, sighting.MeterIp, err)
+		return
+	}
+	if meter == nil {
+		meter = &models.MeterPresence{MeterIp: sighting.MeterIp, FirstSeenAt: sighting.At, Online: true}
+		r.meters[sighting.MeterIp] = meter
+	} else if !meter.Online {
+		r.events = append(r.events, onlineEvent(constants.KIND_METER, meter.MeterIp, meter.LastSeenAt, sighting.At))
+		changedAt := sighting.At
+		meter.Online = true
+		meter.StatusChangedAt = &changedAt
+	}
+	meter.GatewayMode = sighting.GatewayMode
+	meter.DcuID = sighting.DcuID
+	meter.SinkID = sighting.SinkID
+	meter.HopCount = sighting.HopCount
+	if sighting.NodeAddress != nil {
+		meter.NodeAddress = sighting.NodeAddress
+	}
+	meter.LastCmdID = int32(sighting.CmdID)
+	meter.LastSeenAt = sighting.At
+	r.dirtyMeters[meter.MeterIp] = meter
+}
+
+// SeeDcu records a message from a DCU.
+func (r *PresenceRegistry) SeeDcu(sighting models.DcuSighting) {
+	if sighting.DcuID == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seeDcu(sighting)
+}
+
+// seeDcu records a DCU sighting and returns the DCU's record, nil when it
+// cannot be looked up.
+func (r *PresenceRegistry) seeDcu(sighting models.DcuSighting) *models.DcuPresence {
+	dcu, err := r.dcu(sighting.DcuID)
+	if err != nil {
+		r.logger.Errorf(constants.ERR_LOOKING_UP_NODE, constants.KIND_DCU, sighting.DcuID, err)
+		return nil
+	}
+	if dcu == nil {
+		dcu = &models.DcuPresence{DcuID: sighting.DcuID, FirstSeenAt: sighting.At, Online: true}
+		r.dcus[sighting.DcuID] = dcu
+	} else if !dcu.Online {
+		r.events = append(r.events, onlineEvent(constants.KIND_DCU, dcu.DcuID, dcu.LastSeenAt, sighting.At))
+		changedAt := sighting.At
+		dcu.Online = true
+		dcu.StatusChangedAt = &changedAt
+	}
+	dcu.GatewayMode = sighting.GatewayMode
+	if sighting.SinkID != nil {
+		dcu.SinkID = sighting.SinkID
+	}
+	dcu.LastSeenAt = sighting.At
+	r.dirtyDcus[dcu.DcuID] = dcu
+	return dcu
+}
+
+// meter returns the cached record of a meter, loading it on first use. A
+// failed lookup is not cached, so the next packet of the meter tries again.
+func (r *PresenceRegistry) meter(meterIp string) (*models.MeterPresence, error) {
+	if meter, ok := r.meters[meterIp]; ok {
+		return meter, nil
+	}
+	var meter *models.MeterPresence
+	if err := recoverError(func() { meter = r.dao.GetMeter(meterIp, registryRequestID) }); err != nil {
+		return nil, err
+	}
+	r.meters[meterIp] = meter
+	return meter, nil
+}
+
+// dcu returns the cached record of a DCU, loading it on first use. A failed
+// lookup is not cached, so the next message of the DCU tries again.
+func (r *PresenceRegistry) dcu(dcuID string) (*models.DcuPresence, error) {
+	if dcu, ok := r.dcus[dcuID]; ok {
+		return dcu, nil
+	}
+	var dcu *models.DcuPresence
+	if err := recoverError(func() { dcu = r.dao.GetDcu(dcuID, registryRequestID) }); err != nil {
+		return nil, err
+	}
+	r.dcus[dcuID] = dcu
+	return dcu, nil
+}
+
+// Flush writes the buffered nodes when the write-behind interval is up or
+// the buffer is full, and publishes the online events.
+func (r *PresenceRegistry) Flush() {
+	r.flush(time.Now())
+}
+
+func (r *PresenceRegistry) flush(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	presenceCfg := r.cfg.PresenceConfig
+	buffered := len(r.dirtyMeters) + len(r.dirtyDcus)
+	if now.Sub(r.lastWrite) >= time.Duration(presenceCfg.FlushIntervalMs)*time.Millisecond ||
+		(presenceCfg.MaxBuffered > 0 && buffered >= presenceCfg.MaxBuffered) {
+		r.write(now)
+	}
+	r.publish()
+}
+
+// write saves the buffered nodes. Nodes that fail to save stay buffered for
+// the next write.
+func (r *PresenceRegistry) write(now time.Time) error {
+	r.lastWrite = now
+
+	meters := make([]models.MeterPresence, 0, len(r.dirtyMeters))
+	for _, meter := range r.dirtyMeters {
+		meters = append(meters, *meter)
+	}
+	dcus := make([]models.DcuPresence, 0, len(r.dirtyDcus))
+	for _, dcu := range r.dirtyDcus {
+		dcus = append(dcus, *dcu)
+	}
+
+	err := recoverError(func() {
+		r.dao.SaveMeters(meters, registryRequestID)
+		r.dirtyMeters = make(map[string]*models.MeterPresence)
+		r.dao.SaveDcus(dcus, registryRequestID)
+		r.dirtyDcus = make(map[string]*models.DcuPresence)
+	})
+	if err != nil {
+		r.logger.Errorf(constants.ERR_SAVING_PRESENCE, err)
+	}
+	return err
+}
+
+func (r *PresenceRegistry) publish() {
+	if len(r.events) == 0 {
+		return
+	}
+	events := make([][]byte, 0, len(r.events))
+	for _, event := range r.events {
+		if data, err := json.Marshal(event); err == nil {
+			events = append(events, data)
+		}
+	}
+	r.events = nil
+	r.logger.Debugf("<publish> %d presence events", len(events))
+	go r.KafkaProducer.ProduceMessagesInBatch(r.cfg.KafkaTopicsConfig.PRODUCE_PRESENCE_KAFKA_TOPIC_NAME, events)
+}
+
+// recoverError runs fn and returns the error a DAO panicked with, if any.
+func recoverError(fn func()) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			if customError, ok := rec.(*customErrorPkg.CustomError); ok {
+				err = customError.GetErrorField()
+				return
+			}
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+	fn()
+	return nil
+}
+
+// StartScheduler checks for silent nodes every CheckIntervalMs in the
+// background. Calling it again has no effect.
+func (r *PresenceRegistry) StartScheduler() {
+	interval := time.Duration(r.cfg.PresenceConfig.CheckIntervalMs) * time.Millisecond
+	if interval <= 0 {
+		return
+	}
+	r.startScheduler.Do(func() {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for now := range ticker.C {
+				r.checkSilent(now)
+			}
+		}()
+	})
+}
+
+// checkSilent takes the nodes not heard within their offline window offline.
+// The buffer is written first so the database knows every sighting; silent
+// nodes are then read from it, which also catches nodes not heard since the
+// last restart.
+func (r *PresenceRegistry) checkSilent(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.write(now); err != nil {
+		return
+	}
+
+	presenceCfg := r.cfg.PresenceConfig
+	err := recoverError(func() {
+		if presenceCfg.MeterOfflineAfterMs > 0 {
+			cutoff := now.Add(-time.Duration(presenceCfg.MeterOfflineAfterMs) * time.Millisecond)
+			silent := r.dao.ListSilentMeters(cutoff, registryRequestID)
+			for i := range silent {
+				meter := &silent[i]
+				if cached := r.meters[meter.MeterIp]; cached != nil {
+					meter = cached
+				}
+				r.events = append(r.events, offlineEvent(constants.KIND_METER, meter.MeterIp, meter.LastSeenAt, now))
+				changedAt := now
+				meter.Online = false
+				meter.StatusChangedAt = &changedAt
+				r.meters[meter.MeterIp] = meter
+				r.dirtyMeters[meter.MeterIp] = meter
+			}
+		}
+		if presenceCfg.DcuOfflineAfterMs > 0 {
+			cutoff := now.Add(-time.Duration(presenceCfg.DcuOfflineAfterMs) * time.Millisecond)
+			silent := r.dao.ListSilentDcus(cutoff, registryRequestID)
+			for i := range silent {
+				dcu := &silent[i]
+				if cached := r.dcus[dcu.DcuID]; cached != nil {
+					dcu = cached
+				}
+				r.events = append(r.events, offlineEvent(constants.KIND_DCU, dcu.DcuID, dcu.LastSeenAt, now))
+				changedAt := now
+				dcu.Online = false
+				dcu.StatusChangedAt = &changedAt
+				r.dcus[dcu.DcuID] = dcu
+				r.dirtyDcus[dcu.DcuID] = dcu
+			}
+		}
+	})
+	if err != nil {
+		r.logger.Errorf(constants.ERR_CHECKING_PRESENCE, err)
+	}
+
+	r.write(now)
+	r.publish()
+}
+
+func offlineEvent(kind string, id string, lastSeenAt time.Time, at time.Time) models.PresenceEvent {
+	return models.PresenceEvent{
+		Event:       constants.EVENT_OFFLINE,
+		Kind:        kind,
+		ID:          id,
+		LastSeenAt:  lastSeenAt,
+		At:          at,
+		SilentForMs: at.Sub(lastSeenAt).Milliseconds(),
+	}
+}
+
+// GetMeterGroup returns the group provisioned for a meter, nil when it has
+// none. Groups are provisioned in the table behind the registry's back, so
+// they are read again once GroupRefreshIntervalMs old; when that read fails
+// the group last read is kept.
+func (r *PresenceRegistry) GetMeterGroup(meterIp string, now time.Time) (*int32, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	refreshInterval := time.Duration(r.cfg.PresenceConfig.GroupRefreshIntervalMs) * time.Millisecond
+	group, ok := r.groups[meterIp]
+	if ok && now.Sub(group.readAt) < refreshInterval {
+		return group.groupID, nil
+	}
+
+	var meter *models.MeterPresence
+	if err := recoverError(func() { meter = r.dao.GetMeter(meterIp, registryRequestID) }); err != nil {
+		if ok {
+			return group.groupID, nil
+		}
+		return nil, err
+	}
+	group = meterGroup{readAt: now}
+	if meter != nil {
+		group.groupID = meter.GroupID
+	}
+	r.groups[meterIp] = group
+	if cached := r.meters[meterIp]; cached != nil {
+		cached.GroupID = group.groupID
+	}
+	return group.groupID, nil
+}
+
+// GetMeter returns a meter's record, from memory when it has been seen since
+// the last restart.
+func (r *PresenceRegistry) GetMeter(meterIp string, requestID string) *models.MeterPresence {
+	r.mu.Lock()
+	if meter := r.meters[meterIp]; meter != nil {
+		copied := *meter
+		r.mu.Unlock()
+		return &copied
+	}
+	r.mu.Unlock()
+	return r.dao.GetMeter(meterIp, requestID)
+}
+
+// GetDcu returns a DCU's record, from memory when it has been seen since the
+// last restart.
+func (r *PresenceRegistry) GetDcu(dcuID string, requestID string) *models.DcuPresence {
+	r.mu.Lock()
+	if dcu := r.dcus[dcuID]; dcu != nil {
+		copied := *dcu
+		r.mu.Unlock()
+		return &copied
+	}
+	r.mu.Unlock()
+	return r.dao.GetDcu(dcuID, requestID)
+}
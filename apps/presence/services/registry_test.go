@@ -0,0 +1,268 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/apps/presence/constants"
+	"parsing-service/apps/presence/models"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+)
+
+// memoryPresence keeps saved records in memory. Lookups panic while
+// failLookups is set, as the DAO does on a database error.
+type memoryPresence struct {
+	meters      map[string]models.MeterPresence
+	dcus        map[string]models.DcuPresence
+	failLookups bool
+}
+
+func newMemoryPresence() *memoryPresence {
+	return &memoryPresence{meters: make(map[string]models.MeterPresence), dcus: make(map[string]models.DcuPresence)}
+}
+
+func (m *memoryPresence) GetMeter(meterIp string, requestID string) *models.MeterPresence {
+	if m.failLookups {
+		panic(errors.New("database unavailable"))
+	}
+	if meter, ok := m.meters[meterIp]; ok {
+		return &meter
+	}
+	return nil
+}
+
+func (m *memoryPresence) GetDcu(dcuID string, requestID string) *models.DcuPresence {
+	if m.failLookups {
+		panic(errors.New("database unavailable"))
+	}
+	if dcu, ok := m.dcus[dcuID]; ok {
+		return &dcu
+	}
+	return nil
+}
+
+// SaveMeters keeps the provisioned group and first sighting of stored
+// meters, as the upsert does.
+func (m *memoryPresence) SaveMeters(meters []models.MeterPresence, requestID string) {
+	for _, meter := range meters {
+		if stored, ok := m.meters[meter.MeterIp]; ok {
+			meter.GroupID = stored.GroupID
+			meter.FirstSeenAt = stored.FirstSeenAt
+		}
+		m.meters[meter.MeterIp] = meter
+	}
+}
+
+func (m *memoryPresence) SaveDcus(dcus []models.DcuPresence, requestID string) {
+	for _, dcu := range dcus {
+		m.dcus[dcu.DcuID] = dcu
+	}
+}
+
+func (m *memoryPresence) ListSilentMeters(lastSeenBefore time.Time, requestID string) []models.MeterPresence {
+	var silent []models.MeterPresence
+	for _, meter := range m.meters {
+		if meter.Online && meter.LastSeenAt.Before(lastSeenBefore) {
+			silent = append(silent, meter)
+		}
+	}
+	return silent
+}
+
+func (m *memoryPresence) ListSilentDcus(lastSeenBefore time.Time, requestID string) []models.DcuPresence {
+	var silent []models.DcuPresence
+	for _, dcu := range m.dcus {
+		if dcu.Online && dcu.LastSeenAt.Before(lastSeenBefore) {
+			silent = append(silent, dcu)
+		}
+	}
+	return silent
+}
+
+// eventProducer hands the published batches over on a channel, the other
+// methods are not used by the tests.
+type eventProducer struct {
+	kafkaIntf.IKafkaProducer
+	batches chan [][]byte
+}
+
+func (p *eventProducer) ProduceMessagesInBatch(topic string, messages [][]byte) error {
+	p.batches <- messages
+	return nil
+}
+
+func newTestRegistry(dao *memoryPresence, start time.Time) (*PresenceRegistry, *eventProducer) {
+	cfg := &config.Configuration{}
+	cfg.PresenceConfig = config.PresenceConfig{
+		FlushIntervalMs:     int(time.Minute / time.Millisecond),
+		MeterOfflineAfterMs: int(time.Hour / time.Millisecond),
+		DcuOfflineAfterMs:   int(15 * time.Minute / time.Millisecond),
+	}
+	producer := &eventProducer{batches: make(chan [][]byte, 8)}
+	registry := NewPresenceRegistry(dao, producer, cfg, logger.NewLogger())
+	registry.lastWrite = start
+	return registry, producer
+}
+
+func publishedEvents(t *testing.T, producer *eventProducer) []models.PresenceEvent {
+	t.Helper()
+	select {
+	case batch := <-producer.batches:
+		events := make([]models.PresenceEvent, len(batch))
+		for i, data := range batch {
+			if err := json.Unmarshal(data, &events[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return events
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
+
+func meterSighting(meterIp string, at time.Time) models.MeterSighting {
+	return models.MeterSighting{MeterIp: meterIp, GatewayMode: "WP", DcuID: "300", CmdID: 7, At: at}
+}
+
+func TestPresenceOfflineAndOnline(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	dao := newMemoryPresence()
+	registry, producer := newTestRegistry(dao, start)
+
+	registry.SeeMeter(meterSighting("10.0.0.1", start))
+	registry.SeeMeter(meterSighting("10.0.0.2", start.Add(50*time.Minute)))
+	registry.SeeDcu(models.DcuSighting{DcuID: "300", GatewayMode: "WP", At: start.Add(50 * time.Minute)})
+
+	registry.checkSilent(start.Add(61 * time.Minute))
+	events := publishedEvents(t, producer)
+	if len(events) != 1 || events[0].Event != constants.EVENT_OFFLINE || events[0].ID != "10.0.0.1" ||
+		events[0].SilentForMs != (61*time.Minute).Milliseconds() {
+		t.Fatalf("events %+v, want meter 10.0.0.1 offline after 61 minutes", events)
+	}
+	if meter := registry.GetMeter("10.0.0.1", "test"); meter.Online || !meter.StatusChangedAt.Equal(start.Add(61*time.Minute)) {
+		t.Fatalf("meter %+v, want offline since the check", meter)
+	}
+
+	// an offline node is not taken offline again
+	registry.checkSilent(start.Add(62 * time.Minute))
+	if events := publishedEvents(t, producer); len(events) != 0 {
+		t.Fatalf("events %+v on the second check", events)
+	}
+
+	registry.SeeMeter(meterSighting("10.0.0.1", start.Add(70*time.Minute)))
+	registry.flush(start.Add(70 * time.Minute))
+	events = publishedEvents(t, producer)
+	if len(events) != 1 || events[0].Event != constants.EVENT_ONLINE || events[0].ID != "10.0.0.1" {
+		t.Fatalf("events %+v, want meter 10.0.0.1 back online", events)
+	}
+	if meter := dao.meters["10.0.0.1"]; !meter.Online || !meter.FirstSeenAt.Equal(start) || meter.LastCmdID != 7 {
+		t.Fatalf("saved meter %+v, want online and first seen at the start", meter)
+	}
+}
+
+func TestPresenceDcuOffline(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	dao := newMemoryPresence()
+	registry, producer := newTestRegistry(dao, start)
+
+	registry.SeeMeter(meterSighting("10.0.0.1", start))
+	registry.checkSilent(start.Add(16 * time.Minute))
+	events := publishedEvents(t, producer)
+	if len(events) != 1 || events[0].Kind != constants.KIND_DCU || events[0].Event != constants.EVENT_OFFLINE {
+		t.Fatalf("events %+v, want dcu 300 offline", events)
+	}
+	if dcu := dao.dcus["300"]; dcu.Online || dcu.LastCmdID == nil || *dcu.LastCmdID != 7 {
+		t.Fatalf("saved dcu %+v, want offline with the meter's command id", dcu)
+	}
+}
+
+// a node heard before the restart is read back from the database, also when
+// it was offline
+func TestPresenceStoredNodeComesBackOnline(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	dao := newMemoryPresence()
+	dao.meters["10.0.0.1"] = models.MeterPresence{MeterIp: "10.0.0.1", FirstSeenAt: start, LastSeenAt: start}
+	registry, producer := newTestRegistry(dao, start)
+
+	registry.SeeMeter(meterSighting("10.0.0.1", start.Add(2*time.Hour)))
+	registry.flush(start.Add(2 * time.Hour))
+	if events := publishedEvents(t, producer); len(events) != 1 || events[0].Event != constants.EVENT_ONLINE {
+		t.Fatalf("events %+v, want the stored meter back online", events)
+	}
+	if meter := dao.meters["10.0.0.1"]; !meter.FirstSeenAt.Equal(start) {
+		t.Fatalf("first seen at %v, want %v", meter.FirstSeenAt, start)
+	}
+}
+
+func TestPresenceSkipsFailedLookups(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	dao := newMemoryPresence()
+	dao.meters["10.0.0.1"] = models.MeterPresence{MeterIp: "10.0.0.1", FirstSeenAt: start, LastSeenAt: start, Online: true}
+	registry, _ := newTestRegistry(dao, start)
+
+	dao.failLookups = true
+	registry.SeeMeter(meterSighting("10.0.0.1", start.Add(time.Hour)))
+	registry.flush(start.Add(time.Hour))
+	if meter := dao.meters["10.0.0.1"]; !meter.LastSeenAt.Equal(start) {
+		t.Fatalf("saved meter %+v, want the failed sighting skipped", meter)
+	}
+	if len(dao.dcus) != 0 {
+		t.Fatalf("saved dcus %+v after a failed lookup", dao.dcus)
+	}
+
+	// the lookup is retried on the next sighting
+	dao.failLookups = false
+	registry.SeeMeter(meterSighting("10.0.0.1", start.Add(2*time.Hour)))
+	registry.flush(start.Add(2 * time.Hour))
+	if meter := dao.meters["10.0.0.1"]; !meter.FirstSeenAt.Equal(start) || !meter.LastSeenAt.Equal(start.Add(2*time.Hour)) {
+		t.Fatalf("saved meter %+v, want its first sighting kept", meter)
+	}
+}
+
+func TestPresenceGroupIsReadAgain(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	dao := newMemoryPresence()
+	registry, _ := newTestRegistry(dao, start)
+	registry.cfg.PresenceConfig.GroupRefreshIntervalMs = int(5 * time.Minute / time.Millisecond)
+
+	registry.SeeMeter(meterSighting("10.0.0.1", start))
+	if groupID, err := registry.GetMeterGroup("10.0.0.1", start); err != nil || groupID != nil {
+		t.Fatalf("group %v, %v, want none", groupID, err)
+	}
+
+	// the group is provisioned in the table
+	provisioned := int32(3)
+	meter := dao.meters["10.0.0.1"]
+	meter.GroupID = &provisioned
+	dao.meters["10.0.0.1"] = meter
+
+	registry.SeeMeter(meterSighting("10.0.0.1", start.Add(2*time.Minute)))
+	registry.flush(start.Add(2 * time.Minute))
+	if meter := dao.meters["10.0.0.1"]; meter.GroupID == nil || *meter.GroupID != 3 {
+		t.Fatalf("saved meter %+v, want the provisioned group kept", meter)
+	}
+
+	if groupID, _ := registry.GetMeterGroup("10.0.0.1", start.Add(4*time.Minute)); groupID != nil {
+		t.Fatalf("group %d read again within the refresh interval", *groupID)
+	}
+	groupID, err := registry.GetMeterGroup("10.0.0.1", start.Add(5*time.Minute))
+	if err != nil || groupID == nil || *groupID != 3 {
+		t.Fatalf("group %v, %v, want 3", groupID, err)
+	}
+	if meter := registry.GetMeter("10.0.0.1", "test"); meter.GroupID == nil || *meter.GroupID != 3 {
+		t.Fatalf("cached meter %+v, want group 3", meter)
+	}
+
+	// a failed read keeps the group last read
+	dao.failLookups = true
+	if groupID, err := registry.GetMeterGroup("10.0.0.1", start.Add(time.Hour)); err != nil || groupID == nil || *groupID != 3 {
+		t.Fatalf("group %v, %v, want 3 kept", groupID, err)
+	}
+	if _, err := registry.GetMeterGroup("10.0.0.2", start.Add(time.Hour)); err == nil {
+		t.Fatal("a failed read of a meter never read returned no error")
+	}
+}
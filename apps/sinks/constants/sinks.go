@@ -0,0 +1,14 @@
+package constants
+
+// what revealed a meter's move to another sink
+const (
+	TRIGGER_SINK_CHANGE_MSG = "sink_change_msg"
+	TRIGGER_UPLINK          = "uplink"
+)
+
+const (
+	ERR_SAVING_ATTACHMENT    = "error occurred while saving sink attachments, retrying next flush: %v"
+	ERR_INVALID_NODE_ADDRESS = "invalid node address %q"
+	ERR_INVALID_PARAM        = "invalid %s %q"
+	ERR_ATTACHMENT_NOT_FOUND = "no sink attachment for node %d"
+)
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	sinkConstants "parsing-service/apps/sinks/constants"
+	services "parsing-service/apps/sinks/service_interfaces"
+	"parsing-service/constants"
+	"parsing-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SinkController struct {
+	sinkRegistry services.ISinkRegistry
+	logger       logger.ILogger
+}
+
+func NewSinkController(
+	sinkRegistryIntf services.ISinkRegistry,
+	logger logger.ILogger,
+) *SinkController {
+	return &SinkController{
+		sinkRegistry: sinkRegistryIntf,
+		logger:       logger,
+	}
+}
+
+func badRequest(ctx *gin.Context, message string) {
+	ctx.JSON(http.StatusBadRequest, gin.H{constants.ERROR_STRING: message, constants.ERROR_CODE_STRING: constants.INVALID_REQ_PARAMS_CODE})
+}
+
+func notFound(ctx *gin.Context, message string) {
+	ctx.JSON(http.StatusNotFound, gin.H{constants.ERROR_STRING: message, constants.ERROR_CODE_STRING: constants.RECORD_NOT_FOUND_ERROR_CODE})
+}
+
+// GetAttachment returns the sink and DCU the meter with the WP node address
+// :nodeAddress was last heard through.
+func (c *SinkController) GetAttachment(ctx *gin.Context) {
+	nodeAddress, err := strconv.ParseInt(ctx.Param("nodeAddress"), 10, 64)
+	if err != nil {
+		badRequest(ctx, fmt.Sprintf(sinkConstants.ERR_INVALID_NODE_ADDRESS, ctx.Param("nodeAddress")))
+		return
+	}
+
+	attachment := c.sinkRegistry.GetAttachment(nodeAddress, ctx.GetString(constants.REQUEST_ID))
+	if attachment == nil {
+		notFound(ctx, fmt.Sprintf(sinkConstants.ERR_ATTACHMENT_NOT_FOUND, nodeAddress))
+		return
+	}
+	ctx.JSON(http.StatusOK, attachment)
+}
+
+// FindAttachment looks a meter up by the meterIp query param.
+func (c *SinkController) FindAttachment(ctx *gin.Context) {
+	meterIp := ctx.Query("meterIp")
+	if meterIp == "" {
+		badRequest(ctx, fmt.Sprintf(sinkConstants.ERR_INVALID_PARAM, "meterIp", meterIp))
+		return
+	}
+
+	attachment := c.sinkRegistry.GetAttachmentByMeterIp(meterIp, ctx.GetString(constants.REQUEST_ID))
+	if attachment == nil {
+		notFound(ctx, constants.RECORD_NOT_FOUND_MSG)
+		return
+	}
+	ctx.JSON(http.StatusOK, attachment)
+}
+
+// GetSinkMeters lists the meters last heard through sink :sinkId of DCU
+// :dcuNumber.
+func (c *SinkController) GetSinkMeters(ctx *gin.Context) {
+	dcuNumber, err := strconv.ParseInt(ctx.Param("dcuNumber"), 10, 64)
+	if err != nil {
+		badRequest(ctx, fmt.Sprintf(sinkConstants.ERR_INVALID_PARAM, "dcuNumber", ctx.Param("dcuNumber")))
+		return
+	}
+	sinkID, err := strconv.ParseInt(ctx.Param("sinkId"), 10, 16)
+	if err != nil {
+		badRequest(ctx, fmt.Sprintf(sinkConstants.ERR_INVALID_PARAM, "sinkId", ctx.Param("sinkId")))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, c.sinkRegistry.GetSinkMeters(dcuNumber, int16(sinkID), ctx.GetString(constants.REQUEST_ID)))
+}
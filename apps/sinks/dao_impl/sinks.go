@@ -0,0 +1,92 @@
+package daoimpl
+
+import (
+	"errors"
+	"net/http"
+	"parsing-service/apps/sinks/models"
+	"parsing-service/constants"
+	customErrorPkg "parsing-service/pkg/custom_error"
+	"parsing-service/pkg/database"
+	"parsing-service/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+type MeterSinkAttachmentImpl struct {
+	logger logger.ILogger
+}
+
+func NewMeterSinkAttachmentDAO(logger logger.ILogger) *MeterSinkAttachmentImpl {
+	return &MeterSinkAttachmentImpl{logger: logger}
+}
+
+func processingError() *customErrorPkg.CustomError {
+	return customErrorPkg.NewCustomError(
+		errors.New(constants.PROCESSING_ERROR),
+		constants.INTERNAL_SERVER_ERROR_CODE,
+		http.StatusInternalServerError,
+	)
+}
+
+func (attachmentDao *MeterSinkAttachmentImpl) GetAttachment(nodeAddress int64, requestID string) *models.MeterSinkAttachment {
+	var attachment models.MeterSinkAttachment
+
+	err := database.DB.Model(&models.MeterSinkAttachment{}).Where("meter_sink_attachment.node_address = ?", nodeAddress).First(&attachment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		attachmentDao.logger.Errorf("<GetAttachment> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+
+	return &attachment
+}
+
+// GetAttachmentByMeterIp returns the most recently heard meter with the ip.
+func (attachmentDao *MeterSinkAttachmentImpl) GetAttachmentByMeterIp(meterIp string, requestID string) *models.MeterSinkAttachment {
+	var attachment models.MeterSinkAttachment
+
+	query := database.DB.Model(&models.MeterSinkAttachment{}).
+		Where("meter_sink_attachment.meter_ip = ?", meterIp).
+		Order("meter_sink_attachment.last_seen_at DESC")
+
+	err := query.First(&attachment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		attachmentDao.logger.Errorf("<GetAttachmentByMeterIp> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+
+	return &attachment
+}
+
+func (attachmentDao *MeterSinkAttachmentImpl) GetSinkMeters(dcuNumber int64, sinkID int16, requestID string) []models.MeterSinkAttachment {
+	var attachments []models.MeterSinkAttachment
+
+	query := database.DB.Model(&models.MeterSinkAttachment{}).
+		Where("meter_sink_attachment.dcu_number = ? AND meter_sink_attachment.sink_id = ?", dcuNumber, sinkID).
+		Order("meter_sink_attachment.node_address")
+
+	err := query.Find(&attachments).Error
+	if err != nil {
+		attachmentDao.logger.Errorf("<GetSinkMeters> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+
+	return attachments
+}
+
+// SaveAttachments upserts attachments in one statement.
+func (attachmentDao *MeterSinkAttachmentImpl) SaveAttachments(attachments []models.MeterSinkAttachment, requestID string) {
+	if len(attachments) == 0 {
+		return
+	}
+	err := database.DB.Save(&attachments).Error
+	if err != nil {
+		attachmentDao.logger.Errorf("<SaveAttachments> RequestID %v, Error %v", requestID, err)
+		panic(processingError())
+	}
+}
@@ -0,0 +1,10 @@
+package daointerfaces
+
+import "parsing-service/apps/sinks/models"
+
+type IMeterSinkAttachmentDAO interface {
+	GetAttachment(nodeAddress int64, requestID string) *models.MeterSinkAttachment
+	GetAttachmentByMeterIp(meterIp string, requestID string) *models.MeterSinkAttachment
+	GetSinkMeters(dcuNumber int64, sinkID int16, requestID string) []models.MeterSinkAttachment
+	SaveAttachments(attachments []models.MeterSinkAttachment, requestID string)
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// MeterSinkAttachment is the sink and DCU a meter was last heard through.
+// Meters are keyed by their WP node address; MeterIp is filled in once the
+// meter sent a TAP packet.
+type MeterSinkAttachment struct {
+	NodeAddress int64  `gorm:"primaryKey;autoIncrement:false" json:"nodeAddress"`
+	MeterIp     string `gorm:"type:varchar(15);index" json:"meterIp,omitempty"`
+	DcuNumber   int64  `gorm:"index:idx_meter_sink_attachment_dcu_sink" json:"dcuNumber"`
+	SinkID      int16  `gorm:"index:idx_meter_sink_attachment_dcu_sink" json:"sinkId"`
+
+	// when the meter was first heard through the current sink
+	AttachedAt  time.Time `json:"attachedAt"`
+	FirstSeenAt time.Time `json:"firstSeenAt"`
+	LastSeenAt  time.Time `json:"lastSeenAt"`
+	LastDcuTime int64     `json:"lastDcuTime"`
+	SinkChanges int32     `json:"sinkChanges"`
+}
+
+func (MeterSinkAttachment) TableName() string {
+	return "meter_sink_attachment"
+}
+
+// SinkChangeEvent is published on the sink change topic when a meter shows
+// up on another sink or DCU, or announces a sink change itself. The previous
+// fields are empty for a meter not heard before.
+type SinkChangeEvent struct {
+	NodeAddress int64  `json:"nodeAddress"`
+	MeterIp     string `json:"meterIp,omitempty"`
+	Trigger     string `json:"trigger"`
+
+	PreviousDcuNumber  *int64     `json:"previousDcuNumber,omitempty"`
+	PreviousSinkID     *int16     `json:"previousSinkId,omitempty"`
+	PreviousAttachedAt *time.Time `json:"previousAttachedAt,omitempty"`
+	PreviousLastSeenAt *time.Time `json:"previousLastSeenAt,omitempty"`
+
+	DcuNumber int64     `json:"dcuNumber"`
+	SinkID    int16     `json:"sinkId"`
+	ChangedAt time.Time `json:"changedAt"`
+	DcuTime   int64     `json:"dcuTime"`
+	// payload of the Sink_Change_Msg, hex
+	Payload string `json:"payload,omitempty"`
+}
@@ -0,0 +1,15 @@
+package serviceinterfaces
+
+import (
+	"parsing-service/apps/sinks/models"
+	"parsing-service/pkg/wp"
+	"time"
+)
+
+type ISinkRegistry interface {
+	Observe(frame wp.Frame, message wp.Message, now time.Time) bool
+	Flush()
+	GetAttachment(nodeAddress int64, requestID string) *models.MeterSinkAttachment
+	GetAttachmentByMeterIp(meterIp string, requestID string) *models.MeterSinkAttachment
+	GetSinkMeters(dcuNumber int64, sinkID int16, requestID string) []models.MeterSinkAttachment
+}
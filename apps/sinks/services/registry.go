@@ -0,0 +1,233 @@
+package services
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/apps/sinks/constants"
+	daoInterfaces "parsing-service/apps/sinks/dao_interfaces"
+	"parsing-service/apps/sinks/models"
+	"parsing-service/pkg/config"
+	customErrorPkg "parsing-service/pkg/custom_error"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/tap"
+	"parsing-service/pkg/wp"
+)
+
+const registryRequestID = "sink-registry"
+
+// SinkRegistry keeps the sink and DCU every meter is heard through. Uplinks
+// update it in memory; Flush publishes the sink changes a batch saw and saves
+// the meters heard behind, in one upsert per flush interval.
+type SinkRegistry struct {
+	dao           daoInterfaces.IMeterSinkAttachmentDAO
+	KafkaProducer kafkaIntf.IKafkaProducer
+	cfg           *config.Configuration
+	logger        logger.ILogger
+
+	mu sync.Mutex
+	// every meter looked up so far, nil for meters never heard before
+	attachments map[int64]*models.MeterSinkAttachment
+	dirty       map[int64]*models.MeterSinkAttachment
+	events      []models.SinkChangeEvent
+	lastWrite   time.Time
+}
+
+func NewSinkRegistry(
+	dao daoInterfaces.IMeterSinkAttachmentDAO,
+	kafkaProducer kafkaIntf.IKafkaProducer,
+	cfg *config.Configuration,
+	logger logger.ILogger,
+) *SinkRegistry {
+	return &SinkRegistry{
+		dao:           dao,
+		KafkaProducer: kafkaProducer,
+		cfg:           cfg,
+		logger:        logger,
+		attachments:   make(map[int64]*models.MeterSinkAttachment),
+		dirty:         make(map[int64]*models.MeterSinkAttachment),
+		lastWrite:     time.Now(),
+	}
+}
+
+// Observe records the sink and DCU an uplink of frame came through and
+// reports whether message was an uplink. A Sink_Change_Msg always yields an
+// event; any other uplink only when its meter was last heard elsewhere.
+func (r *SinkRegistry) Observe(frame wp.Frame, message wp.Message, now time.Time) bool {
+	if message.Type != wp.UplinkMsg {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	nodeAddress := int64(message.Uplink.SrcAddress)
+	dcuNumber := int64(frame.DcuNumber)
+	sinkID := int16(frame.SinkID)
+	sinkChangeMsg := message.Uplink.SrcEndpoint == wp.SINK_CHANGE_ENDPOINT
+
+	attachment := r.attachment(nodeAddress)
+	moved := attachment != nil && (attachment.DcuNumber != dcuNumber || attachment.SinkID != sinkID)
+
+	if sinkChangeMsg || moved {
+		event := models.SinkChangeEvent{
+			NodeAddress: nodeAddress,
+			Trigger:     constants.TRIGGER_UPLINK,
+			DcuNumber:   dcuNumber,
+			SinkID:      sinkID,
+			ChangedAt:   now,
+			DcuTime:     int64(frame.DcuTime),
+		}
+		if sinkChangeMsg {
+			event.Trigger = constants.TRIGGER_SINK_CHANGE_MSG
+			event.Payload = hex.EncodeToString(message.Uplink.Payload)
+		}
+		if attachment != nil {
+			previousDcuNumber, previousSinkID := attachment.DcuNumber, attachment.SinkID
+			previousAttachedAt, previousLastSeenAt := attachment.AttachedAt, attachment.LastSeenAt
+			event.MeterIp = attachment.MeterIp
+			event.PreviousDcuNumber = &previousDcuNumber
+			event.PreviousSinkID = &previousSinkID
+			event.PreviousAttachedAt = &previousAttachedAt
+			event.PreviousLastSeenAt = &previousLastSeenAt
+		}
+		if meterIp := uplinkMeterIp(message.Uplink.Payload); meterIp != "" {
+			event.MeterIp = meterIp
+		}
+		r.events = append(r.events, event)
+	}
+
+	if attachment == nil {
+		attachment = &models.MeterSinkAttachment{
+			NodeAddress: nodeAddress,
+			DcuNumber:   dcuNumber,
+			SinkID:      sinkID,
+			AttachedAt:  now,
+			FirstSeenAt: now,
+		}
+		r.attachments[nodeAddress] = attachment
+	}
+	if moved {
+		attachment.DcuNumber = dcuNumber
+		attachment.SinkID = sinkID
+		attachment.AttachedAt = now
+		attachment.SinkChanges++
+	}
+	if meterIp := uplinkMeterIp(message.Uplink.Payload); meterIp != "" {
+		attachment.MeterIp = meterIp
+	}
+	attachment.LastSeenAt = now
+	attachment.LastDcuTime = int64(frame.DcuTime)
+	r.dirty[nodeAddress] = attachment
+
+	return true
+}
+
+// uplinkMeterIp returns the source address of the TAP packet an uplink
+// carries, empty when it carries none.
+func uplinkMeterIp(payload []byte) string {
+	if len(payload) < tap.TAP_HEADER_LEN || payload[0] != tap.TAP_START_BYTE {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", payload[1], payload[2], payload[3], payload[4])
+}
+
+// attachment returns the cached attachment of a meter, loading it on first
+// use.
+func (r *SinkRegistry) attachment(nodeAddress int64) (attachment *models.MeterSinkAttachment) {
+	if attachment, ok := r.attachments[nodeAddress]; ok {
+		return attachment
+	}
+	defer func() {
+		if rec := recover(); rec != nil {
+			// the next uplink of the meter tries again
+			r.logger.Errorf("<attachment> node %d: %v", nodeAddress, rec)
+			attachment = nil
+		}
+	}()
+	attachment = r.dao.GetAttachment(nodeAddress, registryRequestID)
+	r.attachments[nodeAddress] = attachment
+	return attachment
+}
+
+// Flush publishes the sink changes seen since the last flush and saves the
+// meters heard once the flush interval is up or the buffer is full.
+func (r *SinkRegistry) Flush() {
+	r.flush(time.Now())
+}
+
+func (r *SinkRegistry) flush(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sinkCfg := r.cfg.SinkConfig
+	if now.Sub(r.lastWrite) >= time.Duration(sinkCfg.FlushIntervalMs)*time.Millisecond ||
+		(sinkCfg.MaxBuffered > 0 && len(r.dirty) >= sinkCfg.MaxBuffered) {
+		r.write(now)
+	}
+	r.publish()
+}
+
+// write saves the buffered meters. Meters that fail to save stay buffered for
+// the next write.
+func (r *SinkRegistry) write(now time.Time) {
+	r.lastWrite = now
+	if len(r.dirty) == 0 {
+		return
+	}
+
+	attachments := make([]models.MeterSinkAttachment, 0, len(r.dirty))
+	for _, attachment := range r.dirty {
+		attachments = append(attachments, *attachment)
+	}
+	if err := r.save(attachments); err != nil {
+		r.logger.Errorf(constants.ERR_SAVING_ATTACHMENT, err)
+		return
+	}
+	r.dirty = make(map[int64]*models.MeterSinkAttachment)
+}
+
+func (r *SinkRegistry) publish() {
+	if len(r.events) == 0 {
+		return
+	}
+	events := make([][]byte, 0, len(r.events))
+	for _, event := range r.events {
+		if data, err := json.Marshal(event); err == nil {
+			events = append(events, data)
+		}
+	}
+	r.events = nil
+	r.logger.Debugf("<publish> %d sink change events", len(events))
+	go r.KafkaProducer.ProduceMessagesInBatch(r.cfg.KafkaTopicsConfig.PRODUCE_SINK_CHANGE_KAFKA_TOPIC_NAME, events)
+}
+
+func (r *SinkRegistry) save(attachments []models.MeterSinkAttachment) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			if customError, ok := rec.(*customErrorPkg.CustomError); ok {
+				err = customError.GetErrorField()
+				return
+			}
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+	r.dao.SaveAttachments(attachments, registryRequestID)
+	return nil
+}
+
+func (r *SinkRegistry) GetAttachment(nodeAddress int64, requestID string) *models.MeterSinkAttachment {
+	return r.dao.GetAttachment(nodeAddress, requestID)
+}
+
+func (r *SinkRegistry) GetAttachmentByMeterIp(meterIp string, requestID string) *models.MeterSinkAttachment {
+	return r.dao.GetAttachmentByMeterIp(meterIp, requestID)
+}
+
+func (r *SinkRegistry) GetSinkMeters(dcuNumber int64, sinkID int16, requestID string) []models.MeterSinkAttachment {
+	return r.dao.GetSinkMeters(dcuNumber, sinkID, requestID)
+}
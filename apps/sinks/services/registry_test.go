@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/apps/sinks/models"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/wp"
+)
+
+// memoryAttachments records every upsert, failing while fail is set.
+type memoryAttachments struct {
+	saves [][]models.MeterSinkAttachment
+	fail  bool
+}
+
+func (m *memoryAttachments) GetAttachment(nodeAddress int64, requestID string) *models.MeterSinkAttachment {
+	return nil
+}
+
+func (m *memoryAttachments) GetAttachmentByMeterIp(meterIp string, requestID string) *models.MeterSinkAttachment {
+	return nil
+}
+
+func (m *memoryAttachments) GetSinkMeters(dcuNumber int64, sinkID int16, requestID string) []models.MeterSinkAttachment {
+	return nil
+}
+
+func (m *memoryAttachments) SaveAttachments(attachments []models.MeterSinkAttachment, requestID string) {
+	if m.fail {
+		panic(errors.New("database unavailable"))
+	}
+	m.saves = append(m.saves, attachments)
+}
+
+// eventProducer hands the published batches over on a channel, the other
+// methods are not used by the tests.
+type eventProducer struct {
+	kafkaIntf.IKafkaProducer
+	batches chan [][]byte
+}
+
+func (p *eventProducer) ProduceMessagesInBatch(topic string, messages [][]byte) error {
+	p.batches <- messages
+	return nil
+}
+
+func newTestRegistry(flushInterval time.Duration, maxBuffered int, start time.Time) (*SinkRegistry, *memoryAttachments, *eventProducer) {
+	cfg := &config.Configuration{}
+	cfg.SinkConfig.FlushIntervalMs = int(flushInterval / time.Millisecond)
+	cfg.SinkConfig.MaxBuffered = maxBuffered
+	dao := &memoryAttachments{}
+	producer := &eventProducer{batches: make(chan [][]byte, 8)}
+	registry := NewSinkRegistry(dao, producer, cfg, logger.NewLogger())
+	registry.lastWrite = start
+	return registry, dao, producer
+}
+
+func uplink(nodeAddress uint32, endpoint uint8) wp.Message {
+	return wp.Message{Type: wp.UplinkMsg, Uplink: wp.Uplink{SrcAddress: nodeAddress, SrcEndpoint: endpoint}}
+}
+
+func TestSinkRegistryWritesBehind(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	registry, dao, _ := newTestRegistry(5*time.Second, 0, start)
+	frame := wp.Frame{DcuNumber: 300, SinkID: 1}
+
+	registry.Observe(frame, uplink(1, 1), start)
+	registry.Observe(frame, uplink(2, 1), start)
+	registry.flush(start.Add(time.Second))
+	if len(dao.saves) != 0 {
+		t.Fatalf("saved %d times inside the flush interval", len(dao.saves))
+	}
+
+	registry.Observe(frame, uplink(1, 1), start.Add(2*time.Second))
+	registry.flush(start.Add(5 * time.Second))
+	if len(dao.saves) != 1 || len(dao.saves[0]) != 2 {
+		t.Fatalf("saves %v, want one upsert of both meters", dao.saves)
+	}
+
+	registry.flush(start.Add(11 * time.Second))
+	if len(dao.saves) != 1 {
+		t.Fatal("an empty buffer was saved")
+	}
+}
+
+func TestSinkRegistryWritesFullBuffer(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	registry, dao, _ := newTestRegistry(time.Hour, 2, start)
+	frame := wp.Frame{DcuNumber: 300, SinkID: 1}
+
+	registry.Observe(frame, uplink(1, 1), start)
+	registry.flush(start)
+	if len(dao.saves) != 0 {
+		t.Fatal("saved before the buffer was full")
+	}
+	registry.Observe(frame, uplink(2, 1), start)
+	registry.flush(start)
+	if len(dao.saves) != 1 || len(dao.saves[0]) != 2 {
+		t.Fatalf("saves %v, want one upsert of both meters", dao.saves)
+	}
+}
+
+func TestSinkRegistryKeepsFailedMeters(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	registry, dao, _ := newTestRegistry(time.Second, 0, start)
+	frame := wp.Frame{DcuNumber: 300, SinkID: 1}
+
+	dao.fail = true
+	registry.Observe(frame, uplink(1, 1), start)
+	registry.flush(start.Add(time.Second))
+
+	dao.fail = false
+	registry.Observe(frame, uplink(2, 1), start.Add(time.Second))
+	registry.flush(start.Add(2 * time.Second))
+	if len(dao.saves) != 1 || len(dao.saves[0]) != 2 {
+		t.Fatalf("saves %v, want the failed meter saved with the next", dao.saves)
+	}
+}
+
+func TestSinkRegistryPublishesEveryBatch(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	registry, dao, producer := newTestRegistry(time.Hour, 0, start)
+
+	registry.Observe(wp.Frame{DcuNumber: 300, SinkID: 1}, uplink(1, wp.SINK_CHANGE_ENDPOINT), start)
+	registry.flush(start)
+	select {
+	case batch := <-producer.batches:
+		if len(batch) != 1 {
+			t.Fatalf("published %d events, want 1", len(batch))
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("the sink change was held back with the write")
+	}
+	if len(dao.saves) != 0 {
+		t.Fatal("saved inside the flush interval")
+	}
+}
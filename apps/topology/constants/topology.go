@@ -0,0 +1,34 @@
+package constants
+
+// graph renderings served by /topology/graph
+const (
+	FORMAT_JSON    = "json"
+	FORMAT_GRAPHML = "graphml"
+	FORMAT_DOT     = "dot"
+)
+
+const (
+	CONTENT_TYPE_JSON    = "application/json; charset=utf-8"
+	CONTENT_TYPE_GRAPHML = "application/graphml+xml; charset=utf-8"
+	CONTENT_TYPE_DOT     = "text/vnd.graphviz; charset=utf-8"
+)
+
+// kinds of graph nodes
+const (
+	NODE_METER = "meter"
+	NODE_SINK  = "sink"
+	NODE_DCU   = "dcu"
+)
+
+// what changed in a meter's route
+const (
+	CHANGE_SINK      = "sink"
+	CHANGE_HOP_COUNT = "hop_count"
+)
+
+const (
+	ERR_INVALID_FORMAT       = "invalid format %q, want json, graphml or dot"
+	ERR_INVALID_QUERY_PARAM  = "invalid %s %q"
+	ERR_INVALID_NODE_ADDRESS = "invalid node address %q"
+	ERR_ROUTE_NOT_FOUND      = "node %d has not been heard within the topology window"
+)
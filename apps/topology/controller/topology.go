@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	topologyConstants "parsing-service/apps/topology/constants"
+	"parsing-service/apps/topology/models"
+	services "parsing-service/apps/topology/service_interfaces"
+	"parsing-service/constants"
+	"parsing-service/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TopologyController struct {
+	topology services.ITopologyService
+	logger   logger.ILogger
+}
+
+func NewTopologyController(
+	topologyServiceIntf services.ITopologyService,
+	logger logger.ILogger,
+) *TopologyController {
+	return &TopologyController{
+		topology: topologyServiceIntf,
+		logger:   logger,
+	}
+}
+
+func badRequest(ctx *gin.Context, message string) {
+	ctx.JSON(http.StatusBadRequest, gin.H{constants.ERROR_STRING: message, constants.ERROR_CODE_STRING: constants.INVALID_REQ_PARAMS_CODE})
+}
+
+// GetGraph renders the topology of the meters matching the dcuNumber, sinkId
+// and weakOnly query params in the format query param: json (default),
+// graphml or dot.
+func (c *TopologyController) GetGraph(ctx *gin.Context) {
+	var filter models.GraphFilter
+
+	if value := ctx.Query("dcuNumber"); value != "" {
+		dcuNumber, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			badRequest(ctx, fmt.Sprintf(topologyConstants.ERR_INVALID_QUERY_PARAM, "dcuNumber", value))
+			return
+		}
+		dcu := uint32(dcuNumber)
+		filter.DcuNumber = &dcu
+	}
+	if value := ctx.Query("sinkId"); value != "" {
+		sinkID, err := strconv.ParseUint(value, 10, 8)
+		if err != nil {
+			badRequest(ctx, fmt.Sprintf(topologyConstants.ERR_INVALID_QUERY_PARAM, "sinkId", value))
+			return
+		}
+		sink := uint8(sinkID)
+		filter.SinkID = &sink
+	}
+	if value := ctx.Query("weakOnly"); value != "" {
+		weakOnly, err := strconv.ParseBool(value)
+		if err != nil {
+			badRequest(ctx, fmt.Sprintf(topologyConstants.ERR_INVALID_QUERY_PARAM, "weakOnly", value))
+			return
+		}
+		filter.WeakOnly = weakOnly
+	}
+
+	data, contentType, err := c.topology.RenderGraph(c.topology.Graph(filter), ctx.Query("format"))
+	if err != nil {
+		c.logger.Errorf("<GetGraph> Error %v", err)
+		badRequest(ctx, err.Error())
+		return
+	}
+	ctx.Data(http.StatusOK, contentType, data)
+}
+
+// GetMeterRoute returns the route and statistics of the meter with the WP
+// node address :nodeAddress.
+func (c *TopologyController) GetMeterRoute(ctx *gin.Context) {
+	nodeAddress, err := strconv.ParseUint(ctx.Param("nodeAddress"), 10, 32)
+	if err != nil {
+		badRequest(ctx, fmt.Sprintf(topologyConstants.ERR_INVALID_NODE_ADDRESS, ctx.Param("nodeAddress")))
+		return
+	}
+
+	route, ok := c.topology.GetRoute(uint32(nodeAddress))
+	if !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{constants.ERROR_STRING: fmt.Sprintf(topologyConstants.ERR_ROUTE_NOT_FOUND, nodeAddress), constants.ERROR_CODE_STRING: constants.RECORD_NOT_FOUND_ERROR_CODE})
+		return
+	}
+	ctx.JSON(http.StatusOK, route)
+}
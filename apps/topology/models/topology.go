@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+// Route is the way a meter's uplinks reach the backend: through sink SinkID
+// of DCU DcuNumber, HopCount hops away from the sink.
+type Route struct {
+	DcuNumber   uint32 `json:"dcuNumber"`
+	SinkID      uint8  `json:"sinkId"`
+	SinkAddress uint32 `json:"sinkAddress"`
+	HopCount    uint8  `json:"hopCount"`
+}
+
+// Stats summarises the samples of a meter's rolling window.
+type Stats struct {
+	Samples int     `json:"samples"`
+	Min     uint32  `json:"min"`
+	Max     uint32  `json:"max"`
+	Mean    float64 `json:"mean"`
+	Last    uint32  `json:"last"`
+}
+
+// MeterRoute is a meter's current route with the hop count and travel time
+// statistics of its last uplinks. Weak is set when the means cross the
+// configured thresholds.
+type MeterRoute struct {
+	NodeAddress  uint32    `json:"nodeAddress"`
+	Route        Route     `json:"route"`
+	Hops         Stats     `json:"hops"`
+	TravelTime   Stats     `json:"travelTime"`
+	RouteChanges int       `json:"routeChanges"`
+	Weak         bool      `json:"weak"`
+	FirstSeenAt  time.Time `json:"firstSeenAt"`
+	LastSeenAt   time.Time `json:"lastSeenAt"`
+	LastDcuTime  uint32    `json:"lastDcuTime"`
+}
+
+// RouteChangeEvent is published on the topology topic when a meter is heard
+// through another sink or at another hop count.
+type RouteChangeEvent struct {
+	NodeAddress uint32    `json:"nodeAddress"`
+	Change      string    `json:"change"`
+	Previous    Route     `json:"previous"`
+	Current     Route     `json:"current"`
+	ChangedAt   time.Time `json:"changedAt"`
+	DcuTime     uint32    `json:"dcuTime"`
+}
+
+type GraphNode struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+}
+
+// GraphEdge links a meter to its sink, or a sink to its DCU. Meter edges
+// carry the meter's statistics, sink edges how many of the sink's meters
+// there are and how many of them are weak.
+type GraphEdge struct {
+	Source       string `json:"source"`
+	Target       string `json:"target"`
+	Hops         *Stats `json:"hops,omitempty"`
+	TravelTime   *Stats `json:"travelTime,omitempty"`
+	RouteChanges int    `json:"routeChanges,omitempty"`
+	Meters       int    `json:"meters,omitempty"`
+	WeakMeters   int    `json:"weakMeters,omitempty"`
+	Weak         bool   `json:"weak"`
+}
+
+type Graph struct {
+	GeneratedAt time.Time   `json:"generatedAt"`
+	Nodes       []GraphNode `json:"nodes"`
+	Edges       []GraphEdge `json:"edges"`
+}
+
+// GraphFilter narrows the graph; zero values match everything.
+type GraphFilter struct {
+	DcuNumber *uint32
+	SinkID    *uint8
+	WeakOnly  bool
+}
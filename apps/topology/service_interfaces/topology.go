@@ -0,0 +1,15 @@
+package serviceinterfaces
+
+import (
+	"parsing-service/apps/topology/models"
+	"parsing-service/pkg/wp"
+	"time"
+)
+
+type ITopologyService interface {
+	Observe(frame wp.Frame, message wp.Message, now time.Time) bool
+	Flush()
+	Graph(filter models.GraphFilter) models.Graph
+	RenderGraph(graph models.Graph, format string) ([]byte, string, error)
+	GetRoute(nodeAddress uint32) (models.MeterRoute, bool)
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"parsing-service/apps/topology/constants"
+	"parsing-service/apps/topology/models"
+)
+
+// RenderGraph renders graph as JSON, GraphML or DOT and returns it with its
+// content type.
+func (t *TopologyService) RenderGraph(graph models.Graph, format string) ([]byte, string, error) {
+	switch format {
+	case constants.FORMAT_JSON, "":
+		data, err := json.Marshal(graph)
+		return data, constants.CONTENT_TYPE_JSON, err
+	case constants.FORMAT_GRAPHML:
+		data, err := renderGraphML(graph)
+		return data, constants.CONTENT_TYPE_GRAPHML, err
+	case constants.FORMAT_DOT:
+		return renderDot(graph), constants.CONTENT_TYPE_DOT, nil
+	}
+	return nil, "", fmt.Errorf(constants.ERR_INVALID_FORMAT, format)
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+var graphMLKeys = []graphMLKey{
+	{"kind", "node", "kind", "string"},
+	{"label", "node", "label", "string"},
+	{"hopsMean", "edge", "hopsMean", "double"},
+	{"hopsMax", "edge", "hopsMax", "int"},
+	{"hopsLast", "edge", "hopsLast", "int"},
+	{"travelTimeMean", "edge", "travelTimeMean", "double"},
+	{"travelTimeMax", "edge", "travelTimeMax", "long"},
+	{"travelTimeLast", "edge", "travelTimeLast", "long"},
+	{"samples", "edge", "samples", "int"},
+	{"routeChanges", "edge", "routeChanges", "int"},
+	{"meters", "edge", "meters", "int"},
+	{"weakMeters", "edge", "weakMeters", "int"},
+	{"weak", "edge", "weak", "boolean"},
+}
+
+func formatMean(mean float64) string {
+	return strconv.FormatFloat(mean, 'f', 2, 64)
+}
+
+func renderGraphML(graph models.Graph) ([]byte, error) {
+	document := graphMLDocument{Xmlns: "http://graphml.graphdrawing.org/xmlns", Keys: graphMLKeys}
+	document.Graph.ID = "topology"
+	document.Graph.EdgeDefault = "directed"
+
+	for _, node := range graph.Nodes {
+		document.Graph.Nodes = append(document.Graph.Nodes, graphMLNode{
+			ID:   node.ID,
+			Data: []graphMLData{{"kind", node.Kind}, {"label", node.Label}},
+		})
+	}
+	for _, edge := range graph.Edges {
+		var data []graphMLData
+		if edge.Hops != nil && edge.TravelTime != nil {
+			data = append(data,
+				graphMLData{"hopsMean", formatMean(edge.Hops.Mean)},
+				graphMLData{"hopsMax", fmt.Sprint(edge.Hops.Max)},
+				graphMLData{"hopsLast", fmt.Sprint(edge.Hops.Last)},
+				graphMLData{"travelTimeMean", formatMean(edge.TravelTime.Mean)},
+				graphMLData{"travelTimeMax", fmt.Sprint(edge.TravelTime.Max)},
+				graphMLData{"travelTimeLast", fmt.Sprint(edge.TravelTime.Last)},
+				graphMLData{"samples", fmt.Sprint(edge.Hops.Samples)},
+				graphMLData{"routeChanges", fmt.Sprint(edge.RouteChanges)},
+			)
+		} else {
+			data = append(data,
+				graphMLData{"meters", fmt.Sprint(edge.Meters)},
+				graphMLData{"weakMeters", fmt.Sprint(edge.WeakMeters)},
+			)
+		}
+		data = append(data, graphMLData{"weak", strconv.FormatBool(edge.Weak)})
+		document.Graph.Edges = append(document.Graph.Edges, graphMLEdge{Source: edge.Source, Target: edge.Target, Data: data})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+var dotShapes = map[string]string{
+	constants.NODE_METER: "ellipse",
+	constants.NODE_SINK:  "box",
+	constants.NODE_DCU:   "doubleoctagon",
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// renderDot draws meter edges with their mean hops and travel time, and
+// weak edges in red.
+func renderDot(graph models.Graph) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("digraph topology {\n\trankdir=LR;\n")
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(&buf, "\t%s [label=%s, shape=%s];\n", dotQuote(node.ID), dotQuote(node.Label), dotShapes[node.Kind])
+	}
+	for _, edge := range graph.Edges {
+		var label string
+		if edge.Hops != nil && edge.TravelTime != nil {
+			label = fmt.Sprintf("hops %s, tt %s", formatMean(edge.Hops.Mean), formatMean(edge.TravelTime.Mean))
+		} else {
+			label = fmt.Sprintf("%d meters, %d weak", edge.Meters, edge.WeakMeters)
+		}
+		color := "black"
+		if edge.Weak {
+			color = "red"
+		}
+		fmt.Fprintf(&buf, "\t%s -> %s [label=%s, color=%s];\n", dotQuote(edge.Source), dotQuote(edge.Target), dotQuote(label), color)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/apps/topology/constants"
+	"parsing-service/apps/topology/models"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/wp"
+)
+
+type sample struct {
+	hopCount   uint8
+	travelTime uint32
+}
+
+// meterState is the route of a meter and a ring of its last uplinks.
+type meterState struct {
+	route        models.Route
+	samples      []sample
+	next         int
+	routeChanges int
+	firstSeenAt  time.Time
+	lastSeenAt   time.Time
+	lastDcuTime  uint32
+}
+
+// TopologyService keeps a rolling meter -> sink -> DCU graph of the mesh
+// from the uplinks it observes. It is held in memory only: meters not heard
+// for StaleAfterMs drop out, and a restart starts from an empty graph.
+type TopologyService struct {
+	KafkaProducer kafkaIntf.IKafkaProducer
+	cfg           *config.Configuration
+	logger        logger.ILogger
+	windowSize    int
+	staleAfter    time.Duration
+
+	mu     sync.Mutex
+	meters map[uint32]*meterState
+	events []models.RouteChangeEvent
+}
+
+func NewTopologyService(
+	kafkaProducer kafkaIntf.IKafkaProducer,
+	cfg *config.Configuration,
+	logger logger.ILogger,
+) *TopologyService {
+	windowSize := cfg.TopologyConfig.WindowSize
+	if windowSize <= 0 {
+		windowSize = 1
+	}
+	return &TopologyService{
+		KafkaProducer: kafkaProducer,
+		cfg:           cfg,
+		logger:        logger,
+		windowSize:    windowSize,
+		staleAfter:    time.Duration(cfg.TopologyConfig.StaleAfterMs) * time.Millisecond,
+		meters:        make(map[uint32]*meterState),
+	}
+}
+
+// Observe adds an uplink of frame to its meter's window and reports whether
+// message was an uplink. A meter heard through another sink, or at another
+// hop count, than its previous uplink yields a route change event.
+func (t *TopologyService) Observe(frame wp.Frame, message wp.Message, now time.Time) bool {
+	if message.Type != wp.UplinkMsg {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	uplink := message.Uplink
+	route := models.Route{
+		DcuNumber:   frame.DcuNumber,
+		SinkID:      frame.SinkID,
+		SinkAddress: uplink.DstAddress,
+		HopCount:    uplink.HopCount,
+	}
+
+	meter, ok := t.meters[uplink.SrcAddress]
+	if !ok {
+		meter = &meterState{
+			route:       route,
+			samples:     make([]sample, 0, t.windowSize),
+			firstSeenAt: now,
+		}
+		t.meters[uplink.SrcAddress] = meter
+	}
+
+	var change string
+	switch {
+	case meter.route.DcuNumber != route.DcuNumber || meter.route.SinkID != route.SinkID:
+		change = constants.CHANGE_SINK
+	case meter.route.HopCount != route.HopCount:
+		change = constants.CHANGE_HOP_COUNT
+	}
+	if change != "" {
+		t.events = append(t.events, models.RouteChangeEvent{
+			NodeAddress: uplink.SrcAddress,
+			Change:      change,
+			Previous:    meter.route,
+			Current:     route,
+			ChangedAt:   now,
+			DcuTime:     frame.DcuTime,
+		})
+		meter.routeChanges++
+	}
+	if change == constants.CHANGE_SINK {
+		// the old sink's hops and latencies say nothing about the new one
+		meter.samples = meter.samples[:0]
+		meter.next = 0
+	}
+
+	meter.route = route
+	meter.add(sample{hopCount: uplink.HopCount, travelTime: uplink.TravelTime}, t.windowSize)
+	meter.lastSeenAt = now
+	meter.lastDcuTime = frame.DcuTime
+
+	return true
+}
+
+func (m *meterState) add(s sample, windowSize int) {
+	if len(m.samples) < windowSize {
+		m.samples = append(m.samples, s)
+		return
+	}
+	m.samples[m.next] = s
+	m.next = (m.next + 1) % windowSize
+}
+
+// Flush drops the meters not heard within the stale period and publishes
+// the route changes seen since the last flush.
+func (t *TopologyService) Flush() {
+	t.flush(time.Now())
+}
+
+func (t *TopologyService) flush(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.staleAfter > 0 {
+		for nodeAddress, meter := range t.meters {
+			if now.Sub(meter.lastSeenAt) > t.staleAfter {
+				delete(t.meters, nodeAddress)
+			}
+		}
+	}
+
+	if len(t.events) == 0 {
+		return
+	}
+	events := make([][]byte, 0, len(t.events))
+	for _, event := range t.events {
+		if data, err := json.Marshal(event); err == nil {
+			events = append(events, data)
+		}
+	}
+	t.events = nil
+	t.logger.Debugf("<flush> %d route change events", len(events))
+	go t.KafkaProducer.ProduceMessagesInBatch(t.cfg.KafkaTopicsConfig.PRODUCE_TOPOLOGY_ROUTE_CHANGE_KAFKA_TOPIC_NAME, events)
+}
+
+func (t *TopologyService) meterRoute(nodeAddress uint32, meter *meterState) models.MeterRoute {
+	route := models.MeterRoute{
+		NodeAddress:  nodeAddress,
+		Route:        meter.route,
+		RouteChanges: meter.routeChanges,
+		FirstSeenAt:  meter.firstSeenAt,
+		LastSeenAt:   meter.lastSeenAt,
+		LastDcuTime:  meter.lastDcuTime,
+	}
+	route.Hops = stats(meter, func(s sample) uint32 { return uint32(s.hopCount) })
+	route.TravelTime = stats(meter, func(s sample) uint32 { return s.travelTime })
+
+	weak := t.cfg.TopologyConfig
+	route.Weak = (weak.WeakHopCount > 0 && route.Hops.Mean >= float64(weak.WeakHopCount)) ||
+		(weak.WeakTravelTime > 0 && route.TravelTime.Mean >= float64(weak.WeakTravelTime))
+	return route
+}
+
+func stats(meter *meterState, value func(sample) uint32) models.Stats {
+	var result models.Stats
+	if len(meter.samples) == 0 {
+		return result
+	}
+	var sum float64
+	for i, s := range meter.samples {
+		v := value(s)
+		if i == 0 || v < result.Min {
+			result.Min = v
+		}
+		if v > result.Max {
+			result.Max = v
+		}
+		sum += float64(v)
+	}
+	// the newest sample sits just before the next slot to overwrite, which
+	// stays 0 until the ring is full
+	last := (meter.next + len(meter.samples) - 1) % len(meter.samples)
+	result.Samples = len(meter.samples)
+	result.Mean = sum / float64(len(meter.samples))
+	result.Last = value(meter.samples[last])
+	return result
+}
+
+// GetRoute returns the route and statistics of a meter heard within the
+// topology window.
+func (t *TopologyService) GetRoute(nodeAddress uint32) (models.MeterRoute, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	meter, ok := t.meters[nodeAddress]
+	if !ok {
+		return models.MeterRoute{}, false
+	}
+	return t.meterRoute(nodeAddress, meter), true
+}
+
+// Graph builds the meter -> sink -> DCU graph of the meters matching filter.
+// Nodes and edges are sorted so that renderings of the same state are equal.
+func (t *TopologyService) Graph(filter models.GraphFilter) models.Graph {
+	t.mu.Lock()
+	routes := make([]models.MeterRoute, 0, len(t.meters))
+	for nodeAddress, meter := range t.meters {
+		if filter.DcuNumber != nil && meter.route.DcuNumber != *filter.DcuNumber {
+			continue
+		}
+		if filter.SinkID != nil && meter.route.SinkID != *filter.SinkID {
+			continue
+		}
+		route := t.meterRoute(nodeAddress, meter)
+		if filter.WeakOnly && !route.Weak {
+			continue
+		}
+		routes = append(routes, route)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(routes, func(i, j int) bool { return routes[i].NodeAddress < routes[j].NodeAddress })
+
+	graph := models.Graph{GeneratedAt: time.Now(), Nodes: []models.GraphNode{}, Edges: []models.GraphEdge{}}
+	sinkEdges := make(map[string]*models.GraphEdge)
+	var sinkIDs []string
+	dcus := make(map[uint32]bool)
+
+	for i := range routes {
+		route := &routes[i]
+		meterID := fmt.Sprintf("%s:%d", constants.NODE_METER, route.NodeAddress)
+		sinkID := fmt.Sprintf("%s:%d:%d", constants.NODE_SINK, route.Route.DcuNumber, route.Route.SinkID)
+		dcuID := fmt.Sprintf("%s:%d", constants.NODE_DCU, route.Route.DcuNumber)
+
+		graph.Nodes = append(graph.Nodes, models.GraphNode{
+			ID:    meterID,
+			Kind:  constants.NODE_METER,
+			Label: fmt.Sprintf("meter %d", route.NodeAddress),
+		})
+		graph.Edges = append(graph.Edges, models.GraphEdge{
+			Source:       meterID,
+			Target:       sinkID,
+			Hops:         &route.Hops,
+			TravelTime:   &route.TravelTime,
+			RouteChanges: route.RouteChanges,
+			Weak:         route.Weak,
+		})
+
+		sinkEdge, ok := sinkEdges[sinkID]
+		if !ok {
+			sinkEdge = &models.GraphEdge{Source: sinkID, Target: dcuID}
+			sinkEdges[sinkID] = sinkEdge
+			sinkIDs = append(sinkIDs, sinkID)
+			graph.Nodes = append(graph.Nodes, models.GraphNode{
+				ID:    sinkID,
+				Kind:  constants.NODE_SINK,
+				Label: fmt.Sprintf("sink %d (dcu %d)", route.Route.SinkID, route.Route.DcuNumber),
+			})
+		}
+		sinkEdge.Meters++
+		if route.Weak {
+			sinkEdge.WeakMeters++
+			sinkEdge.Weak = true
+		}
+
+		if !dcus[route.Route.DcuNumber] {
+			dcus[route.Route.DcuNumber] = true
+			graph.Nodes = append(graph.Nodes, models.GraphNode{
+				ID:    dcuID,
+				Kind:  constants.NODE_DCU,
+				Label: fmt.Sprintf("dcu %d", route.Route.DcuNumber),
+			})
+		}
+	}
+
+	sort.Strings(sinkIDs)
+	for _, sinkID := range sinkIDs {
+		graph.Edges = append(graph.Edges, *sinkEdges[sinkID])
+	}
+	sort.SliceStable(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+
+	return graph
+}
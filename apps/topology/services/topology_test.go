@@ -0,0 +1,136 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
+	"parsing-service/apps/topology/constants"
+	"parsing-service/apps/topology/models"
+	"parsing-service/pkg/config"
+	"parsing-service/pkg/logger"
+	"parsing-service/pkg/wp"
+)
+
+// eventProducer hands the published batches over on a channel, the other
+// methods are not used by the tests.
+type eventProducer struct {
+	kafkaIntf.IKafkaProducer
+	batches chan [][]byte
+}
+
+func (p *eventProducer) ProduceMessagesInBatch(topic string, messages [][]byte) error {
+	p.batches <- messages
+	return nil
+}
+
+func newTestTopology(windowSize int, staleAfter time.Duration) (*TopologyService, *eventProducer) {
+	cfg := &config.Configuration{}
+	cfg.TopologyConfig.WindowSize = windowSize
+	cfg.TopologyConfig.StaleAfterMs = int(staleAfter / time.Millisecond)
+	cfg.TopologyConfig.WeakHopCount = 4
+	producer := &eventProducer{batches: make(chan [][]byte, 8)}
+	return NewTopologyService(producer, cfg, logger.NewLogger()), producer
+}
+
+func flushedEvents(t *testing.T, topology *TopologyService, producer *eventProducer, now time.Time) []models.RouteChangeEvent {
+	t.Helper()
+	topology.flush(now)
+	select {
+	case batch := <-producer.batches:
+		events := make([]models.RouteChangeEvent, len(batch))
+		for i, data := range batch {
+			if err := json.Unmarshal(data, &events[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return events
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
+
+func meterUplink(nodeAddress uint32, hopCount uint8, travelTime uint32) wp.Message {
+	return wp.Message{Type: wp.UplinkMsg, Uplink: wp.Uplink{SrcAddress: nodeAddress, HopCount: hopCount, TravelTime: travelTime}}
+}
+
+func TestTopologyRouteChanges(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	topology, producer := newTestTopology(10, 0)
+	sink1 := wp.Frame{DcuNumber: 300, SinkID: 1}
+	sink2 := wp.Frame{DcuNumber: 300, SinkID: 2}
+
+	if topology.Observe(sink1, wp.Message{Type: wp.DcuDiagRespMsg}, start) {
+		t.Fatal("a diagnostic response was observed as an uplink")
+	}
+	topology.Observe(sink1, meterUplink(7, 2, 100), start)
+	topology.Observe(sink1, meterUplink(7, 2, 120), start.Add(time.Minute))
+	if events := flushedEvents(t, topology, producer, start.Add(time.Minute)); len(events) != 0 {
+		t.Fatalf("a steady route yielded %v", events)
+	}
+
+	topology.Observe(sink1, meterUplink(7, 3, 150), start.Add(2*time.Minute))
+	topology.Observe(sink2, meterUplink(7, 1, 50), start.Add(3*time.Minute))
+	events := flushedEvents(t, topology, producer, start.Add(3*time.Minute))
+	if len(events) != 2 {
+		t.Fatalf("events %v, want a hop count and a sink change", events)
+	}
+	if events[0].Change != constants.CHANGE_HOP_COUNT || events[0].Previous.HopCount != 2 || events[0].Current.HopCount != 3 {
+		t.Fatalf("first event %+v, want hops 2 -> 3", events[0])
+	}
+	if events[1].Change != constants.CHANGE_SINK || events[1].Previous.SinkID != 1 || events[1].Current.SinkID != 2 {
+		t.Fatalf("second event %+v, want sink 1 -> 2", events[1])
+	}
+
+	route, ok := topology.GetRoute(7)
+	if !ok {
+		t.Fatal("meter 7 not found")
+	}
+	if route.RouteChanges != 2 || route.Route.SinkID != 2 {
+		t.Fatalf("route %+v, want 2 changes on sink 2", route)
+	}
+	// the sink change starts a new window
+	if route.Hops.Samples != 1 || route.Hops.Last != 1 || route.TravelTime.Mean != 50 {
+		t.Fatalf("window %+v / %+v, want only the uplink through sink 2", route.Hops, route.TravelTime)
+	}
+}
+
+func TestTopologyRollingWindow(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	topology, _ := newTestTopology(3, 0)
+	frame := wp.Frame{DcuNumber: 300, SinkID: 1}
+
+	for i, travelTime := range []uint32{10, 20, 30, 40, 50} {
+		topology.Observe(frame, meterUplink(7, 5, travelTime), start.Add(time.Duration(i)*time.Minute))
+	}
+	route, _ := topology.GetRoute(7)
+	if route.TravelTime.Samples != 3 || route.TravelTime.Min != 30 || route.TravelTime.Max != 50 ||
+		route.TravelTime.Mean != 40 || route.TravelTime.Last != 50 {
+		t.Fatalf("travel time %+v, want the last three uplinks", route.TravelTime)
+	}
+	if !route.Weak {
+		t.Fatal("a meter at 5 hops is not weak")
+	}
+}
+
+func TestTopologyDropsStaleMeters(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	topology, _ := newTestTopology(10, time.Hour)
+	frame := wp.Frame{DcuNumber: 300, SinkID: 1}
+
+	topology.Observe(frame, meterUplink(7, 1, 10), start)
+	topology.Observe(frame, meterUplink(8, 1, 10), start.Add(30*time.Minute))
+	topology.flush(start.Add(time.Hour + time.Minute))
+
+	if _, ok := topology.GetRoute(7); ok {
+		t.Fatal("meter 7 is still in the graph after the stale period")
+	}
+	if _, ok := topology.GetRoute(8); !ok {
+		t.Fatal("meter 8 dropped inside the stale period")
+	}
+	graph := topology.Graph(models.GraphFilter{})
+	if len(graph.Edges) != 2 || graph.Edges[1].Meters != 1 {
+		t.Fatalf("edges %+v, want meter 8 on its sink", graph.Edges)
+	}
+}
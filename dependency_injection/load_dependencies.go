@@ -1,6 +1,16 @@
 package dependencyinjection
 
 import (
+	captureController "parsing-service/apps/capture/controller"
+	otapController "parsing-service/apps/otap/controller"
+	sinkController "parsing-service/apps/sinks/controller"
+	topologyController "parsing-service/apps/topology/controller"
+	downlinkController "parsing-service/apps/downlinks/controller"
+	clockController "parsing-service/apps/clock/controller"
+	presenceController "parsing-service/apps/presence/controller"
+	kafkaController "parsing-service/apps/kafka/controller"
+	presenceServiceIntf "parsing-service/apps/presence/service_interfaces"
+	downlinkServiceIntf "parsing-service/apps/downlinks/service_interfaces"
 	decoderServiceIntf "parsing-service/apps/decoder/service_interfaces"
 	"parsing-service/pkg/config"
 	"parsing-service/pkg/database"
@@ -17,6 +27,13 @@ func LoadDependecies() {
 		configModule,
 		loggerModule,
 		kafkaFactoy,
+		captureModule,
+		otapModule,
+		sinkModule,
+		topologyModule,
+		downlinkModule,
+		clockModule,
+		presenceModule,
 		decoderModule,
 		routerModule,
 
@@ -29,6 +46,8 @@ func LoadDependecies() {
 func StartKafkaConsumer(
 	logger logger.ILogger,
 	decoderHandler decoderServiceIntf.IDecoderKafkaConsumerService,
+	downlinkConsumer downlinkServiceIntf.IDownlinkConsumerService,
+	presenceRegistry presenceServiceIntf.IPresenceRegistry,
 ) {
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -36,6 +55,8 @@ func StartKafkaConsumer(
 		defer wg.Done()
 		decoderHandler.FetchData()
 	}()
+	downlinkConsumer.FetchDownlinks()
+	presenceRegistry.StartScheduler()
 
 }
 
@@ -51,6 +72,17 @@ func initializeConnectionsAndConfig(logger logger.ILogger) {
 }
 
 
-func serveHttpRequests(r *routers.Routes) {
+func serveHttpRequests(r *routers.Routes, capture *captureController.CaptureController, otap *otapController.OtapController, sinks *sinkController.SinkController, topology *topologyController.TopologyController, downlinks *downlinkController.DownlinkController, clock *clockController.ClockController, presence *presenceController.PresenceController, kafka *kafkaController.KafkaController) {
+	routers.RegisterRoutes(r)
+	v1 := r.Router.Group("/v1")
+	routers.RegisterCaptureRoutes(v1, capture)
+	routers.RegisterOtapRoutes(v1, otap)
+	routers.RegisterSinkRoutes(v1, sinks)
+	routers.RegisterTopologyRoutes(v1, topology)
+	routers.RegisterDownlinkRoutes(v1, downlinks)
+	routers.RegisterClockRoutes(v1, clock)
+	routers.RegisterPresenceRoutes(v1, presence)
+	routers.RegisterKafkaRoutes(v1, kafka)
+
 	logger.GetLogger().Fatalf("%v", r.Router.Run(config.ServerConfig()))
 }
@@ -4,10 +4,46 @@ import (
 	"parsing-service/pkg/config"
 	"parsing-service/pkg/logger"
 
+	kafkaController "parsing-service/apps/kafka/controller"
 	kafkaImpl "parsing-service/apps/kafka/service_impl"
 	kafkaIntf "parsing-service/apps/kafka/service_interfaces"
 
+	captureController "parsing-service/apps/capture/controller"
+	captureServiceInt "parsing-service/apps/capture/service_interfaces"
+	captureServices "parsing-service/apps/capture/services"
+
+	otapController "parsing-service/apps/otap/controller"
+	otapDaoImpl "parsing-service/apps/otap/dao_impl"
+	otapDaoInt "parsing-service/apps/otap/dao_interfaces"
+	otapServiceInt "parsing-service/apps/otap/service_interfaces"
+	otapServices "parsing-service/apps/otap/services"
+
+	sinkController "parsing-service/apps/sinks/controller"
+	sinkDaoImpl "parsing-service/apps/sinks/dao_impl"
+	sinkDaoInt "parsing-service/apps/sinks/dao_interfaces"
+	sinkServiceInt "parsing-service/apps/sinks/service_interfaces"
+	sinkServices "parsing-service/apps/sinks/services"
+
+	topologyController "parsing-service/apps/topology/controller"
+	topologyServiceInt "parsing-service/apps/topology/service_interfaces"
+	topologyServices "parsing-service/apps/topology/services"
+
+	downlinkController "parsing-service/apps/downlinks/controller"
+	downlinkServiceInt "parsing-service/apps/downlinks/service_interfaces"
+	downlinkServices "parsing-service/apps/downlinks/services"
+
+	clockController "parsing-service/apps/clock/controller"
+	clockServiceInt "parsing-service/apps/clock/service_interfaces"
+	clockServices "parsing-service/apps/clock/services"
+	presenceController "parsing-service/apps/presence/controller"
+	presenceDaoImpl "parsing-service/apps/presence/dao_impl"
+	presenceDaoInt "parsing-service/apps/presence/dao_interfaces"
+	presenceServiceInt "parsing-service/apps/presence/service_interfaces"
+	presenceServices "parsing-service/apps/presence/services"
+
 	decoderController "parsing-service/apps/decoder/controller"
+	decoderDaoImpl "parsing-service/apps/decoder/dao_impl"
+	decoderDaoInt "parsing-service/apps/decoder/dao_interfaces"
 	decoderServiceInt "parsing-service/apps/decoder/service_interfaces"
 	decoderServices "parsing-service/apps/decoder/services"
 
@@ -31,16 +67,125 @@ var loggerModule = fx.Provide(
 
 var kafkaFactoy = fx.Options(
 	fx.Provide(
+		kafkaController.NewKafkaController,
 		fx.Annotate(
 			kafkaImpl.NewKafkaConsumerFactory,
 			fx.As(new(kafkaIntf.IKafkaConsumerFactory)),
 		),
+		fx.Annotate(
+			kafkaImpl.NewKafkaProducerRouter,
+			fx.As(new(kafkaIntf.IKafkaProducer)),
+		),
+	),
+)
+
+var captureModule = fx.Options(
+	fx.Provide(
+		captureController.NewCaptureController,
+		fx.Annotate(
+			captureServices.NewCaptureService,
+			fx.As(new(captureServiceInt.ICaptureService)),
+		),
+	),
+)
+
+var otapModule = fx.Options(
+	fx.Provide(
+		otapController.NewOtapController,
+		fx.Annotate(
+			otapDaoImpl.NewOtapSessionDAO,
+			fx.As(new(otapDaoInt.IOtapSessionDAO)),
+		),
+		fx.Annotate(
+			otapServices.NewOtapTracker,
+			fx.As(new(otapServiceInt.IOtapTracker)),
+		),
+	),
+)
+
+var sinkModule = fx.Options(
+	fx.Provide(
+		sinkController.NewSinkController,
+		fx.Annotate(
+			sinkDaoImpl.NewMeterSinkAttachmentDAO,
+			fx.As(new(sinkDaoInt.IMeterSinkAttachmentDAO)),
+		),
+		fx.Annotate(
+			sinkServices.NewSinkRegistry,
+			fx.As(new(sinkServiceInt.ISinkRegistry)),
+		),
+	),
+)
+
+var topologyModule = fx.Options(
+	fx.Provide(
+		topologyController.NewTopologyController,
+		fx.Annotate(
+			topologyServices.NewTopologyService,
+			fx.As(new(topologyServiceInt.ITopologyService)),
+		),
+	),
+)
+
+var downlinkModule = fx.Options(
+	fx.Provide(
+		downlinkController.NewDownlinkController,
+		fx.Annotate(
+			downlinkServices.NewDownlinkStore,
+			fx.As(new(downlinkServiceInt.IDownlinkStore)),
+		),
+		fx.Annotate(
+			downlinkServices.NewDownlinkConsumer,
+			fx.As(new(downlinkServiceInt.IDownlinkConsumerService)),
+		),
+	),
+)
+
+var clockModule = fx.Options(
+	fx.Provide(
+		clockController.NewClockController,
+		fx.Annotate(
+			clockServices.NewClockTracker,
+			fx.As(new(clockServiceInt.IClockTracker)),
+		),
+	),
+)
+
+var presenceModule = fx.Options(
+	fx.Provide(
+		presenceController.NewPresenceController,
+		fx.Annotate(
+			presenceDaoImpl.NewPresenceDAO,
+			fx.As(new(presenceDaoInt.IPresenceDAO)),
+		),
+		fx.Annotate(
+			presenceServices.NewPresenceRegistry,
+			fx.As(new(presenceServiceInt.IPresenceRegistry)),
+		),
 	),
 )
 
 var decoderModule = fx.Options(
 	fx.Provide(
 		decoderController.NewDecoderController,
+		decoderServices.NewFrameChecksums,
+		decoderServices.NewReadingValidator,
+		fx.Annotate(
+			decoderDaoImpl.NewCommandMappingDAO,
+			fx.As(new(decoderDaoInt.ICommandMappingDAO)),
+		),
+		fx.Annotate(
+			decoderDaoImpl.NewDeserializeLogicsDAO,
+			fx.As(new(decoderDaoInt.IDeserializeLogicsDAO)),
+		),
+		fx.Annotate(
+			decoderDaoImpl.NewEventCatalogueDAO,
+			fx.As(new(decoderDaoInt.IEventCatalogueDAO)),
+		),
+		fx.Annotate(
+			decoderDaoImpl.NewFieldCatalogueDAO,
+			fx.As(new(decoderDaoInt.IFieldCatalogueDAO)),
+		),
 		fx.Annotate(
 			decoderServices.NewKafkaConsumerHandler,
 			fx.As(new(decoderServiceInt.IDecoderKafkaConsumerService)),
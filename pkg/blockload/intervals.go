@@ -0,0 +1,131 @@
+package blockload
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"parsing-service/pkg/tap"
+)
+
+// layout BlockLoadParser formats its date times with, in UTC
+const DATE_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+const DAY_LAYOUT = "2006-01-02"
+
+// record type the field catalogue knows interval records by
+const RECORD_TYPE_INTERVAL = "block_load_interval"
+
+// SlotStatus tells whether the meter recorded an interval. Slots ending after
+// the record was taken are not due yet and stay pending.
+type SlotStatus string
+
+const (
+	SLOT_PRESENT SlotStatus = "present"
+	SLOT_MISSING SlotStatus = "missing"
+	SLOT_PENDING SlotStatus = "pending"
+)
+
+var (
+	ErrNoBitMask   = errors.New("block load record carries no interval bitmask")
+	ErrBitMaskSize = errors.New("block load bitmask does not divide the day into whole minutes")
+)
+
+// Interval is one slot of a meter's day, expanded from the bitmask of a
+// block-load record.
+type Interval struct {
+	MeterIp string     `json:"meter_ip"`
+	DcuNo   uint64     `json:"dcu_no"`
+	Day     string     `json:"day"`
+	Slot    int        `json:"slot"`
+	Start   time.Time  `json:"slot_start"`
+	End     time.Time  `json:"slot_end"`
+	Status  SlotStatus `json:"status"`
+}
+
+// Completeness sums up the slots of a meter's day.
+type Completeness struct {
+	MeterIp         string    `json:"meter_ip"`
+	DcuNo           uint64    `json:"dcu_no"`
+	Day             string    `json:"day"`
+	IntervalMinutes int       `json:"interval_minutes"`
+	Slots           int       `json:"slots"`
+	Due             int       `json:"due"`
+	Present         int       `json:"present"`
+	Missing         int       `json:"missing"`
+	MissingSlots    []int     `json:"missing_slots"`
+	Completeness    float64   `json:"completeness"`
+	RecordedAt      time.Time `json:"recorded_at"`
+}
+
+// Expand splits the day of a block-load record into the slots of its
+// bitmask, the first bit being the slot starting at midnight in location. A
+// set bit marks a recorded slot. Days are taken to be 24 hours long, so a
+// location with daylight saving shifts the slots of its transition days.
+func Expand(record tap.BlockLoadParser, location *time.Location) ([]Interval, error) {
+	if record.BitMaskString == "" {
+		return nil, ErrNoBitMask
+	}
+	recordedAt, err := time.Parse(DATE_TIME_LAYOUT, record.BlockLoadDateTime)
+	if err != nil {
+		return nil, fmt.Errorf("block load date time %q: %w", record.BlockLoadDateTime, err)
+	}
+	slots := len(record.BitMaskString)
+	if (24*60)%slots != 0 {
+		return nil, fmt.Errorf("%w: %d slots", ErrBitMaskSize, slots)
+	}
+	length := time.Duration(24*60/slots) * time.Minute
+
+	local := recordedAt.In(location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	intervals := make([]Interval, slots)
+	for slot := range intervals {
+		start := midnight.Add(time.Duration(slot) * length)
+		status := SLOT_MISSING
+		switch {
+		case record.BitMaskString[slot] == '1':
+			status = SLOT_PRESENT
+		case start.Add(length).After(recordedAt):
+			status = SLOT_PENDING
+		}
+		intervals[slot] = Interval{
+			MeterIp: record.MeterIp,
+			DcuNo:   record.DcuNo,
+			Day:     midnight.Format(DAY_LAYOUT),
+			Slot:    slot,
+			Start:   start,
+			End:     start.Add(length),
+			Status:  status,
+		}
+	}
+	return intervals, nil
+}
+
+// Summarize sums up the intervals Expand returned for one record. The
+// completeness is the share of due slots that are present, 1 when none is
+// due yet.
+func Summarize(intervals []Interval, recordedAt time.Time) Completeness {
+	summary := Completeness{Slots: len(intervals), MissingSlots: []int{}, Completeness: 1, RecordedAt: recordedAt}
+	if len(intervals) == 0 {
+		return summary
+	}
+	summary.MeterIp = intervals[0].MeterIp
+	summary.DcuNo = intervals[0].DcuNo
+	summary.Day = intervals[0].Day
+	summary.IntervalMinutes = int(intervals[0].End.Sub(intervals[0].Start).Minutes())
+
+	for _, interval := range intervals {
+		switch interval.Status {
+		case SLOT_PRESENT:
+			summary.Present++
+		case SLOT_MISSING:
+			summary.Missing++
+			summary.MissingSlots = append(summary.MissingSlots, interval.Slot)
+		}
+	}
+	summary.Due = summary.Present + summary.Missing
+	if summary.Due > 0 {
+		summary.Completeness = float64(summary.Present) / float64(summary.Due)
+	}
+	return summary
+}
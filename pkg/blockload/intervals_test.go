@@ -0,0 +1,168 @@
+package blockload
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"parsing-service/pkg/tap"
+)
+
+var ist = time.FixedZone("IST", 5*3600+1800)
+
+var statusMarks = map[SlotStatus]string{SLOT_PRESENT: "1", SLOT_MISSING: "0", SLOT_PENDING: "-"}
+
+// statuses spells the slot statuses of intervals like a bitmask, pending
+// slots as -
+func statuses(intervals []Interval) string {
+	var b strings.Builder
+	for _, interval := range intervals {
+		b.WriteString(statusMarks[interval.Status])
+	}
+	return b.String()
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name         string
+		dateTime     string
+		bitMask      string
+		location     *time.Location
+		wantDay      string
+		wantStart    time.Time
+		wantStatuses string
+	}{
+		{
+			name:         "the first bit is the slot at midnight",
+			dateTime:     "2024-05-01 23:00:00",
+			bitMask:      "10000001",
+			location:     time.UTC,
+			wantDay:      "2024-05-01",
+			wantStart:    time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			wantStatuses: "10000001",
+		},
+		{
+			name:         "unset slots ending after the record are pending",
+			dateTime:     "2024-05-01 07:30:00",
+			bitMask:      "10000000",
+			location:     time.UTC,
+			wantDay:      "2024-05-01",
+			wantStart:    time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			wantStatuses: "10------",
+		},
+		{
+			name:         "a slot ending at the record time is due",
+			dateTime:     "2024-05-01 06:00:00",
+			bitMask:      "00000000",
+			location:     time.UTC,
+			wantDay:      "2024-05-01",
+			wantStart:    time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			wantStatuses: "00------",
+		},
+		{
+			name:         "days start at midnight in the configured zone",
+			dateTime:     "2024-05-01 20:00:00",
+			bitMask:      "10000000",
+			location:     ist,
+			wantDay:      "2024-05-02",
+			wantStart:    time.Date(2024, 5, 1, 18, 30, 0, 0, time.UTC),
+			wantStatuses: "1-------",
+		},
+		{
+			name:         "the zone also moves the record into the previous day",
+			dateTime:     "2024-05-01 18:00:00",
+			bitMask:      "11111110",
+			location:     ist,
+			wantDay:      "2024-05-01",
+			wantStart:    time.Date(2024, 4, 30, 18, 30, 0, 0, time.UTC),
+			wantStatuses: "1111111-",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := tap.BlockLoadParser{MeterIp: "10.0.0.1", DcuNo: 7, BlockLoadDateTime: tt.dateTime, BitMaskString: tt.bitMask}
+			intervals, err := Expand(record, tt.location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := statuses(intervals); got != tt.wantStatuses {
+				t.Fatalf("statuses %s, want %s", got, tt.wantStatuses)
+			}
+			first := intervals[0]
+			if first.Day != tt.wantDay || !first.Start.Equal(tt.wantStart) || first.End.Sub(first.Start) != 3*time.Hour {
+				t.Fatalf("first slot %s %v-%v, want %s from %v", first.Day, first.Start, first.End, tt.wantDay, tt.wantStart)
+			}
+			if last := intervals[len(intervals)-1]; !last.End.Equal(tt.wantStart.Add(24 * time.Hour)) {
+				t.Fatalf("last slot ends %v, want the next midnight", last.End)
+			}
+		})
+	}
+}
+
+func TestExpandRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		dateTime string
+		bitMask  string
+		wantErr  error
+	}{
+		{"no bitmask", "2024-05-01 07:30:00", "", ErrNoBitMask},
+		{"slots of a fraction of a minute", "2024-05-01 07:30:00", "1111111", ErrBitMaskSize},
+		{"unparsable date time", "01/05/2024 07:30", "11110000", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Expand(tap.BlockLoadParser{BlockLoadDateTime: tt.dateTime, BitMaskString: tt.bitMask}, time.UTC)
+			if err == nil {
+				t.Fatal("expanded")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name             string
+		dateTime         string
+		bitMask          string
+		wantDue          int
+		wantMissingSlots []int
+		wantCompleteness float64
+	}{
+		{"pending slots are not due", "2024-05-01 07:30:00", "10000000", 2, []int{1}, 0.5},
+		{"a complete day so far", "2024-05-01 12:00:00", "11110000", 4, []int{}, 1},
+		{"nothing due yet", "2024-05-01 01:00:00", "00000000", 0, []int{}, 1},
+		{"a recorded last slot is due before the day ends", "2024-05-01 23:59:59", "10110111", 8, []int{1, 4}, 0.75},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recordedAt, _ := time.Parse(DATE_TIME_LAYOUT, tt.dateTime)
+			record := tap.BlockLoadParser{MeterIp: "10.0.0.1", BlockLoadDateTime: tt.dateTime, BitMaskString: tt.bitMask}
+			intervals, err := Expand(record, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			summary := Summarize(intervals, recordedAt)
+			if summary.Slots != 8 || summary.IntervalMinutes != 180 || summary.Due != tt.wantDue ||
+				summary.Present+summary.Missing != summary.Due || summary.Completeness != tt.wantCompleteness {
+				t.Fatalf("summary %+v, want %d due at %v", summary, tt.wantDue, tt.wantCompleteness)
+			}
+			if len(summary.MissingSlots) != len(tt.wantMissingSlots) {
+				t.Fatalf("missing slots %v, want %v", summary.MissingSlots, tt.wantMissingSlots)
+			}
+			for i, slot := range tt.wantMissingSlots {
+				if summary.MissingSlots[i] != slot {
+					t.Fatalf("missing slots %v, want %v", summary.MissingSlots, tt.wantMissingSlots)
+				}
+			}
+		})
+	}
+
+	if summary := Summarize(nil, time.Now()); summary.Slots != 0 || summary.Completeness != 1 {
+		t.Fatalf("empty summary %+v", summary)
+	}
+}
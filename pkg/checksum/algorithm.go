@@ -0,0 +1,145 @@
+package checksum
+
+import "fmt"
+
+// Padding is applied to the data before the crc is computed.
+type Padding string
+
+const (
+	PAD_NONE Padding = "none"
+	// zero-pad odd-length data to an even length, as TAP and WP frames do
+	PAD_EVEN Padding = "even"
+)
+
+// Params describe a crc in the usual Rocksoft model terms. Poly, Init and
+// XorOut are given unreflected, Width is 16 or 32.
+type Params struct {
+	Name    string
+	Width   int
+	Poly    uint32
+	Init    uint32
+	RefIn   bool
+	RefOut  bool
+	XorOut  uint32
+	Padding Padding
+}
+
+// Algorithm is a table-driven crc built from Params. It is immutable and safe
+// for concurrent use.
+type Algorithm struct {
+	Params
+	mask  uint32
+	table [256]uint32
+}
+
+func NewAlgorithm(params Params) (*Algorithm, error) {
+	if params.Width != 16 && params.Width != 32 {
+		return nil, fmt.Errorf("checksum %s: width %d not supported, use 16 or 32", params.Name, params.Width)
+	}
+	if params.Padding == "" {
+		params.Padding = PAD_NONE
+	}
+	if params.Padding != PAD_NONE && params.Padding != PAD_EVEN {
+		return nil, fmt.Errorf("checksum %s: unknown padding %q", params.Name, params.Padding)
+	}
+
+	a := &Algorithm{Params: params, mask: uint32(1<<params.Width - 1)}
+	if params.Width == 32 {
+		a.mask = 0xFFFFFFFF
+	}
+	a.Poly &= a.mask
+	a.Init &= a.mask
+	a.XorOut &= a.mask
+
+	if a.RefIn {
+		poly := reflect(a.Poly, a.Width)
+		for i := range a.table {
+			crc := uint32(i)
+			for j := 0; j < 8; j++ {
+				if crc&1 != 0 {
+					crc = crc>>1 ^ poly
+				} else {
+					crc >>= 1
+				}
+			}
+			a.table[i] = crc
+		}
+	} else {
+		topBit := uint32(1) << (a.Width - 1)
+		for i := range a.table {
+			crc := uint32(i) << (a.Width - 8)
+			for j := 0; j < 8; j++ {
+				if crc&topBit != 0 {
+					crc = crc<<1 ^ a.Poly
+				} else {
+					crc <<= 1
+				}
+			}
+			a.table[i] = crc & a.mask
+		}
+	}
+
+	return a, nil
+}
+
+func mustAlgorithm(params Params) *Algorithm {
+	a, err := NewAlgorithm(params)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Size is the number of bytes the crc takes on the wire.
+func (a *Algorithm) Size() int {
+	return a.Width / 8
+}
+
+// Checksum computes the crc of data, padding it first if the algorithm asks
+// for it.
+func (a *Algorithm) Checksum(data []byte) uint32 {
+	crc := a.Init
+	if a.RefIn {
+		crc = reflect(crc, a.Width)
+		for _, b := range data {
+			crc = crc>>8 ^ a.table[byte(crc)^b]
+		}
+		if a.Padding == PAD_EVEN && len(data)%2 != 0 {
+			crc = crc>>8 ^ a.table[byte(crc)]
+		}
+	} else {
+		shift := a.Width - 8
+		for _, b := range data {
+			crc = (crc<<8 ^ a.table[byte(crc>>shift)^b]) & a.mask
+		}
+		if a.Padding == PAD_EVEN && len(data)%2 != 0 {
+			crc = (crc<<8 ^ a.table[byte(crc>>shift)]) & a.mask
+		}
+	}
+
+	if a.RefIn != a.RefOut {
+		crc = reflect(crc, a.Width)
+	}
+	return (crc ^ a.XorOut) & a.mask
+}
+
+// Verify compares the crc of data with the crc stored big-endian in stored,
+// which must hold Size bytes.
+func (a *Algorithm) Verify(data []byte, stored []byte) (bool, uint32, uint32) {
+	var inPacket uint32
+	for _, b := range stored {
+		inPacket = inPacket<<8 | uint32(b)
+	}
+	computed := a.Checksum(data)
+	return len(stored) == a.Size() && computed == inPacket, inPacket, computed
+}
+
+func reflect(value uint32, width int) uint32 {
+	var reflected uint32
+	for i := 0; i < width; i++ {
+		if value&(1<<i) != 0 {
+			reflected |= 1 << (width - 1 - i)
+		}
+	}
+	return reflected
+}
@@ -0,0 +1,97 @@
+package checksum
+
+import "testing"
+
+// check values of the catalogue of parametrised crc algorithms, the crc of
+// the ASCII digits "123456789"
+func TestCheckValues(t *testing.T) {
+	check := []byte("123456789")
+	for _, tc := range []struct {
+		alg  *Algorithm
+		want uint32
+	}{
+		{CRC16_XMODEM, 0x31C3},
+		{CRC16_CCITT_FALSE, 0x29B1},
+		{CRC16_MODBUS, 0x4B37},
+		{CRC32, 0xCBF43926},
+		// nine digits are padded with a zero byte
+		{FRAME_DEFAULT, 0xE572},
+	} {
+		if got := tc.alg.Checksum(check); got != tc.want {
+			t.Errorf("%s: %X, want %X", tc.alg.Name, got, tc.want)
+		}
+	}
+}
+
+func TestEvenPadding(t *testing.T) {
+	for _, data := range [][]byte{nil, []byte("12345678"), []byte("123456789\x00")} {
+		if got, want := FRAME_DEFAULT.Checksum(data), CRC16_XMODEM.Checksum(data); got != want {
+			t.Errorf("%q: even-padded %X, unpadded %X", data, got, want)
+		}
+	}
+	if got, want := FRAME_DEFAULT.Checksum([]byte("123456789")), CRC16_XMODEM.Checksum([]byte("123456789\x00")); got != want {
+		t.Errorf("odd length: %X, want the crc of the zero-padded data %X", got, want)
+	}
+
+	reflected := mustAlgorithm(Params{Name: "MODBUS_EVEN", Width: 16, Poly: 0x8005, Init: 0xFFFF, RefIn: true, RefOut: true, Padding: PAD_EVEN})
+	if got, want := reflected.Checksum([]byte("123456789")), CRC16_MODBUS.Checksum([]byte("123456789\x00")); got != want {
+		t.Errorf("reflected odd length: %X, want %X", got, want)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	data := []byte("123456789")
+	if ok, stored, computed := CRC16_XMODEM.Verify(data, []byte{0x31, 0xC3}); !ok {
+		t.Fatalf("stored %X, computed %X", stored, computed)
+	}
+	if ok, _, _ := CRC16_XMODEM.Verify(data, []byte{0xC3, 0x31}); ok {
+		t.Fatal("a little-endian crc verified")
+	}
+	if ok, _, _ := CRC16_XMODEM.Verify(data, []byte{0x00, 0x31, 0xC3}); ok {
+		t.Fatal("a crc of the wrong size verified")
+	}
+	if ok, _, _ := CRC32.Verify(data, []byte{0xCB, 0xF4, 0x39, 0x26}); !ok {
+		t.Fatal("crc32 did not verify")
+	}
+}
+
+func TestNewAlgorithmRejects(t *testing.T) {
+	for _, params := range []Params{
+		{Name: "CRC8", Width: 8, Poly: 0x07},
+		{Name: "ODD_PAD", Width: 16, Poly: 0x1021, Padding: "odd"},
+	} {
+		if _, err := NewAlgorithm(params); err == nil {
+			t.Errorf("%s accepted", params.Name)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	if a, err := r.Get("crc16_modbus"); err != nil || a != CRC16_MODBUS {
+		t.Fatalf("Get = %v, %v", a, err)
+	}
+	if _, err := r.Get("CRC64"); err == nil {
+		t.Fatal("an unregistered variant was found")
+	}
+	if err := r.AssignGroup(3, "CRC64"); err == nil {
+		t.Fatal("a group was assigned an unregistered variant")
+	}
+
+	kermit, err := r.Register(Params{Name: "crc16_kermit", Width: 16, Poly: 0x1021, RefIn: true, RefOut: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := kermit.Checksum([]byte("123456789")); got != 0x2189 {
+		t.Fatalf("kermit check value %X, want 2189", got)
+	}
+	if err := r.AssignGroup(3, "CRC16_KERMIT"); err != nil {
+		t.Fatal(err)
+	}
+	if a := r.ForGroup(3, FRAME_DEFAULT); a != kermit {
+		t.Fatalf("group 3 uses %s", a.Name)
+	}
+	if a := r.ForGroup(4, FRAME_DEFAULT); a != FRAME_DEFAULT {
+		t.Fatalf("group 4 uses %s, want the fallback", a.Name)
+	}
+}
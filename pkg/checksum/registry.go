@@ -0,0 +1,94 @@
+package checksum
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// names of the built-in variants
+const (
+	CRC16_XMODEM_NAME      = "CRC16_XMODEM"
+	CRC16_XMODEM_EVEN_NAME = "CRC16_XMODEM_EVEN"
+	CRC16_CCITT_FALSE_NAME = "CRC16_CCITT_FALSE"
+	CRC16_MODBUS_NAME      = "CRC16_MODBUS"
+	CRC32_NAME             = "CRC32"
+)
+
+var (
+	CRC16_XMODEM      = mustAlgorithm(Params{Name: CRC16_XMODEM_NAME, Width: 16, Poly: 0x1021})
+	CRC16_CCITT_FALSE = mustAlgorithm(Params{Name: CRC16_CCITT_FALSE_NAME, Width: 16, Poly: 0x1021, Init: 0xFFFF})
+	CRC16_MODBUS      = mustAlgorithm(Params{Name: CRC16_MODBUS_NAME, Width: 16, Poly: 0x8005, Init: 0xFFFF, RefIn: true, RefOut: true})
+	CRC32             = mustAlgorithm(Params{Name: CRC32_NAME, Width: 32, Poly: 0x04C11DB7, Init: 0xFFFFFFFF, RefIn: true, RefOut: true, XorOut: 0xFFFFFFFF})
+
+	// FRAME_DEFAULT is what TAP and WP frames have always been checked with
+	FRAME_DEFAULT = mustAlgorithm(Params{Name: CRC16_XMODEM_EVEN_NAME, Width: 16, Poly: 0x1021, Padding: PAD_EVEN})
+)
+
+// Registry holds the checksum variants by name and which variant each
+// firmware group uses. Names are case-insensitive.
+type Registry struct {
+	mu         sync.RWMutex
+	algorithms map[string]*Algorithm
+	groups     map[int]string
+}
+
+// NewRegistry returns a registry with the built-in variants.
+func NewRegistry() *Registry {
+	r := &Registry{algorithms: make(map[string]*Algorithm), groups: make(map[int]string)}
+	for _, a := range []*Algorithm{CRC16_XMODEM, FRAME_DEFAULT, CRC16_CCITT_FALSE, CRC16_MODBUS, CRC32} {
+		r.algorithms[a.Name] = a
+	}
+	return r
+}
+
+// Register adds a variant, replacing any variant of the same name.
+func (r *Registry) Register(params Params) (*Algorithm, error) {
+	params.Name = strings.ToUpper(params.Name)
+	a, err := NewAlgorithm(params)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.algorithms[a.Name] = a
+	return a, nil
+}
+
+func (r *Registry) Get(name string) (*Algorithm, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	a, ok := r.algorithms[strings.ToUpper(name)]
+	if !ok {
+		return nil, fmt.Errorf("checksum variant %q is not registered", name)
+	}
+	return a, nil
+}
+
+// AssignGroup makes a firmware group use the named variant.
+func (r *Registry) AssignGroup(groupID int, name string) error {
+	if _, err := r.Get(name); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.groups[groupID] = strings.ToUpper(name)
+	return nil
+}
+
+// ForGroup returns the variant of a firmware group, or fallback when the
+// group has none assigned.
+func (r *Registry) ForGroup(groupID int, fallback *Algorithm) *Algorithm {
+	r.mu.RLock()
+	name, ok := r.groups[groupID]
+	r.mu.RUnlock()
+	if !ok {
+		return fallback
+	}
+	a, err := r.Get(name)
+	if err != nil {
+		return fallback
+	}
+	return a
+}
@@ -3,12 +3,31 @@ package config
 import (
 	"fmt"
 	"parsing-service/pkg/logger"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Configuration struct {
 	KafkaConfig         KafkaConfig
+	KafkaConsumerConfig KafkaConsumerConfig
+	TapSegmentConfig    TapSegmentConfig
+	ChecksumConfig      ChecksumConfig
+	DissectorConfig     DissectorConfig
+	CaptureConfig       CaptureConfig
+	OtapConfig          OtapConfig
+	SinkConfig          SinkConfig
+	TopologyConfig      TopologyConfig
+	DownlinkConfig      DownlinkConfig
+	ClockConfig         ClockConfig
+	PresenceConfig      PresenceConfig
+	PayloadParserConfig PayloadParserConfig
+	FieldCatalogueConfig FieldCatalogueConfig
+	BlockLoadConfig     BlockLoadConfig
+	ValidationConfig    ValidationConfig
 	RedisConfig         RedisConfig
 	KafkaTopicsConfig   KafkaTopicsConfig
 	BusinessLogicConfig BusinessLogicConfig
@@ -29,6 +48,211 @@ type KafkaConfig struct {
 	KafkaSaslUsername     string
 	KafkaSaslPassword     string
 	KafkaSaslMechanism    string
+
+	// InputCluster is the cluster the decoder consumes from. OutputClusters
+	// are the clusters it produces to; topics not assigned to any of them go
+	// to the DEFAULT_KAFKA_CLUSTER_NAME cluster built from the settings above.
+	InputCluster   KafkaClusterConfig
+	OutputClusters []KafkaClusterConfig
+}
+
+type KafkaClusterConfig struct {
+	Name                  string
+	KafkaBootstrapServers string
+	KafkaSecurityProtocol string
+	KafkaSaslUsername     string
+	KafkaSaslPassword     string
+	KafkaSaslMechanism    string
+	Topics                []string
+}
+
+// KafkaConsumerConfig tunes the fetch consumer. With AdaptivePoll set the
+// batch size moves between MinPollBatchSize and MaxPollBatchSize and the
+// poll wait between MinPollWaitMs and PollMaxWaitMs, driven by processing
+// latency against TargetLatencyMs and by channel occupancy.
+type KafkaConsumerConfig struct {
+	PollBatchSize       int
+	PollMaxWaitMs       int
+	ChannelSize         int
+	SessionTimeoutMs    int
+	HeartbeatIntervalMs int
+
+	AdaptivePoll     bool
+	MinPollBatchSize int
+	MaxPollBatchSize int
+	MinPollWaitMs    int
+	TargetLatencyMs  int
+}
+
+// TapSegmentConfig drives the reassembly of responses that span several TAP
+// packets. Only responses to SegmentedCmdIDs carry a segment header, whose
+// layout is assumed rather than taken from the meter protocol (see
+// tap.SEGMENT_HEADER_LEN), so the list is empty by default; a
+// response missing packets for TimeoutMs, or evicted to stay within
+// MaxPendingSets, is published to the incomplete TAP topic.
+type TapSegmentConfig struct {
+	SegmentedCmdIDs []int
+	TimeoutMs       int
+	MaxPendingSets  int
+}
+
+// ChecksumConfig picks the crc variants frames are validated with.
+// TapVariant and WpVariant name the defaults, Variants adds variants beyond
+// the built-in ones and GroupVariants overrides the TAP variant per firmware
+// group id.
+type ChecksumConfig struct {
+	TapVariant    string
+	WpVariant     string
+	Variants      []ChecksumVariantConfig
+	GroupVariants map[int]string
+}
+
+type ChecksumVariantConfig struct {
+	Name    string
+	Width   int
+	Poly    uint32
+	Init    uint32
+	RefIn   bool
+	RefOut  bool
+	XorOut  uint32
+	Padding string
+}
+
+// DissectorConfig turns on the field by field dump of every frame the
+// consumer handles. Mode is off, text or json.
+type DissectorConfig struct {
+	Mode string
+}
+
+// CaptureConfig says where pcapng captures are written and which DCUs and
+// meter IPs are captured from startup. More can be switched on at runtime.
+// A target's file is rotated once it reaches MaxFileBytes, and only its
+// MaxFiles latest files are kept; a target is switched off after
+// MaxDurationMs. 0 lifts the limit.
+type CaptureConfig struct {
+	Dir      string
+	Dcus     []string
+	MeterIps []string
+
+	MaxFileBytes  int64
+	MaxFiles      int
+	MaxDurationMs int
+}
+
+// OtapConfig drives the otap session tracker. A session without a response
+// for SessionIdleTimeoutMs is abandoned; 0 keeps sessions open forever.
+type OtapConfig struct {
+	SessionIdleTimeoutMs int
+}
+
+// SinkConfig drives the meter sink attachment registry. Attachments are
+// written to Postgres every FlushIntervalMs, or sooner once MaxBuffered meters
+// are waiting; sink change events are published every batch.
+type SinkConfig struct {
+	FlushIntervalMs int
+	MaxBuffered     int
+}
+
+// TopologyConfig sizes the rolling topology graph. Statistics cover the last
+// WindowSize uplinks of a meter, and meters not heard for StaleAfterMs drop
+// out of the graph. A meter whose mean hop count or travel time reaches
+// WeakHopCount or WeakTravelTime is marked weak; 0 disables a threshold.
+type TopologyConfig struct {
+	WindowSize     int
+	StaleAfterMs   int
+	WeakHopCount   int
+	WeakTravelTime int
+}
+
+// DownlinkConfig bounds the downlink correlation store. Downlinks without a
+// sent status for AckTimeoutMs time out; 0 keeps them until their message id
+// is reused. At most MaxPending downlinks are kept, 0 for no limit.
+type DownlinkConfig struct {
+	AckTimeoutMs int
+	MaxPending   int
+}
+
+// ClockConfig drives the DCU clock drift tracker. Drift is estimated over the
+// last WindowSize frames of a DCU, once MinSamples are in, and raises an
+// event when it passes DriftThresholdSeconds either way.
+type ClockConfig struct {
+	DriftThresholdSeconds int
+	WindowSize            int
+	MinSamples            int
+}
+
+// PresenceConfig drives the meter and DCU last-seen registry. Sightings are
+// written to Postgres every FlushIntervalMs, or sooner once MaxBuffered nodes
+// are waiting. Every CheckIntervalMs nodes silent for longer than their
+// offline window are taken offline; 0 turns the check off. A meter's
+// provisioned group is read again once it is GroupRefreshIntervalMs old.
+type PresenceConfig struct {
+	FlushIntervalMs        int
+	MaxBuffered            int
+	CheckIntervalMs        int
+	MeterOfflineAfterMs    int
+	DcuOfflineAfterMs      int
+	GroupRefreshIntervalMs int
+}
+
+// PayloadParserConfig binds payload parsers to command_mapping rows. A
+// profile decodes the responses to the rows named in CmdNames, or to the
+// rows whose cmd_name is the upper-cased profile when it is not listed;
+// profiles of an assumed layout are bound only when listed. The bindings and
+// the event and field catalogues are re-read every RefreshIntervalMs.
+type PayloadParserConfig struct {
+	CmdNames          map[string][]string
+	RefreshIntervalMs int
+}
+
+// FieldCatalogueConfig picks the units the OBIS-tagged values of decoded
+// records are published in: values of a field catalogued in a unit of
+// OutputUnits are converted to the unit it maps to.
+type FieldCatalogueConfig struct {
+	OutputUnits map[string]string
+}
+
+// BlockLoadConfig drives the gap detection of block loads: a meter's day
+// whose share of recorded slots falls below CompletenessThreshold raises a
+// backfill request, at most once per BackfillMinIntervalMs. Days start at
+// midnight in DayLocation, UTC by default.
+type BlockLoadConfig struct {
+	CompletenessThreshold float64
+	BackfillMinIntervalMs int
+	DayLocation           *time.Location
+}
+
+// ValidationConfig holds the rules decoded readings are checked with before
+// they are published; readings failing any are quarantined. Meters get the
+// category of the first MeterCategories entry matching their IP, single
+// addresses first. A meter's rate and monotonic baseline is replaced after
+// BaselineResetFailures readings in a row failed against it, and dropped
+// when not moved for BaselineMaxAgeMs; 0 disables either.
+type ValidationConfig struct {
+	Rules                 []ValidationRuleConfig
+	MeterCategories       []MeterCategoryConfig
+	BaselineResetFailures int
+	BaselineMaxAgeMs      int
+}
+
+// ValidationRuleConfig is one rule; which fields apply depends on Kind:
+// range, rate, monotonic or timestamp. Min and Max are nil when unset.
+type ValidationRuleConfig struct {
+	ID          string
+	Kind        string
+	Field       string
+	Min         *float64
+	Max         *float64
+	MaxPerHour  float64
+	MaxFutureMs int
+	MaxAgeMs    int
+	Categories  []string
+	Groups      []int
+}
+
+type MeterCategoryConfig struct {
+	Meters   string
+	Category string
 }
 
 type RedisConfig struct {
@@ -42,12 +266,27 @@ type RedisConfig struct {
 type KafkaTopicsConfig struct {
 	FETCH_DATA_KAFKA_TOPIC_GROUP_ID string
 	FETCH_DATA_KAFKA_TOPIC_NAME     string
+	FETCH_DOWNLINK_KAFKA_TOPIC_GROUP_ID string
+	FETCH_DOWNLINK_KAFKA_TOPIC_NAME     string
 
 	PRODUCE_INVALID_TAP_KAFKA_TOPIC_NAME     string
 	PRODUCE_INVALID_WP_KAFKA_TOPIC_NAME     string
 	PRODUCE_DCU_DIAGNOSTIC_KAFKA_TOPIC_NAME     string
 	PRODUCE_DOWN_ACK_KAFKA_TOPIC_NAME     string
 	PRODUCE_SINK_CHANGE_KAFKA_TOPIC_NAME     string
+	PRODUCE_INCOMPLETE_TAP_KAFKA_TOPIC_NAME     string
+	PRODUCE_UNSUPPORTED_WP_KAFKA_TOPIC_NAME     string
+	PRODUCE_OTAP_SESSION_KAFKA_TOPIC_NAME     string
+	PRODUCE_TOPOLOGY_ROUTE_CHANGE_KAFKA_TOPIC_NAME     string
+	PRODUCE_CLOCK_DRIFT_KAFKA_TOPIC_NAME     string
+	PRODUCE_PRESENCE_KAFKA_TOPIC_NAME     string
+	PRODUCE_BLOCK_LOAD_KAFKA_TOPIC_NAME     string
+	PRODUCE_METER_READING_KAFKA_TOPIC_NAME     string
+	PRODUCE_METER_EVENT_KAFKA_TOPIC_NAME     string
+	PRODUCE_BLOCK_LOAD_INTERVAL_KAFKA_TOPIC_NAME     string
+	PRODUCE_BLOCK_LOAD_COMPLETENESS_KAFKA_TOPIC_NAME     string
+	PRODUCE_BACKFILL_REQUEST_KAFKA_TOPIC_NAME     string
+	PRODUCE_QUARANTINE_KAFKA_TOPIC_NAME     string
 
 }
 
@@ -86,13 +325,22 @@ func SetupConfig() (*Configuration, error) {
 	}
 
 	configuration := &Configuration{
-		KafkaConfig: KafkaConfig{
-			KafkaBootstrapServers: viper.GetString("KAFKA_BOOTSTRAP_SERVERS"),
-			KafkaSecurityProtocol: viper.GetString("KAFKA_SECURITY_PROTOCOL"),
-			KafkaSaslUsername:     viper.GetString("KAFKA_SASL_USERNAME"),
-			KafkaSaslPassword:     viper.GetString("KAFKA_SASL_PASSWORD"),
-			KafkaSaslMechanism:    viper.GetString("KAFKA_SASL_MECHANISM"),
-		},
+		KafkaConfig:         loadKafkaConfig(),
+		KafkaConsumerConfig: loadKafkaConsumerConfig(),
+		TapSegmentConfig:    loadTapSegmentConfig(),
+		ChecksumConfig:      loadChecksumConfig(),
+		DissectorConfig:     loadDissectorConfig(),
+		CaptureConfig:       loadCaptureConfig(),
+		OtapConfig:          loadOtapConfig(),
+		SinkConfig:          loadSinkConfig(),
+		TopologyConfig:      loadTopologyConfig(),
+		DownlinkConfig:      loadDownlinkConfig(),
+		ClockConfig:         loadClockConfig(),
+		PresenceConfig:      loadPresenceConfig(),
+		PayloadParserConfig: loadPayloadParserConfig(),
+		FieldCatalogueConfig: loadFieldCatalogueConfig(),
+		BlockLoadConfig:     loadBlockLoadConfig(),
+		ValidationConfig:    loadValidationConfig(),
 
 		RedisConfig: RedisConfig{
 			Host:              viper.GetString("REDIS_HOST"),
@@ -105,12 +353,27 @@ func SetupConfig() (*Configuration, error) {
 		KafkaTopicsConfig: KafkaTopicsConfig{
 			FETCH_DATA_KAFKA_TOPIC_GROUP_ID: viper.GetString("FETCH_DATA_KAFKA_TOPIC_GROUP_ID"),
 			FETCH_DATA_KAFKA_TOPIC_NAME:     viper.GetString("FETCH_DATA_KAFKA_TOPIC_NAME"),
+			FETCH_DOWNLINK_KAFKA_TOPIC_GROUP_ID: viper.GetString("FETCH_DOWNLINK_KAFKA_TOPIC_GROUP_ID"),
+			FETCH_DOWNLINK_KAFKA_TOPIC_NAME:     viper.GetString("FETCH_DOWNLINK_KAFKA_TOPIC_NAME"),
 
 			PRODUCE_INVALID_TAP_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_INVALID_TAP_KAFKA_TOPIC_NAME"),
 			PRODUCE_INVALID_WP_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_INVALID_WP_KAFKA_TOPIC_NAME"),
 			PRODUCE_DCU_DIAGNOSTIC_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_DCU_DIAGNOSTIC_KAFKA_TOPIC_NAME"),
 			PRODUCE_DOWN_ACK_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_DOWN_ACK_KAFKA_TOPIC_NAME"),
 			PRODUCE_SINK_CHANGE_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_SINK_CHANGE_KAFKA_TOPIC_NAME"),
+			PRODUCE_INCOMPLETE_TAP_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_INCOMPLETE_TAP_KAFKA_TOPIC_NAME"),
+			PRODUCE_UNSUPPORTED_WP_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_UNSUPPORTED_WP_KAFKA_TOPIC_NAME"),
+			PRODUCE_OTAP_SESSION_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_OTAP_SESSION_KAFKA_TOPIC_NAME"),
+			PRODUCE_TOPOLOGY_ROUTE_CHANGE_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_TOPOLOGY_ROUTE_CHANGE_KAFKA_TOPIC_NAME"),
+			PRODUCE_CLOCK_DRIFT_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_CLOCK_DRIFT_KAFKA_TOPIC_NAME"),
+			PRODUCE_PRESENCE_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_PRESENCE_KAFKA_TOPIC_NAME"),
+			PRODUCE_BLOCK_LOAD_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_BLOCK_LOAD_KAFKA_TOPIC_NAME"),
+			PRODUCE_METER_READING_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_METER_READING_KAFKA_TOPIC_NAME"),
+			PRODUCE_METER_EVENT_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_METER_EVENT_KAFKA_TOPIC_NAME"),
+			PRODUCE_BLOCK_LOAD_INTERVAL_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_BLOCK_LOAD_INTERVAL_KAFKA_TOPIC_NAME"),
+			PRODUCE_BLOCK_LOAD_COMPLETENESS_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_BLOCK_LOAD_COMPLETENESS_KAFKA_TOPIC_NAME"),
+			PRODUCE_BACKFILL_REQUEST_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_BACKFILL_REQUEST_KAFKA_TOPIC_NAME"),
+			PRODUCE_QUARANTINE_KAFKA_TOPIC_NAME:     viper.GetString("PRODUCE_QUARANTINE_KAFKA_TOPIC_NAME"),
 		},
 
 		BusinessLogicConfig: BusinessLogicConfig{},
@@ -139,3 +402,415 @@ func SetupConfig() (*Configuration, error) {
 
 	return configuration, nil
 }
+
+const DEFAULT_KAFKA_CLUSTER_NAME = "default"
+
+func loadKafkaConfig() KafkaConfig {
+	kafkaConfig := KafkaConfig{
+		KafkaBootstrapServers: viper.GetString("KAFKA_BOOTSTRAP_SERVERS"),
+		KafkaSecurityProtocol: viper.GetString("KAFKA_SECURITY_PROTOCOL"),
+		KafkaSaslUsername:     viper.GetString("KAFKA_SASL_USERNAME"),
+		KafkaSaslPassword:     viper.GetString("KAFKA_SASL_PASSWORD"),
+		KafkaSaslMechanism:    viper.GetString("KAFKA_SASL_MECHANISM"),
+	}
+
+	defaultCluster := KafkaClusterConfig{
+		Name:                  DEFAULT_KAFKA_CLUSTER_NAME,
+		KafkaBootstrapServers: kafkaConfig.KafkaBootstrapServers,
+		KafkaSecurityProtocol: kafkaConfig.KafkaSecurityProtocol,
+		KafkaSaslUsername:     kafkaConfig.KafkaSaslUsername,
+		KafkaSaslPassword:     kafkaConfig.KafkaSaslPassword,
+		KafkaSaslMechanism:    kafkaConfig.KafkaSaslMechanism,
+	}
+
+	kafkaConfig.InputCluster = loadKafkaClusterConfig("KAFKA_INPUT", "input", defaultCluster)
+	kafkaConfig.OutputClusters = []KafkaClusterConfig{defaultCluster}
+
+	// KAFKA_OUTPUT_CLUSTERS=analytics,headend reads KAFKA_OUTPUT_ANALYTICS_BOOTSTRAP_SERVERS,
+	// KAFKA_OUTPUT_ANALYTICS_TOPICS and so on for every listed cluster.
+	for _, name := range splitAndTrim(viper.GetString("KAFKA_OUTPUT_CLUSTERS")) {
+		if strings.EqualFold(name, DEFAULT_KAFKA_CLUSTER_NAME) {
+			continue
+		}
+		prefix := "KAFKA_OUTPUT_" + strings.ToUpper(name)
+		kafkaConfig.OutputClusters = append(kafkaConfig.OutputClusters, loadKafkaClusterConfig(prefix, name, defaultCluster))
+	}
+
+	return kafkaConfig
+}
+
+// loadKafkaClusterConfig reads the connection settings stored under prefix,
+// falling back to the given cluster for every setting that is not set.
+func loadKafkaClusterConfig(prefix string, name string, fallback KafkaClusterConfig) KafkaClusterConfig {
+	getOrDefault := func(key string, defaultValue string) string {
+		if value := viper.GetString(prefix + "_" + key); value != "" {
+			return value
+		}
+		return defaultValue
+	}
+
+	return KafkaClusterConfig{
+		Name:                  name,
+		KafkaBootstrapServers: getOrDefault("BOOTSTRAP_SERVERS", fallback.KafkaBootstrapServers),
+		KafkaSecurityProtocol: getOrDefault("SECURITY_PROTOCOL", fallback.KafkaSecurityProtocol),
+		KafkaSaslUsername:     getOrDefault("SASL_USERNAME", fallback.KafkaSaslUsername),
+		KafkaSaslPassword:     getOrDefault("SASL_PASSWORD", fallback.KafkaSaslPassword),
+		KafkaSaslMechanism:    getOrDefault("SASL_MECHANISM", fallback.KafkaSaslMechanism),
+		Topics:                splitAndTrim(viper.GetString(prefix + "_TOPICS")),
+	}
+}
+
+func splitAndTrim(value string) []string {
+	var parts []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+func loadKafkaConsumerConfig() KafkaConsumerConfig {
+	viper.SetDefault("KAFKA_POLL_BATCH_SIZE", 100)
+	viper.SetDefault("KAFKA_POLL_MAX_WAIT_MS", 2000)
+	viper.SetDefault("KAFKA_CONSUMER_CHANNEL_SIZE", 100)
+	viper.SetDefault("KAFKA_SESSION_TIMEOUT_MS", 30000)
+	viper.SetDefault("KAFKA_HEARTBEAT_INTERVAL_MS", 10000)
+	viper.SetDefault("KAFKA_ADAPTIVE_POLL", false)
+	viper.SetDefault("KAFKA_POLL_MIN_BATCH_SIZE", 10)
+	viper.SetDefault("KAFKA_POLL_MAX_BATCH_SIZE", 1000)
+	viper.SetDefault("KAFKA_POLL_MIN_WAIT_MS", 100)
+	viper.SetDefault("KAFKA_POLL_TARGET_LATENCY_MS", 1000)
+
+	return KafkaConsumerConfig{
+		PollBatchSize:       viper.GetInt("KAFKA_POLL_BATCH_SIZE"),
+		PollMaxWaitMs:       viper.GetInt("KAFKA_POLL_MAX_WAIT_MS"),
+		ChannelSize:         viper.GetInt("KAFKA_CONSUMER_CHANNEL_SIZE"),
+		SessionTimeoutMs:    viper.GetInt("KAFKA_SESSION_TIMEOUT_MS"),
+		HeartbeatIntervalMs: viper.GetInt("KAFKA_HEARTBEAT_INTERVAL_MS"),
+
+		AdaptivePoll:     viper.GetBool("KAFKA_ADAPTIVE_POLL"),
+		MinPollBatchSize: viper.GetInt("KAFKA_POLL_MIN_BATCH_SIZE"),
+		MaxPollBatchSize: viper.GetInt("KAFKA_POLL_MAX_BATCH_SIZE"),
+		MinPollWaitMs:    viper.GetInt("KAFKA_POLL_MIN_WAIT_MS"),
+		TargetLatencyMs:  viper.GetInt("KAFKA_POLL_TARGET_LATENCY_MS"),
+	}
+}
+
+func loadTapSegmentConfig() TapSegmentConfig {
+	viper.SetDefault("TAP_SEGMENT_TIMEOUT_MS", 60000)
+	viper.SetDefault("TAP_SEGMENT_MAX_PENDING_SETS", 10000)
+
+	var cmdIDs []int
+	for _, value := range splitAndTrim(viper.GetString("TAP_SEGMENTED_CMD_IDS")) {
+		cmdID, err := strconv.Atoi(value)
+		if err != nil {
+			logger.GetLogger().Errorf("ignoring invalid TAP_SEGMENTED_CMD_IDS entry %q: %v", value, err)
+			continue
+		}
+		cmdIDs = append(cmdIDs, cmdID)
+	}
+
+	return TapSegmentConfig{
+		SegmentedCmdIDs: cmdIDs,
+		TimeoutMs:       viper.GetInt("TAP_SEGMENT_TIMEOUT_MS"),
+		MaxPendingSets:  viper.GetInt("TAP_SEGMENT_MAX_PENDING_SETS"),
+	}
+}
+
+func loadChecksumConfig() ChecksumConfig {
+	viper.SetDefault("CHECKSUM_TAP_VARIANT", "CRC16_XMODEM_EVEN")
+	viper.SetDefault("CHECKSUM_WP_VARIANT", "CRC16_XMODEM_EVEN")
+
+	checksumConfig := ChecksumConfig{
+		TapVariant:    viper.GetString("CHECKSUM_TAP_VARIANT"),
+		WpVariant:     viper.GetString("CHECKSUM_WP_VARIANT"),
+		GroupVariants: make(map[int]string),
+	}
+
+	// CHECKSUM_VARIANTS=vendor_x reads CHECKSUM_VENDOR_X_WIDTH, CHECKSUM_VENDOR_X_POLY
+	// and so on; numbers may be given in hex as 0x1021
+	for _, name := range splitAndTrim(viper.GetString("CHECKSUM_VARIANTS")) {
+		prefix := "CHECKSUM_" + strings.ToUpper(name) + "_"
+		viper.SetDefault(prefix+"WIDTH", 16)
+		checksumConfig.Variants = append(checksumConfig.Variants, ChecksumVariantConfig{
+			Name:    name,
+			Width:   viper.GetInt(prefix + "WIDTH"),
+			Poly:    parseUint32(prefix + "POLY"),
+			Init:    parseUint32(prefix + "INIT"),
+			RefIn:   viper.GetBool(prefix + "REFIN"),
+			RefOut:  viper.GetBool(prefix + "REFOUT"),
+			XorOut:  parseUint32(prefix + "XOROUT"),
+			Padding: viper.GetString(prefix + "PADDING"),
+		})
+	}
+
+	// CHECKSUM_GROUP_VARIANTS=3:CRC16_MODBUS,7:VENDOR_X
+	for _, entry := range splitAndTrim(viper.GetString("CHECKSUM_GROUP_VARIANTS")) {
+		groupID, variant, found := strings.Cut(entry, ":")
+		id, err := strconv.Atoi(strings.TrimSpace(groupID))
+		if !found || err != nil {
+			logger.GetLogger().Errorf("ignoring invalid CHECKSUM_GROUP_VARIANTS entry %q", entry)
+			continue
+		}
+		checksumConfig.GroupVariants[id] = strings.TrimSpace(variant)
+	}
+
+	return checksumConfig
+}
+
+func loadDissectorConfig() DissectorConfig {
+	viper.SetDefault("DISSECTOR_MODE", "off")
+
+	return DissectorConfig{
+		Mode: strings.ToLower(strings.TrimSpace(viper.GetString("DISSECTOR_MODE"))),
+	}
+}
+
+func loadCaptureConfig() CaptureConfig {
+	viper.SetDefault("CAPTURE_DIR", "captures")
+	viper.SetDefault("CAPTURE_MAX_FILE_BYTES", 104857600)
+	viper.SetDefault("CAPTURE_MAX_FILES", 10)
+	viper.SetDefault("CAPTURE_MAX_DURATION_MS", 86400000)
+
+	return CaptureConfig{
+		Dir:      viper.GetString("CAPTURE_DIR"),
+		Dcus:     splitAndTrim(viper.GetString("CAPTURE_DCUS")),
+		MeterIps: splitAndTrim(viper.GetString("CAPTURE_METER_IPS")),
+
+		MaxFileBytes:  viper.GetInt64("CAPTURE_MAX_FILE_BYTES"),
+		MaxFiles:      viper.GetInt("CAPTURE_MAX_FILES"),
+		MaxDurationMs: viper.GetInt("CAPTURE_MAX_DURATION_MS"),
+	}
+}
+
+func loadOtapConfig() OtapConfig {
+	viper.SetDefault("OTAP_SESSION_IDLE_TIMEOUT_MS", 86400000)
+
+	return OtapConfig{
+		SessionIdleTimeoutMs: viper.GetInt("OTAP_SESSION_IDLE_TIMEOUT_MS"),
+	}
+}
+
+func loadSinkConfig() SinkConfig {
+	viper.SetDefault("SINK_FLUSH_INTERVAL_MS", 5000)
+	viper.SetDefault("SINK_MAX_BUFFERED", 5000)
+
+	return SinkConfig{
+		FlushIntervalMs: viper.GetInt("SINK_FLUSH_INTERVAL_MS"),
+		MaxBuffered:     viper.GetInt("SINK_MAX_BUFFERED"),
+	}
+}
+
+func loadTopologyConfig() TopologyConfig {
+	viper.SetDefault("TOPOLOGY_WINDOW_SIZE", 50)
+	viper.SetDefault("TOPOLOGY_STALE_AFTER_MS", 604800000)
+
+	return TopologyConfig{
+		WindowSize:     viper.GetInt("TOPOLOGY_WINDOW_SIZE"),
+		StaleAfterMs:   viper.GetInt("TOPOLOGY_STALE_AFTER_MS"),
+		WeakHopCount:   viper.GetInt("TOPOLOGY_WEAK_HOP_COUNT"),
+		WeakTravelTime: viper.GetInt("TOPOLOGY_WEAK_TRAVEL_TIME"),
+	}
+}
+
+func loadDownlinkConfig() DownlinkConfig {
+	viper.SetDefault("DOWNLINK_ACK_TIMEOUT_MS", 300000)
+	viper.SetDefault("DOWNLINK_MAX_PENDING", 100000)
+
+	return DownlinkConfig{
+		AckTimeoutMs: viper.GetInt("DOWNLINK_ACK_TIMEOUT_MS"),
+		MaxPending:   viper.GetInt("DOWNLINK_MAX_PENDING"),
+	}
+}
+
+func loadClockConfig() ClockConfig {
+	viper.SetDefault("CLOCK_DRIFT_THRESHOLD_SECONDS", 60)
+	viper.SetDefault("CLOCK_DRIFT_WINDOW_SIZE", 20)
+	viper.SetDefault("CLOCK_DRIFT_MIN_SAMPLES", 5)
+
+	return ClockConfig{
+		DriftThresholdSeconds: viper.GetInt("CLOCK_DRIFT_THRESHOLD_SECONDS"),
+		WindowSize:            viper.GetInt("CLOCK_DRIFT_WINDOW_SIZE"),
+		MinSamples:            viper.GetInt("CLOCK_DRIFT_MIN_SAMPLES"),
+	}
+}
+
+func loadPresenceConfig() PresenceConfig {
+	viper.SetDefault("PRESENCE_FLUSH_INTERVAL_MS", 5000)
+	viper.SetDefault("PRESENCE_MAX_BUFFERED", 5000)
+	viper.SetDefault("PRESENCE_CHECK_INTERVAL_MS", 60000)
+	viper.SetDefault("PRESENCE_METER_OFFLINE_AFTER_MS", 86400000)
+	viper.SetDefault("PRESENCE_DCU_OFFLINE_AFTER_MS", 900000)
+	viper.SetDefault("PRESENCE_GROUP_REFRESH_INTERVAL_MS", 300000)
+
+	return PresenceConfig{
+		FlushIntervalMs:        viper.GetInt("PRESENCE_FLUSH_INTERVAL_MS"),
+		MaxBuffered:            viper.GetInt("PRESENCE_MAX_BUFFERED"),
+		CheckIntervalMs:        viper.GetInt("PRESENCE_CHECK_INTERVAL_MS"),
+		MeterOfflineAfterMs:    viper.GetInt("PRESENCE_METER_OFFLINE_AFTER_MS"),
+		DcuOfflineAfterMs:      viper.GetInt("PRESENCE_DCU_OFFLINE_AFTER_MS"),
+		GroupRefreshIntervalMs: viper.GetInt("PRESENCE_GROUP_REFRESH_INTERVAL_MS"),
+	}
+}
+
+func loadPayloadParserConfig() PayloadParserConfig {
+	viper.SetDefault("PAYLOAD_PARSER_REFRESH_INTERVAL_MS", 300000)
+
+	payloadParserConfig := PayloadParserConfig{
+		CmdNames:          make(map[string][]string),
+		RefreshIntervalMs: viper.GetInt("PAYLOAD_PARSER_REFRESH_INTERVAL_MS"),
+	}
+
+	// PAYLOAD_PARSER_CMD_NAMES=block_load:BLOCK_LOAD|LOAD_SURVEY,billing:BILLING_HISTORY
+	for _, entry := range splitAndTrim(viper.GetString("PAYLOAD_PARSER_CMD_NAMES")) {
+		profile, cmdNames, found := strings.Cut(entry, ":")
+		if !found {
+			logger.GetLogger().Errorf("ignoring invalid PAYLOAD_PARSER_CMD_NAMES entry %q", entry)
+			continue
+		}
+		profile = strings.TrimSpace(profile)
+		for _, cmdName := range strings.Split(cmdNames, "|") {
+			if cmdName = strings.TrimSpace(cmdName); cmdName != "" {
+				payloadParserConfig.CmdNames[profile] = append(payloadParserConfig.CmdNames[profile], cmdName)
+			}
+		}
+	}
+
+	return payloadParserConfig
+}
+
+func loadFieldCatalogueConfig() FieldCatalogueConfig {
+	fieldCatalogueConfig := FieldCatalogueConfig{OutputUnits: make(map[string]string)}
+
+	// FIELD_OUTPUT_UNITS=Wh:kWh,VAh:kVAh
+	for _, entry := range splitAndTrim(viper.GetString("FIELD_OUTPUT_UNITS")) {
+		unit, outputUnit, found := strings.Cut(entry, ":")
+		unit, outputUnit = strings.TrimSpace(unit), strings.TrimSpace(outputUnit)
+		if !found || unit == "" || outputUnit == "" {
+			logger.GetLogger().Errorf("ignoring invalid FIELD_OUTPUT_UNITS entry %q", entry)
+			continue
+		}
+		fieldCatalogueConfig.OutputUnits[unit] = outputUnit
+	}
+
+	return fieldCatalogueConfig
+}
+
+func loadBlockLoadConfig() BlockLoadConfig {
+	viper.SetDefault("BLOCK_LOAD_COMPLETENESS_THRESHOLD", 0.9)
+	viper.SetDefault("BLOCK_LOAD_BACKFILL_MIN_INTERVAL_MS", 21600000)
+	viper.SetDefault("BLOCK_LOAD_DAY_TIME_ZONE", "UTC")
+
+	blockLoadConfig := BlockLoadConfig{
+		CompletenessThreshold: viper.GetFloat64("BLOCK_LOAD_COMPLETENESS_THRESHOLD"),
+		BackfillMinIntervalMs: viper.GetInt("BLOCK_LOAD_BACKFILL_MIN_INTERVAL_MS"),
+		DayLocation:           time.UTC,
+	}
+	timeZone := viper.GetString("BLOCK_LOAD_DAY_TIME_ZONE")
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		logger.GetLogger().Errorf("ignoring invalid BLOCK_LOAD_DAY_TIME_ZONE %q: %v", timeZone, err)
+		return blockLoadConfig
+	}
+	blockLoadConfig.DayLocation = location
+	return blockLoadConfig
+}
+
+// the rules used when VALIDATION_RULES is not set, the limits block-load
+// decoding used to log
+var defaultValidationRules = map[string]map[string]interface{}{
+	"import_wh_range":           {"KIND": "range", "FIELD": "import_Wh", "MAX": 14400},
+	"import_vah_range":          {"KIND": "range", "FIELD": "import_VAh", "MAX": 14400},
+	"voltage_range":             {"KIND": "range", "FIELD": "avg_voltage", "MAX": 400},
+	"current_range":             {"KIND": "range", "FIELD": "avg_current", "MAX": 70},
+	"cumm_import_wh_monotonic":  {"KIND": "monotonic", "FIELD": "cumm_import_Wh"},
+	"daily_import_wh_monotonic": {"KIND": "monotonic", "FIELD": "daily_cumm_active_energy_imp"},
+	"reading_time":              {"KIND": "timestamp", "MAX_FUTURE_MS": 900000, "MAX_AGE_MS": 7776000000},
+}
+
+func loadValidationConfig() ValidationConfig {
+	ruleIDs := make([]string, 0, len(defaultValidationRules))
+	for ruleID, defaults := range defaultValidationRules {
+		ruleIDs = append(ruleIDs, ruleID)
+		for key, value := range defaults {
+			viper.SetDefault("VALIDATION_RULE_"+strings.ToUpper(ruleID)+"_"+key, value)
+		}
+	}
+	sort.Strings(ruleIDs)
+	viper.SetDefault("VALIDATION_RULES", strings.Join(ruleIDs, ","))
+	viper.SetDefault("VALIDATION_BASELINE_RESET_FAILURES", 5)
+	viper.SetDefault("VALIDATION_BASELINE_MAX_AGE_MS", 604800000)
+
+	validationConfig := ValidationConfig{
+		BaselineResetFailures: viper.GetInt("VALIDATION_BASELINE_RESET_FAILURES"),
+		BaselineMaxAgeMs:      viper.GetInt("VALIDATION_BASELINE_MAX_AGE_MS"),
+	}
+
+	// VALIDATION_RULES=voltage_range reads VALIDATION_RULE_VOLTAGE_RANGE_KIND,
+	// VALIDATION_RULE_VOLTAGE_RANGE_FIELD and so on
+	for _, ruleID := range splitAndTrim(viper.GetString("VALIDATION_RULES")) {
+		prefix := "VALIDATION_RULE_" + strings.ToUpper(ruleID) + "_"
+		rule := ValidationRuleConfig{
+			ID:          ruleID,
+			Kind:        strings.ToLower(viper.GetString(prefix + "KIND")),
+			Field:       viper.GetString(prefix + "FIELD"),
+			Min:         parseOptionalFloat(prefix + "MIN"),
+			Max:         parseOptionalFloat(prefix + "MAX"),
+			MaxPerHour:  viper.GetFloat64(prefix + "MAX_PER_HOUR"),
+			MaxFutureMs: viper.GetInt(prefix + "MAX_FUTURE_MS"),
+			MaxAgeMs:    viper.GetInt(prefix + "MAX_AGE_MS"),
+			Categories:  splitAndTrim(viper.GetString(prefix + "CATEGORIES")),
+		}
+		for _, value := range splitAndTrim(viper.GetString(prefix + "GROUPS")) {
+			groupID, err := strconv.Atoi(value)
+			if err != nil {
+				logger.GetLogger().Errorf("ignoring invalid %sGROUPS entry %q: %v", prefix, value, err)
+				continue
+			}
+			rule.Groups = append(rule.Groups, groupID)
+		}
+		validationConfig.Rules = append(validationConfig.Rules, rule)
+	}
+
+	// VALIDATION_METER_CATEGORIES=10.1.0.0/16:ct,10.2.3.4:whole_current
+	for _, entry := range splitAndTrim(viper.GetString("VALIDATION_METER_CATEGORIES")) {
+		meters, category, found := strings.Cut(entry, ":")
+		if !found {
+			logger.GetLogger().Errorf("ignoring invalid VALIDATION_METER_CATEGORIES entry %q", entry)
+			continue
+		}
+		validationConfig.MeterCategories = append(validationConfig.MeterCategories, MeterCategoryConfig{
+			Meters:   strings.TrimSpace(meters),
+			Category: strings.TrimSpace(category),
+		})
+	}
+
+	return validationConfig
+}
+
+func parseOptionalFloat(key string) *float64 {
+	value := strings.TrimSpace(viper.GetString(key))
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		logger.GetLogger().Errorf("ignoring invalid %s %q: %v", key, value, err)
+		return nil
+	}
+	return &parsed
+}
+
+func parseUint32(key string) uint32 {
+	value := strings.TrimSpace(viper.GetString(key))
+	if value == "" {
+		return 0
+	}
+	parsed, err := strconv.ParseUint(value, 0, 32)
+	if err != nil {
+		logger.GetLogger().Errorf("ignoring invalid %s %q: %v", key, value, err)
+		return 0
+	}
+	return uint32(parsed)
+}
@@ -2,6 +2,9 @@ package database
 
 import(
 	decoder "parsing-service/apps/decoder/models"
+	otap "parsing-service/apps/otap/models"
+	sinks "parsing-service/apps/sinks/models"
+	presence "parsing-service/apps/presence/models"
 )
 
 var migrationModels = []interface{}{
@@ -9,6 +12,12 @@ var migrationModels = []interface{}{
 	// &decoder.SwVersion{},
 	// &decoder.CommandMappingSwVersion{},
 	&decoder.DeserializeLogics{},
+	&decoder.EventCatalogue{},
+	&decoder.FieldCatalogue{},
 	// &decoder.DeserializeLogicSwVersion{},
+	&otap.OtapSession{},
+	&sinks.MeterSinkAttachment{},
+	&presence.MeterPresence{},
+	&presence.DcuPresence{},
 
 }
\ No newline at end of file
@@ -0,0 +1,129 @@
+package dissect
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Node is one field of a dissected packet. Offset and Length give its byte
+// range within the dissected buffer, Hex the raw bytes of that range. A node
+// is invalid when its own bytes are wrong or missing, or when any of its
+// children is invalid; Error says why for the node itself.
+type Node struct {
+	Name     string      `json:"name"`
+	Offset   int         `json:"offset"`
+	Length   int         `json:"length"`
+	Hex      string      `json:"hex"`
+	Value    interface{} `json:"value,omitempty"`
+	Valid    bool        `json:"valid"`
+	Error    string      `json:"error,omitempty"`
+	Children []*Node     `json:"children,omitempty"`
+}
+
+// NewNode returns a node for length bytes of data at offset. A range running
+// past the end of data is cut short and the node marked invalid.
+func NewNode(name string, data []byte, offset int, length int) *Node {
+	node := &Node{Name: name, Offset: offset, Length: length, Valid: true}
+	if offset < 0 || length < 0 || offset > len(data) {
+		node.Length = 0
+		return node.Invalidate(fmt.Sprintf("needs bytes %d..%d, buffer has %d", offset, offset+length, len(data)))
+	}
+	if offset+length > len(data) {
+		node.Hex = hex.EncodeToString(data[offset:])
+		return node.Invalidate(fmt.Sprintf("needs bytes %d..%d, buffer has %d", offset, offset+length, len(data)))
+	}
+	node.Hex = hex.EncodeToString(data[offset : offset+length])
+	return node
+}
+
+// Complete reports whether all of the node's bytes were present.
+func (n *Node) Complete() bool {
+	return len(n.Hex) == 2*n.Length
+}
+
+func (n *Node) SetValue(value interface{}) *Node {
+	n.Value = value
+	return n
+}
+
+// Invalidate marks the node invalid. The first reason given is kept.
+func (n *Node) Invalidate(reason string) *Node {
+	n.Valid = false
+	if n.Error == "" {
+		n.Error = reason
+	}
+	return n
+}
+
+// Add appends children and returns n. An invalid child makes n invalid.
+func (n *Node) Add(children ...*Node) *Node {
+	for _, child := range children {
+		if child == nil {
+			continue
+		}
+		n.Children = append(n.Children, child)
+		if !child.Valid {
+			n.Valid = false
+		}
+	}
+	return n
+}
+
+// Find returns the first node named name in the tree below n.
+func (n *Node) Find(name string) *Node {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+		if found := child.Find(name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Rebase moves the tree by base bytes, for a tree dissected out of a slice
+// that starts at base within a larger buffer.
+func (n *Node) Rebase(base int) *Node {
+	n.Offset += base
+	for _, child := range n.Children {
+		child.Rebase(base)
+	}
+	return n
+}
+
+func (n *Node) JSON() ([]byte, error) {
+	return json.MarshalIndent(n, "", "  ")
+}
+
+// Text renders the tree one node per line, children indented below their
+// parent:
+//
+//	crc [40..42) 1a2b = 6699  INVALID: crc mismatch
+func (n *Node) Text() string {
+	var b strings.Builder
+	n.writeText(&b, 0)
+	return b.String()
+}
+
+func (n *Node) writeText(b *strings.Builder, depth int) {
+	b.WriteString(strings.Repeat("  ", depth))
+	fmt.Fprintf(b, "%s [%d..%d)", n.Name, n.Offset, n.Offset+n.Length)
+	if len(n.Children) == 0 && n.Hex != "" {
+		b.WriteString(" " + n.Hex)
+	}
+	if n.Value != nil {
+		fmt.Fprintf(b, " = %v", n.Value)
+	}
+	if n.Error != "" {
+		b.WriteString("  INVALID: " + n.Error)
+	} else if !n.Valid && len(n.Children) == 0 {
+		b.WriteString("  INVALID")
+	}
+	b.WriteString("\n")
+	for _, child := range n.Children {
+		child.writeText(b, depth+1)
+	}
+}
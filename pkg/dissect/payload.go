@@ -0,0 +1,72 @@
+package dissect
+
+import "fmt"
+
+// value types of a PayloadField
+const (
+	TYPE_UINT  = "uint"
+	TYPE_INT   = "int"
+	TYPE_HEX   = "hex"
+	TYPE_ASCII = "ascii"
+)
+
+// byte orders of a PayloadField, as in the tap Deserialize helpers
+const (
+	ORDER_NORMAL  = "normal"
+	ORDER_REVERSE = "reverse"
+)
+
+// PayloadField describes one value inside a command payload: Length bytes
+// at Index, read as Type in Order. Integers wider than 8 bytes are shown as
+// hex.
+type PayloadField struct {
+	Name   string
+	Index  int
+	Length int
+	Type   string
+	Order  string
+}
+
+// PayloadNodes dissects the fields of payload, which starts at base within
+// the dissected buffer.
+func PayloadNodes(payload []byte, base int, fields []PayloadField) []*Node {
+	nodes := make([]*Node, 0, len(fields))
+	for _, field := range fields {
+		node := NewNode(field.Name, payload, field.Index, field.Length)
+		node.Offset += base
+		if node.Complete() {
+			node.SetValue(field.decode(payload[field.Index : field.Index+field.Length]))
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func (f PayloadField) decode(raw []byte) interface{} {
+	buf := append([]byte(nil), raw...)
+	if f.Order == ORDER_REVERSE {
+		for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+			buf[i], buf[j] = buf[j], buf[i]
+		}
+	}
+
+	switch f.Type {
+	case TYPE_HEX:
+		return fmt.Sprintf("%X", buf)
+	case TYPE_ASCII:
+		return string(buf)
+	}
+	if len(buf) == 0 || len(buf) > 8 {
+		return fmt.Sprintf("%X", buf)
+	}
+
+	var value uint64
+	for _, b := range buf {
+		value = value<<8 | uint64(b)
+	}
+	if f.Type == TYPE_INT {
+		shift := 64 - 8*len(buf)
+		return int64(value<<shift) >> shift
+	}
+	return value
+}
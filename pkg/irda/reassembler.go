@@ -0,0 +1,80 @@
+package irda
+
+import (
+	"parsing-service/pkg/tap"
+	"sync"
+	"time"
+)
+
+// a partial record older than this is given up on, its remainder is not
+// coming anymore
+const CARRY_OVER_TIMEOUT = 60 * time.Second
+
+type carryOver struct {
+	rest    []byte
+	port    uint8
+	updated time.Time
+}
+
+// Reassembler keeps one carry-over buffer per DCU so that a frame split across
+// two Kafka messages of the same DCU is joined before it is scanned.
+type Reassembler struct {
+	mu      sync.Mutex
+	streams map[string]*carryOver
+	pick    tap.ChecksumPicker
+}
+
+// NewReassembler scans with the checksum variants pick returns for each TAP
+// frame.
+func NewReassembler(pick tap.ChecksumPicker) *Reassembler {
+	return &Reassembler{streams: make(map[string]*carryOver), pick: pick}
+}
+
+// Feed appends data to the carry-over of dcu and scans the result. Carry-over
+// that has gone stale is not joined with data: it is scanned on its own as a
+// final chunk, so complete frames behind its broken start are kept and the
+// rest of it is reported invalid, before data is scanned.
+func (r *Reassembler) Feed(dcu string, data []byte, now time.Time) ScanResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var stale *ScanResult
+	var port uint8
+	buf := data
+	if previous, ok := r.streams[dcu]; ok {
+		port = previous.port
+		if now.Sub(previous.updated) > CARRY_OVER_TIMEOUT {
+			rescanned := ScanFinal(previous.rest, port, r.pick)
+			stale = &rescanned
+			port = rescanned.Port
+		} else {
+			buf = make([]byte, 0, len(previous.rest)+len(data))
+			buf = append(buf, previous.rest...)
+			buf = append(buf, data...)
+		}
+	}
+
+	result := Scan(buf, port, r.pick)
+	if stale != nil {
+		result.Frames = append(stale.Frames, result.Frames...)
+		result.Invalid = append(stale.Invalid, result.Invalid...)
+	}
+
+	if len(result.Rest) > 0 {
+		r.streams[dcu] = &carryOver{rest: result.Rest, port: result.Port, updated: now}
+	} else if result.Port != 0 {
+		// keep the port of the last header for the next message of the dcu
+		r.streams[dcu] = &carryOver{port: result.Port, updated: now}
+	} else {
+		delete(r.streams, dcu)
+	}
+
+	return result
+}
+
+// FeedUnkeyed scans data of a stream whose dcu is not known. Nothing is
+// carried over to or from other messages, which may be of other dcus: a
+// frame cut at the end of data is reported invalid.
+func (r *Reassembler) FeedUnkeyed(data []byte) ScanResult {
+	return ScanFinal(data, 0, r.pick)
+}
@@ -0,0 +1,125 @@
+package irda
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"parsing-service/pkg/checksum"
+	"parsing-service/pkg/tap"
+)
+
+func tapFrame(t *testing.T, data []byte) []byte {
+	t.Helper()
+	raw, err := tap.Encode(&tap.TAPPacket{
+		SrcAddr:  tap.NewAddress1("10.0.1.2"),
+		DestAddr: tap.NewAddress1("0.2.44.0"),
+		SrcPort:  1,
+		DestPort: 2,
+		Data:     data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestFeedJoinsFrameAcrossMessagesOfOneDcu(t *testing.T) {
+	r := NewReassembler(tap.FixedChecksum(checksum.FRAME_DEFAULT))
+	frame := tapFrame(t, []byte("RECT in the data"))
+	now := time.Now()
+
+	first := r.Feed("dcu-1", frame[:7], now)
+	if len(first.Frames) != 0 || len(first.Invalid) != 0 {
+		t.Fatalf("first half = %+v, want it carried over", first)
+	}
+	second := r.Feed("dcu-1", frame[7:], now.Add(time.Second))
+	if len(second.Frames) != 1 || !bytes.Equal(second.Frames[0].Raw, frame) {
+		t.Fatalf("second half = %+v, want the joined frame", second)
+	}
+}
+
+func TestFeedKeepsDcusApart(t *testing.T) {
+	r := NewReassembler(tap.FixedChecksum(checksum.FRAME_DEFAULT))
+	frame := tapFrame(t, []byte{1, 2, 3})
+	now := time.Now()
+
+	r.Feed("dcu-1", frame[:7], now)
+	other := r.Feed("dcu-2", frame[7:], now)
+	if len(other.Frames) != 0 {
+		t.Fatalf("dcu-2 completed a frame started by dcu-1: %+v", other)
+	}
+}
+
+func TestFeedUnkeyedCarriesNothingOver(t *testing.T) {
+	r := NewReassembler(tap.FixedChecksum(checksum.FRAME_DEFAULT))
+	frame := tapFrame(t, []byte{1, 2, 3})
+
+	first := r.FeedUnkeyed(frame[:7])
+	if len(first.Rest) != 0 || len(first.Invalid) != 1 {
+		t.Fatalf("cut frame = %+v, want it invalid", first)
+	}
+	second := r.FeedUnkeyed(frame[7:])
+	if len(second.Frames) != 0 {
+		t.Fatalf("unkeyed messages were joined: %+v", second)
+	}
+	whole := r.FeedUnkeyed(frame)
+	if len(whole.Frames) != 1 {
+		t.Fatalf("whole frame = %+v, want it found", whole)
+	}
+}
+
+func TestFeedRescansStaleCarryOver(t *testing.T) {
+	r := NewReassembler(tap.FixedChecksum(checksum.FRAME_DEFAULT))
+	good := tapFrame(t, []byte{4, 5, 6})
+	// a start byte whose length byte claims more than ever arrives, with a
+	// complete frame behind it
+	broken := []byte{tap.TAP_START_BYTE, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xF0}
+	now := time.Now()
+
+	held := r.Feed("dcu-1", append(append([]byte(nil), broken...), good...), now)
+	if len(held.Frames) != 0 {
+		t.Fatalf("frame found before the carry-over went stale: %+v", held)
+	}
+
+	next := tapFrame(t, []byte{7})
+	result := r.Feed("dcu-1", next, now.Add(CARRY_OVER_TIMEOUT+time.Second))
+	if len(result.Frames) != 2 || !bytes.Equal(result.Frames[0].Raw, good) || !bytes.Equal(result.Frames[1].Raw, next) {
+		t.Fatalf("frames = %+v, want the frame behind the stale start and the new one", result.Frames)
+	}
+	if len(result.Invalid) != 1 || !bytes.Equal(result.Invalid[0], broken) {
+		t.Fatalf("invalid = %X, want the broken start only", result.Invalid)
+	}
+}
+
+func TestScanPicksChecksumPerFrame(t *testing.T) {
+	crc32Frame, err := tap.EncodeWith(&tap.TAPPacket{
+		SrcAddr:  tap.NewAddress1("10.0.1.3"),
+		DestAddr: tap.NewAddress1("0.2.45.0"),
+		SrcPort:  1,
+		DestPort: 2,
+		Data:     []byte{1, 2, 3},
+	}, checksum.CRC32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xmodem := tapFrame(t, []byte{4, 5})
+	// the 0.2.45.0 command closes its packets with a crc32, every other one
+	// with the default
+	pick := func(header *tap.TAPPacket) []*checksum.Algorithm {
+		if header.DestAddr.String() == "0.2.45.0" {
+			return []*checksum.Algorithm{checksum.CRC32}
+		}
+		return []*checksum.Algorithm{checksum.FRAME_DEFAULT}
+	}
+
+	stream := append(append([]byte(nil), crc32Frame...), xmodem...)
+	if first := NewReassembler(pick).Feed("dcu-1", stream[:len(crc32Frame)-1], time.Now()); len(first.Frames) != 0 || len(first.Invalid) != 0 {
+		t.Fatalf("first chunk = %+v, want the crc32 frame carried over", first)
+	}
+	result := Scan(stream, 0, pick)
+	if len(result.Invalid) != 0 || len(result.Frames) != 2 ||
+		!bytes.Equal(result.Frames[0].Raw, crc32Frame) || !bytes.Equal(result.Frames[1].Raw, xmodem) {
+		t.Fatalf("Scan = %+v, want both frames", result)
+	}
+}
@@ -0,0 +1,171 @@
+package irda
+
+import (
+	"bytes"
+	"parsing-service/pkg/checksum"
+	"parsing-service/pkg/tap"
+)
+
+const (
+	TAP_START_BYTE = tap.TAP_START_BYTE
+
+	// every IRDA record starts with this marker, followed either directly by a
+	// TAP frame or by the rest of an IRDA header
+	RECORD_MARKER = "RECT"
+
+	// IRDA header, offsets from the first byte of the marker. The header
+	// length, the type byte at 16 and the TAP start byte behind it are those
+	// the original RECT splitting checked. The DCU port at 17 is not: no IRDA
+	// header document is available to this service, the byte is assumed to
+	// be the port and is only passed on with the frames, nothing is decided
+	// on it.
+	HEADER_LEN          = 18
+	HEADER_TYPE_OFFSET  = 16
+	HEADER_TYPE         = byte(0x10)
+	HEADER_PORT_OFFSET  = 17
+	MARKER_LEN          = len(RECORD_MARKER)
+	TAP_LEN_BYTE_OFFSET = tap.TAP_HEADER_LEN - 1 // from the TAP start byte
+)
+
+// Frame is one complete TAP frame found in an IRDA stream. Raw runs from the
+// 0xAA start byte through the crc and is a copy of the input. DcuPort is the
+// byte at HEADER_PORT_OFFSET of the last IRDA header, assumed to be the port.
+type Frame struct {
+	DcuPort uint8
+	Raw     []byte
+}
+
+// ScanResult is the outcome of scanning a chunk of an IRDA stream. Rest holds
+// the trailing bytes of a record that is not complete yet and Port the DCU
+// port in effect at its start; both are fed back into the next Scan of the
+// same stream.
+type ScanResult struct {
+	Frames  []Frame
+	Invalid [][]byte
+	Rest    []byte
+	Port    uint8
+}
+
+// Scan walks data and returns every TAP frame whose length and crc check out.
+// Frames are consumed by their length byte, so "RECT" or 0xAA inside the data
+// or crc of a frame never splits it. Bytes that belong to no valid frame are
+// returned as Invalid, one slice per contiguous run. port is the DCU port
+// carried over from the previous scan of the stream; an IRDA header in data
+// replaces it for the frames that follow. pick returns the checksum variants
+// a TAP frame may be closed with, which also set the size of its crc.
+func Scan(data []byte, port uint8, pick tap.ChecksumPicker) ScanResult {
+	return scan(data, port, pick, false)
+}
+
+// ScanFinal is Scan for a chunk no more data will follow: a record that is
+// not complete is not kept as Rest but taken as invalid from its first byte,
+// and the scan goes on behind it, so complete frames after a broken start are
+// still found. Rest is always empty.
+func ScanFinal(data []byte, port uint8, pick tap.ChecksumPicker) ScanResult {
+	return scan(data, port, pick, true)
+}
+
+func scan(data []byte, port uint8, pick tap.ChecksumPicker, final bool) ScanResult {
+	result := ScanResult{Port: port}
+	junkStart := -1
+	flushJunk := func(end int) {
+		if junkStart >= 0 {
+			result.Invalid = append(result.Invalid, copyBytes(data[junkStart:end]))
+			junkStart = -1
+		}
+	}
+
+	for i := 0; i < len(data); {
+		remaining := len(data) - i
+
+		if bytes.HasPrefix(data[i:], []byte(RECORD_MARKER)) {
+			if remaining <= MARKER_LEN && !final {
+				flushJunk(i)
+				result.Rest = copyBytes(data[i:])
+				return result
+			}
+			if remaining > MARKER_LEN && data[i+MARKER_LEN] == TAP_START_BYTE {
+				// bare marker, no header
+				flushJunk(i)
+				i += MARKER_LEN
+				continue
+			}
+			if remaining < HEADER_LEN+1 && !final {
+				flushJunk(i)
+				result.Rest = copyBytes(data[i:])
+				return result
+			}
+			if remaining >= HEADER_LEN+1 && data[i+HEADER_TYPE_OFFSET] == HEADER_TYPE && data[i+HEADER_LEN] == TAP_START_BYTE {
+				flushJunk(i)
+				result.Port = data[i+HEADER_PORT_OFFSET]
+				i += HEADER_LEN
+				continue
+			}
+		}
+
+		if data[i] == TAP_START_BYTE && (remaining > TAP_LEN_BYTE_OFFSET || !final) {
+			if remaining <= TAP_LEN_BYTE_OFFSET {
+				flushJunk(i)
+				result.Rest = copyBytes(data[i:])
+				return result
+			}
+			frameLen, more := matchFrame(data[i:], pick)
+			if frameLen > 0 {
+				flushJunk(i)
+				result.Frames = append(result.Frames, Frame{DcuPort: result.Port, Raw: copyBytes(data[i : i+frameLen])})
+				i += frameLen
+				continue
+			}
+			if more && !final {
+				flushJunk(i)
+				result.Rest = copyBytes(data[i:])
+				return result
+			}
+		}
+
+		// not the start of a valid record, resume at the next byte
+		if junkStart < 0 {
+			junkStart = i
+		}
+		i++
+	}
+
+	flushJunk(len(data))
+	return result
+}
+
+// matchFrame returns the length of the valid TAP frame data starts with, 0
+// when there is none. more is set when the frame of one of the picked
+// variants runs past the end of data.
+func matchFrame(data []byte, pick tap.ChecksumPicker) (frameLen int, more bool) {
+	header, err := tap.DecodeUnchecked(data[1:tap.TAP_HEADER_LEN])
+	if err != nil {
+		return 0, false
+	}
+	for _, alg := range pick(header) {
+		n := int(header.DataLen) + tap.TAP_HEADER_LEN + alg.Size()
+		if len(data) < n {
+			more = true
+			continue
+		}
+		if ValidCrc(data[:n], alg) {
+			return n, false
+		}
+	}
+	return 0, more
+}
+
+// ValidCrc checks the crc closing a TAP frame that starts with its start
+// byte.
+func ValidCrc(frame []byte, alg *checksum.Algorithm) bool {
+	if len(frame) < tap.TAP_HEADER_LEN+alg.Size() {
+		return false
+	}
+	crcOffset := len(frame) - alg.Size()
+	ok, _, _ := alg.Verify(frame[1:crcOffset], frame[crcOffset:])
+	return ok
+}
+
+func copyBytes(data []byte) []byte {
+	return append([]byte(nil), data...)
+}
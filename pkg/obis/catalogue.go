@@ -0,0 +1,97 @@
+package obis
+
+import (
+	"math"
+	"sort"
+)
+
+// Field describes a decoded field: the OBIS code of the register it holds,
+// the unit of the register, the scaler and the quantity measured. The value
+// in Unit is the decoded value times 10^Scaler. A field with an empty
+// RecordType describes the name in every record type.
+type Field struct {
+	RecordType   string
+	Name         string
+	ObisCode     string
+	Unit         string
+	Scaler       int
+	QuantityType string
+}
+
+// Value is a decoded field tagged with its OBIS code, in the unit it is
+// published in.
+type Value struct {
+	Field        string  `json:"field"`
+	ObisCode     string  `json:"obis_code"`
+	Value        float64 `json:"value"`
+	Unit         string  `json:"unit,omitempty"`
+	Scaler       int     `json:"scaler"`
+	QuantityType string  `json:"quantity_type"`
+}
+
+type fieldKey struct {
+	recordType string
+	name       string
+}
+
+// Catalogue looks decoded fields up by record type and name, and publishes
+// them in the configured output units.
+type Catalogue struct {
+	fields map[fieldKey]Field
+	// unit of the catalogue to unit values are published in
+	outputUnits map[string]string
+}
+
+// NewCatalogue builds a catalogue of fields. Values of a field whose unit is
+// a key of outputUnits are published in the unit it maps to; units that
+// cannot be converted are kept.
+func NewCatalogue(fields []Field, outputUnits map[string]string) *Catalogue {
+	catalogue := &Catalogue{fields: make(map[fieldKey]Field, len(fields)), outputUnits: outputUnits}
+	for _, field := range fields {
+		catalogue.fields[fieldKey{field.RecordType, field.Name}] = field
+	}
+	return catalogue
+}
+
+// Lookup returns the field name of a record of recordType holds, preferring
+// the record type's own entry.
+func (c *Catalogue) Lookup(recordType string, name string) (Field, bool) {
+	if field, ok := c.fields[fieldKey{recordType, name}]; ok {
+		return field, true
+	}
+	field, ok := c.fields[fieldKey{"", name}]
+	return field, ok
+}
+
+// Annotate tags the catalogued values of a record of recordType, in field
+// name order. Values not in the catalogue are left out.
+func (c *Catalogue) Annotate(recordType string, values map[string]float64) []Value {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var annotated []Value
+	for _, name := range names {
+		field, ok := c.Lookup(recordType, name)
+		if !ok {
+			continue
+		}
+		value := Value{
+			Field:        name,
+			ObisCode:     field.ObisCode,
+			Value:        values[name] * math.Pow10(field.Scaler),
+			Unit:         field.Unit,
+			Scaler:       field.Scaler,
+			QuantityType: field.QuantityType,
+		}
+		if unit, ok := c.outputUnits[field.Unit]; ok {
+			if converted, err := Convert(value.Value, field.Unit, unit); err == nil {
+				value.Value, value.Unit = converted, unit
+			}
+		}
+		annotated = append(annotated, value)
+	}
+	return annotated
+}
@@ -0,0 +1,64 @@
+package obis
+
+import "fmt"
+
+// quantity a unit measures and its factor to the base unit of the quantity
+type unitInfo struct {
+	dimension string
+	factor    float64
+}
+
+var units = map[string]unitInfo{
+	"Wh":    {"active_energy", 1},
+	"kWh":   {"active_energy", 1e3},
+	"MWh":   {"active_energy", 1e6},
+	"VAh":   {"apparent_energy", 1},
+	"kVAh":  {"apparent_energy", 1e3},
+	"MVAh":  {"apparent_energy", 1e6},
+	"varh":  {"reactive_energy", 1},
+	"kvarh": {"reactive_energy", 1e3},
+	"Mvarh": {"reactive_energy", 1e6},
+	"W":     {"active_power", 1},
+	"kW":    {"active_power", 1e3},
+	"MW":    {"active_power", 1e6},
+	"VA":    {"apparent_power", 1},
+	"kVA":   {"apparent_power", 1e3},
+	"MVA":   {"apparent_power", 1e6},
+	"var":   {"reactive_power", 1},
+	"kvar":  {"reactive_power", 1e3},
+	"V":     {"voltage", 1},
+	"kV":    {"voltage", 1e3},
+	"mV":    {"voltage", 1e-3},
+	"A":     {"current", 1},
+	"mA":    {"current", 1e-3},
+	"Hz":    {"frequency", 1},
+	"s":     {"time", 1},
+	"min":   {"time", 60},
+	"h":     {"time", 3600},
+}
+
+// Convert returns value, in unit from, in unit to. Both have to measure the
+// same quantity.
+func Convert(value float64, from string, to string) (float64, error) {
+	if from == to {
+		return value, nil
+	}
+	fromInfo, ok := units[from]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", from)
+	}
+	toInfo, ok := units[to]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", to)
+	}
+	if fromInfo.dimension != toInfo.dimension {
+		return 0, fmt.Errorf("cannot convert %s to %s", from, to)
+	}
+	return value * fromInfo.factor / toInfo.factor, nil
+}
+
+// KnownUnit tells whether Convert handles unit.
+func KnownUnit(unit string) bool {
+	_, ok := units[unit]
+	return ok
+}
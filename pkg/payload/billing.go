@@ -0,0 +1,30 @@
+package payload
+
+// billing payload, the registers frozen at the end of a billing period
+//
+//	0-3 billing date time, 4-7 cumulative active energy import (Wh /10),
+//	8-11 cumulative apparent energy import (VAh /10), 12-15 cumulative active
+//	energy export, 16-19 cumulative apparent energy export, 20-23 maximum
+//	demand (W), 24-27 maximum demand (VA), 28-31 power on duration (minutes)
+//
+// This layout is not taken from a meter protocol document, none is available
+// to this service; it follows the field order and scaling of the block-load
+// layout. It is therefore bound only to the command_mapping rows named for
+// billing in PAYLOAD_PARSER_CMD_NAMES, and a row should be named only once
+// its responses are known to match.
+func init() {
+	Register(&Layout{
+		Name:      "billing",
+		TimeField: "billing_datetime",
+		Assumed:   true,
+		Fields: []Field{
+			{Name: "cumm_import_Wh", Index: 4, Length: 4, Scale: 10},
+			{Name: "cumm_import_VAh", Index: 8, Length: 4, Scale: 10},
+			{Name: "cumm_export_Wh", Index: 12, Length: 4, Scale: 10},
+			{Name: "cumm_export_VAh", Index: 16, Length: 4, Scale: 10},
+			{Name: "max_demand_W", Index: 20, Length: 4, Scale: 1},
+			{Name: "max_demand_VA", Index: 24, Length: 4, Scale: 1},
+			{Name: "power_on_minutes", Index: 28, Length: 4, Scale: 1},
+		},
+	})
+}
@@ -0,0 +1,36 @@
+package payload
+
+import (
+	"time"
+
+	"parsing-service/pkg/tap"
+)
+
+const RECORD_TYPE_BLOCK_LOAD = "block_load"
+
+// blockLoad decodes block-load payloads, daily profile included in the newer
+// layout, with tap.BlockLoadParser. Pushed block loads carry cumulative
+// energies instead of interval ones.
+type blockLoad struct {
+	profile  string
+	fromPush bool
+}
+
+func init() {
+	Register(&blockLoad{profile: "block_load"})
+	Register(&blockLoad{profile: "block_load_push", fromPush: true})
+}
+
+func (b *blockLoad) Profile() string {
+	return b.profile
+}
+
+// Parse returns a record whose Data is the *tap.BlockLoadParser.
+func (b *blockLoad) Parse(payload []byte, ctx Context) (Record, error) {
+	var parsed tap.BlockLoadParser
+	if err := parsed.Deserialize(payload, b.fromPush, ctx.DcuNumber, ctx.MeterIp); err != nil {
+		return Record{}, err
+	}
+	takenAt, _ := time.Parse(DATE_TIME_LAYOUT, parsed.BlockLoadDateTime)
+	return Record{Type: RECORD_TYPE_BLOCK_LOAD, TakenAt: takenAt, Data: &parsed}, nil
+}
@@ -0,0 +1,21 @@
+package payload
+
+// daily profile payload, the daily part of the newer block-load layout
+//
+//	0-3 date time, 4-7 cumulative active energy import (Wh x100),
+//	8-11 cumulative apparent energy import (VAh x100), 12-15 cumulative
+//	active energy export, 16-19 cumulative apparent energy export,
+//	20-23 temperature (m°C, signed)
+func init() {
+	Register(&Layout{
+		Name:      "daily_profile",
+		TimeField: "daily_date_time",
+		Fields: []Field{
+			{Name: "daily_cumm_active_energy_imp", Index: 4, Length: 4, Scale: 0.01},
+			{Name: "daily_cumm_apparent_energy_imp", Index: 8, Length: 4, Scale: 0.01},
+			{Name: "daily_cumm_active_energy_exp", Index: 12, Length: 4, Scale: 0.01},
+			{Name: "daily_cumm_apparent_energy_exp", Index: 16, Length: 4, Scale: 0.01},
+			{Name: "daily_temperature", Index: 20, Length: 4, Signed: true, Scale: 0.001},
+		},
+	})
+}
@@ -0,0 +1,84 @@
+package payload
+
+import (
+	"fmt"
+	"time"
+
+	"parsing-service/pkg/tap"
+)
+
+// event log payload: entries of
+//
+//	0-3 occurrence date time, 4-5 vendor event code
+//
+// This entry layout is not taken from a meter protocol document, none is
+// available to this service; it assumes the date time encoding of the other
+// records followed by a two byte code. The event log is therefore bound only
+// to the command_mapping rows named for event_log in PAYLOAD_PARSER_CMD_NAMES,
+// and a row should be named only once its responses are known to match.
+const EVENT_ENTRY_LEN = 6
+
+const RECORD_TYPE_EVENT_LOG = "event_log"
+
+type EventEntry struct {
+	Code       int       `json:"code"`
+	OccurredAt time.Time `json:"-"`
+}
+
+// EventLog is a decoded event log, entries in the order the meter sent them.
+type EventLog struct {
+	RecordType string       `json:"record_type"`
+	MeterIp    string       `json:"meter_ip"`
+	DcuNo      uint32       `json:"dcu_no"`
+	CmdID      int          `json:"cmd_id"`
+	Events     []EventEntry `json:"events"`
+}
+
+// MarshalJSON formats the occurrence time as the other records do.
+func (e EventEntry) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`{"code":%d,"occurred_at":%q}`, e.Code, e.OccurredAt.Format(DATE_TIME_LAYOUT))), nil
+}
+
+type eventLog struct{}
+
+func init() {
+	Register(eventLog{})
+}
+
+func (eventLog) Profile() string {
+	return RECORD_TYPE_EVENT_LOG
+}
+
+func (eventLog) AssumedLayout() bool {
+	return true
+}
+
+// Parse reads the entries of an event log. The record is taken at its
+// latest event.
+func (eventLog) Parse(payload []byte, ctx Context) (Record, error) {
+	if len(payload) == 0 || len(payload)%EVENT_ENTRY_LEN != 0 {
+		return Record{}, fmt.Errorf("%w: event log of %d byte(s) is not whole entries", ErrShortPayload, len(payload))
+	}
+
+	record := Record{Type: RECORD_TYPE_EVENT_LOG}
+	log := &EventLog{
+		RecordType: RECORD_TYPE_EVENT_LOG,
+		MeterIp:    ctx.MeterIp,
+		DcuNo:      ctx.DcuNumber,
+		CmdID:      ctx.CmdID,
+		Events:     make([]EventEntry, 0, len(payload)/EVENT_ENTRY_LEN),
+	}
+	for offset := 0; offset < len(payload); offset += EVENT_ENTRY_LEN {
+		occurredAt := unixTime(tap.DeserializeUInt32(payload[offset:offset+4], "reverse"))
+		code, err := tap.DeserializeUInt16(payload[offset+4:offset+6], "reverse")
+		if err != nil {
+			return Record{}, err
+		}
+		if occurredAt.After(record.TakenAt) {
+			record.TakenAt = occurredAt
+		}
+		log.Events = append(log.Events, EventEntry{Code: code, OccurredAt: occurredAt})
+	}
+	record.Data = log
+	return record, nil
+}
@@ -0,0 +1,36 @@
+package payload
+
+import (
+	"fmt"
+
+	"parsing-service/pkg/dissect"
+)
+
+const GENERIC_PROFILE = "generic"
+
+// Generic decodes a payload with the fields deserialize_logics rows give for
+// its command. It is the fallback for commands no profile is bound to and is
+// built per command rather than registered.
+type Generic struct {
+	Fields []dissect.PayloadField
+}
+
+func (g *Generic) Profile() string {
+	return GENERIC_PROFILE
+}
+
+func (g *Generic) Parse(payload []byte, ctx Context) (Record, error) {
+	data := map[string]interface{}{
+		"record_type": GENERIC_PROFILE,
+		"meter_ip":    ctx.MeterIp,
+		"dcu_no":      ctx.DcuNumber,
+		"cmd_id":      ctx.CmdID,
+	}
+	for _, node := range dissect.PayloadNodes(payload, 0, g.Fields) {
+		if !node.Complete() {
+			return Record{}, fmt.Errorf("%w: field %s %s", ErrShortPayload, node.Name, node.Error)
+		}
+		data[node.Name] = node.Value
+	}
+	return Record{Type: GENERIC_PROFILE, Data: data}, nil
+}
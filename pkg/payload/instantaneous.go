@@ -0,0 +1,36 @@
+package payload
+
+// instantaneous payload
+//
+//	0-3 date time, 4-7 voltage (mV), 8-11 current (mA), 12-15 active power
+//	(W, signed), 16-19 apparent power (VA), 20-21 power factor (x1000,
+//	signed), 22-23 frequency (x100 Hz), 24-27 cumulative active energy
+//	import (Wh /10), 28-31 cumulative apparent energy import (VAh /10),
+//	32-35 cumulative active energy export, 36-39 cumulative apparent energy
+//	export, 40-43 temperature (m°C, signed)
+//
+// This layout is not taken from a meter protocol document, none is available
+// to this service; it follows the field order and scaling of the block-load
+// layout. It is therefore bound only to the command_mapping rows named for
+// instantaneous in PAYLOAD_PARSER_CMD_NAMES, and a row should be named only
+// once its responses are known to match.
+func init() {
+	Register(&Layout{
+		Name:      "instantaneous",
+		TimeField: "instantaneous_datetime",
+		Assumed:   true,
+		Fields: []Field{
+			{Name: "voltage", Index: 4, Length: 4, Scale: 0.001},
+			{Name: "current", Index: 8, Length: 4, Scale: 0.001},
+			{Name: "active_power_W", Index: 12, Length: 4, Signed: true, Scale: 1},
+			{Name: "apparent_power_VA", Index: 16, Length: 4, Scale: 1},
+			{Name: "power_factor", Index: 20, Length: 2, Signed: true, Scale: 0.001},
+			{Name: "frequency", Index: 22, Length: 2, Scale: 0.01},
+			{Name: "cumm_import_Wh", Index: 24, Length: 4, Scale: 10},
+			{Name: "cumm_import_VAh", Index: 28, Length: 4, Scale: 10},
+			{Name: "cumm_export_Wh", Index: 32, Length: 4, Scale: 10},
+			{Name: "cumm_export_VAh", Index: 36, Length: 4, Scale: 10},
+			{Name: "temperature", Index: 40, Length: 4, Signed: true, Scale: 0.001},
+		},
+	})
+}
@@ -0,0 +1,79 @@
+package payload
+
+import (
+	"fmt"
+
+	"parsing-service/pkg/tap"
+)
+
+// Field is a little-endian integer of Length bytes at Index, published as
+// its value times Scale.
+type Field struct {
+	Name   string
+	Index  int
+	Length int
+	Signed bool
+	Scale  float64
+}
+
+// Layout parses payloads of fixed fields. When TimeField is set the payload
+// starts with the unix time the reading was taken at, 4 bytes little-endian,
+// published under that name. Assumed marks a layout that no meter protocol
+// document confirms.
+type Layout struct {
+	Name      string
+	TimeField string
+	Fields    []Field
+	Assumed   bool
+}
+
+func (l *Layout) Profile() string {
+	return l.Name
+}
+
+func (l *Layout) AssumedLayout() bool {
+	return l.Assumed
+}
+
+func (l *Layout) minLength() int {
+	length := 0
+	if l.TimeField != "" {
+		length = 4
+	}
+	for _, field := range l.Fields {
+		if end := field.Index + field.Length; end > length {
+			length = end
+		}
+	}
+	return length
+}
+
+// Parse reads the fields of payload into a flat record that also names the
+// meter, DCU and command it came from. Bytes past the last field are
+// ignored.
+func (l *Layout) Parse(payload []byte, ctx Context) (Record, error) {
+	if minLength := l.minLength(); len(payload) < minLength {
+		return Record{}, fmt.Errorf("%w: %s needs %d byte(s), got %d", ErrShortPayload, l.Name, minLength, len(payload))
+	}
+
+	record := Record{Type: l.Name}
+	data := map[string]interface{}{
+		"record_type": l.Name,
+		"meter_ip":    ctx.MeterIp,
+		"dcu_no":      ctx.DcuNumber,
+		"cmd_id":      ctx.CmdID,
+	}
+	if l.TimeField != "" {
+		record.TakenAt = unixTime(tap.DeserializeUInt32(payload[0:4], "reverse"))
+		data[l.TimeField] = record.TakenAt.Format(DATE_TIME_LAYOUT)
+	}
+	for _, field := range l.Fields {
+		value, err := tap.DeserializeGeneric(payload[field.Index:field.Index+field.Length], field.Length, field.Signed, "reverse")
+		if err != nil {
+			return Record{}, fmt.Errorf("%s field %s: %w", l.Name, field.Name, err)
+		}
+		data[field.Name] = float64(value) * field.Scale
+	}
+	record.Data = data
+	return record, nil
+}
@@ -0,0 +1,97 @@
+package payload
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DATE_TIME_LAYOUT is how record timestamps are formatted, in UTC, as
+// BlockLoadParser does.
+const DATE_TIME_LAYOUT = "2006-01-02 15:04:05"
+
+var ErrShortPayload = errors.New("payload too short for its layout")
+
+// Context is what is known about a payload besides its bytes.
+type Context struct {
+	MeterIp   string
+	DcuNumber uint32
+	CmdID     int
+	SrcPort   uint8
+}
+
+// Record is a decoded payload. Data is published as JSON; TakenAt is the
+// time the meter took the reading, zero when the payload carries none.
+type Record struct {
+	Type    string
+	TakenAt time.Time
+	Data    interface{}
+}
+
+// PayloadParser decodes the payloads of one meter profile. Profile names
+// the profile; command_mapping rows with the upper-cased profile as cmd_name
+// are decoded with the parser unless the config names other rows.
+type PayloadParser interface {
+	Profile() string
+	Parse(payload []byte, ctx Context) (Record, error)
+}
+
+// AssumedLayout is implemented by parsers whose layout is not taken from a
+// meter protocol document. Such a parser is bound only to the command_mapping
+// rows the config names for its profile, never to the rows named after it.
+type AssumedLayout interface {
+	AssumedLayout() bool
+}
+
+// BoundByDefault reports whether parser may be bound to the command_mapping
+// rows named after its profile when the config names no rows for it.
+func BoundByDefault(parser PayloadParser) bool {
+	assumed, ok := parser.(AssumedLayout)
+	return !ok || !assumed.AssumedLayout()
+}
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = map[string]PayloadParser{}
+)
+
+// Register makes a parser available by its profile. Compiled-in parsers
+// register themselves from init; registering a profile twice panics.
+func Register(parser PayloadParser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+
+	profile := parser.Profile()
+	if _, ok := parsers[profile]; ok {
+		panic(fmt.Sprintf("payload parser %s registered twice", profile))
+	}
+	parsers[profile] = parser
+}
+
+// Parser returns the parser registered for profile.
+func Parser(profile string) (PayloadParser, bool) {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+
+	parser, ok := parsers[profile]
+	return parser, ok
+}
+
+// Profiles lists the registered profiles in name order.
+func Profiles() []string {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+
+	profiles := make([]string, 0, len(parsers))
+	for profile := range parsers {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+func unixTime(seconds int) time.Time {
+	return time.Unix(int64(seconds), 0).UTC()
+}
@@ -0,0 +1,98 @@
+package payload
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ANY_PORT and ANY_GROUP make a key match every source port or firmware
+// group, as a command_mapping or deserialize_logics row with 0 does.
+const (
+	ANY_PORT  = 0
+	ANY_GROUP = 0
+)
+
+var (
+	ErrNotBound      = errors.New("no payload parser bound")
+	ErrGroupConflict = errors.New("payload parsers of several firmware groups bound, the meter's group is needed")
+)
+
+// Key is what a parser is bound by: a command id, the source port of the
+// packets and the firmware group of the meter.
+type Key struct {
+	CmdID   int
+	Port    uint8
+	GroupID int
+}
+
+type binding struct {
+	key    Key
+	parser PayloadParser
+}
+
+// Registry picks the parser of a payload by its key. The most specific
+// binding wins: the exact key, then any group, then any port, then both.
+type Registry struct {
+	bindings map[Key]PayloadParser
+}
+
+func NewRegistry() *Registry {
+	return &Registry{bindings: make(map[Key]PayloadParser)}
+}
+
+// Bind decodes payloads of key with parser; a later binding of the same key
+// replaces an earlier one.
+func (r *Registry) Bind(key Key, parser PayloadParser) {
+	r.bindings[key] = parser
+}
+
+// Lookup returns the parser bound to key and the key it was bound by, or
+// ErrNotBound. With ANY_GROUP, for meters whose group is unknown, bindings
+// for single groups are used when they all name the same parser; the key
+// returned carries their group if there is only one, ANY_GROUP otherwise.
+// Bindings of different parsers fail with ErrGroupConflict rather than
+// guessing the group.
+func (r *Registry) Lookup(key Key) (PayloadParser, Key, error) {
+	candidates := []Key{
+		key,
+		{CmdID: key.CmdID, Port: key.Port, GroupID: ANY_GROUP},
+		{CmdID: key.CmdID, Port: ANY_PORT, GroupID: key.GroupID},
+		{CmdID: key.CmdID, Port: ANY_PORT, GroupID: ANY_GROUP},
+	}
+	for _, candidate := range candidates {
+		if parser, ok := r.bindings[candidate]; ok {
+			return parser, candidate, nil
+		}
+	}
+	if key.GroupID != ANY_GROUP {
+		return nil, Key{}, ErrNotBound
+	}
+
+	// the bindings of the exact port win over those of any port
+	for _, port := range []uint8{key.Port, ANY_PORT} {
+		var found PayloadParser
+		var foundKey Key
+		for bound, parser := range r.bindings {
+			if bound.CmdID != key.CmdID || bound.Port != port {
+				continue
+			}
+			if found == nil {
+				found, foundKey = parser, bound
+				continue
+			}
+			if parser.Profile() != found.Profile() {
+				return nil, Key{}, fmt.Errorf("%w: command %d port %d", ErrGroupConflict, key.CmdID, port)
+			}
+			foundKey.GroupID = ANY_GROUP
+		}
+		if found != nil {
+			return found, foundKey, nil
+		}
+	}
+	return nil, Key{}, ErrNotBound
+}
+
+// Len returns the number of bindings.
+func (r *Registry) Len() int {
+	return len(r.bindings)
+}
@@ -0,0 +1,51 @@
+package payload
+
+import (
+	"errors"
+	"testing"
+)
+
+type profileParser string
+
+func (p profileParser) Profile() string { return string(p) }
+
+func (p profileParser) Parse(payload []byte, ctx Context) (Record, error) {
+	return Record{}, nil
+}
+
+func TestRegistryLookupByGroup(t *testing.T) {
+	r := NewRegistry()
+	r.Bind(Key{CmdID: 1, Port: 5, GroupID: 1}, profileParser("billing"))
+	r.Bind(Key{CmdID: 1, Port: 5, GroupID: 2}, profileParser("billing_v2"))
+	r.Bind(Key{CmdID: 2, Port: 5, GroupID: 1}, profileParser("instantaneous"))
+	r.Bind(Key{CmdID: 2, Port: 5, GroupID: 2}, profileParser("instantaneous"))
+	r.Bind(Key{CmdID: 3, Port: ANY_PORT, GroupID: 4}, profileParser("event_log"))
+
+	for _, tt := range []struct {
+		name    string
+		key     Key
+		profile string
+		bound   Key
+		err     error
+	}{
+		{"meter group", Key{CmdID: 1, Port: 5, GroupID: 2}, "billing_v2", Key{CmdID: 1, Port: 5, GroupID: 2}, nil},
+		{"group not bound", Key{CmdID: 1, Port: 5, GroupID: 3}, "", Key{}, ErrNotBound},
+		{"unknown group, conflicting parsers", Key{CmdID: 1, Port: 5}, "", Key{}, ErrGroupConflict},
+		{"unknown group, same parser", Key{CmdID: 2, Port: 5}, "instantaneous", Key{CmdID: 2, Port: 5}, nil},
+		{"unknown group, one group", Key{CmdID: 3, Port: 7}, "event_log", Key{CmdID: 3, GroupID: 4}, nil},
+		{"unknown command", Key{CmdID: 9, Port: 5}, "", Key{}, ErrNotBound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, bound, err := r.Lookup(tt.key)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if parser.Profile() != tt.profile || bound != tt.bound {
+				t.Fatalf("got %s bound by %+v, want %s bound by %+v", parser.Profile(), bound, tt.profile, tt.bound)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package pcapng
+
+import _ "embed"
+
+// LuaDissector is a Wireshark Lua plugin for files written with LINKTYPE_WP
+// and LINKTYPE_IRDA interfaces. It decodes the WP frame header, sub-messages
+// and trailer and the TAP packets inside uplinks and IRDA records, and flags
+// crc mismatches.
+//
+//go:embed wp_tap.lua
+var LuaDissector []byte
+
+const LUA_DISSECTOR_FILE_NAME = "wp_tap.lua"
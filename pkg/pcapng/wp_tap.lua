@@ -0,0 +1,305 @@
+-- Wireshark dissector for the captures written by parsing-service.
+--
+-- Install by copying this file into the Wireshark personal plugins folder
+-- (Help > About Wireshark > Folders). Packets on LINKTYPE_USER0 (147) are WP
+-- frames, packets on LINKTYPE_USER1 (148) are chunks of a DCU's IRDA stream.
+-- The layouts mirror pkg/wp and pkg/tap.
+
+local wp_proto = Proto("wp", "WP gateway frame")
+local tap_proto = Proto("tap", "TAP packet")
+local irda_proto = Proto("irda", "IRDA stream chunk")
+
+local WP_START_BYTE = 0xFE
+local TAP_START_BYTE = 0xAA
+local WP_HEADER_LEN = 4
+local WP_TRAILER_LEN = 10
+local TAP_HEADER_LEN = 12
+local IRDA_MARKER = "RECT"
+local IRDA_HEADER_LEN = 18
+local IRDA_HEADER_TYPE = 0x10
+
+local message_types = {
+    [1] = "Downlink_Sent_Status_Msg",
+    [2] = "Uplink_Msg",
+    [4] = "Set_App_Config_Resp_Msg",
+    [6] = "Set_Sink_Config_Resp_Msg",
+    [8] = "Set_Diag_Resp_Msg",
+    [10] = "Get_App_Config_Msg",
+    [12] = "Get_Sink_Config_Msg",
+    [14] = "Get_Diag_Msg",
+    [16] = "Set_Stack_State_Resp_Msg",
+    [31] = "Set_Otap_Action_Resp_Msg",
+    [33] = "Get_Otap_Action_Resp_Msg",
+    [35] = "Upload_Scratch_Pad_Chunk_Resp_Msg",
+    [37] = "Process_Scratch_Pad_Resp_Msg",
+    [129] = "DCU_Resp_Msg",
+    [130] = "DCU_Diag_Resp_Msg",
+}
+
+-- fixed length of every sub-message type, payload excluded
+local fixed_lengths = {
+    [1] = 4, [4] = 3, [6] = 3, [8] = 3, [10] = 82, [12] = 12, [14] = 4,
+    [16] = 3, [31] = 3, [33] = 7, [35] = 3, [37] = 3, [129] = 16, [130] = 16,
+}
+
+-- uplink header per protocol version: {offset, size} of the variable fields
+local uplink_layouts = {
+    [1] = { len = 19, hop_count = { 18, 1 }, msg_len = { 17, 1 } },
+    [2] = { len = 20, hop_count = { 17, 1 }, msg_len = { 18, 2 } },
+}
+
+local DCU_RESP_MSG_LEN_OFFSET = 13
+
+local wp_fields = {
+    start_byte = ProtoField.uint8("wp.start_byte", "Start byte", base.HEX),
+    packet_len = ProtoField.uint16("wp.packet_len", "Packet length", base.DEC),
+    sink_id = ProtoField.uint8("wp.sink_id", "Sink id", base.DEC),
+    version = ProtoField.uint8("wp.protocol_version", "Protocol version", base.DEC),
+    msg_type = ProtoField.uint8("wp.message_type", "Message type", base.DEC, message_types),
+    message_id = ProtoField.uint8("wp.message_id", "Message id", base.DEC),
+    status = ProtoField.uint8("wp.status", "Status", base.DEC),
+    src_address = ProtoField.uint32("wp.src_address", "Source address", base.DEC),
+    dst_address = ProtoField.uint32("wp.dst_address", "Destination address", base.DEC),
+    src_ep = ProtoField.uint8("wp.src_endpoint", "Source endpoint", base.DEC),
+    dst_ep = ProtoField.uint8("wp.dst_endpoint", "Destination endpoint", base.DEC),
+    travel_time = ProtoField.uint32("wp.travel_time", "Travel time", base.DEC),
+    qos = ProtoField.uint8("wp.qos", "QoS", base.DEC),
+    hop_count = ProtoField.uint8("wp.hop_count", "Hop count", base.DEC),
+    msg_len = ProtoField.uint16("wp.msg_len", "Payload length", base.DEC),
+    diag_interval = ProtoField.uint16("wp.diag_interval", "Diag interval", base.DEC),
+    body = ProtoField.bytes("wp.body", "Body"),
+    payload = ProtoField.bytes("wp.payload", "Payload"),
+    dcu_time = ProtoField.absolute_time("wp.dcu_time", "DCU time", base.UTC),
+    dcu_number = ProtoField.uint32("wp.dcu_number", "DCU number", base.DEC),
+    crc = ProtoField.uint16("wp.crc", "CRC", base.HEX),
+}
+wp_proto.fields = wp_fields
+
+local tap_fields = {
+    start_byte = ProtoField.uint8("tap.start_byte", "Start byte", base.HEX),
+    src_addr = ProtoField.ipv4("tap.src_addr", "Source address"),
+    dst_addr = ProtoField.ipv4("tap.dst_addr", "Destination address"),
+    src_port = ProtoField.uint8("tap.src_port", "Source port", base.DEC),
+    dst_port = ProtoField.uint8("tap.dst_port", "Destination port", base.DEC),
+    data_len = ProtoField.uint8("tap.data_len", "Data length", base.DEC),
+    cmd_id = ProtoField.uint32("tap.cmd_id", "Command id", base.DEC),
+    data = ProtoField.bytes("tap.data", "Data"),
+    crc = ProtoField.uint16("tap.crc", "CRC", base.HEX),
+}
+tap_proto.fields = tap_fields
+
+local irda_fields = {
+    marker = ProtoField.string("irda.marker", "Record marker"),
+    header = ProtoField.bytes("irda.header", "Record header"),
+    dcu_port = ProtoField.uint8("irda.dcu_port", "DCU port", base.DEC),
+    junk = ProtoField.bytes("irda.junk", "Bytes outside a record"),
+}
+irda_proto.fields = irda_fields
+
+local crc_bad = ProtoExpert.new("wp.crc.bad", "CRC mismatch", expert.group.CHECKSUM, expert.severity.ERROR)
+local truncated = ProtoExpert.new("wp.truncated", "Truncated", expert.group.MALFORMED, expert.severity.ERROR)
+wp_proto.experts = { crc_bad, truncated }
+
+-- crc16 xmodem over the bytes of tvb range, zero padded to an even length:
+-- the default CRC16_XMODEM_EVEN variant of pkg/checksum
+local function crc16_xmodem_even(range)
+    local bytes = range:bytes()
+    local len = bytes:len()
+    local crc = 0
+    local padded = len + (len % 2)
+    for i = 0, padded - 1 do
+        local b = 0
+        if i < len then b = bytes:get_index(i) end
+        crc = bit.bxor(crc, bit.lshift(b, 8))
+        for _ = 1, 8 do
+            if bit.band(crc, 0x8000) ~= 0 then
+                crc = bit.band(bit.bxor(bit.lshift(crc, 1), 0x1021), 0xFFFF)
+            else
+                crc = bit.band(bit.lshift(crc, 1), 0xFFFF)
+            end
+        end
+    end
+    return crc
+end
+
+-- cmd id as TAPPacket.CmdID reads it from the destination address
+local function cmd_id(dst, dst_port)
+    if dst_port == 4 then
+        return bit.band(bit.rshift(dst, 8), 0xFFFF)
+    elseif dst_port == 219 then
+        return dst
+    end
+    return bit.band(dst, 0xFFFF)
+end
+
+-- dissects the TAP packet at offset, returns its length or 0 when it does
+-- not fit in tvb
+local function dissect_tap(tvb, pinfo, tree, offset)
+    if tvb:len() - offset < TAP_HEADER_LEN then
+        return 0
+    end
+    local data_len = tvb(offset + 11, 1):uint()
+    local total = TAP_HEADER_LEN + data_len + 2
+    if tvb:len() - offset < total then
+        local subtree = tree:add(tap_proto, tvb(offset), "TAP packet (truncated)")
+        subtree:add_proto_expert_info(truncated)
+        return 0
+    end
+
+    local subtree = tree:add(tap_proto, tvb(offset, total))
+    subtree:add(tap_fields.start_byte, tvb(offset, 1))
+    subtree:add(tap_fields.src_addr, tvb(offset + 1, 4))
+    subtree:add(tap_fields.dst_addr, tvb(offset + 5, 4))
+    subtree:add(tap_fields.src_port, tvb(offset + 9, 1))
+    subtree:add(tap_fields.dst_port, tvb(offset + 10, 1))
+    subtree:add(tap_fields.data_len, tvb(offset + 11, 1))
+    local cmd = cmd_id(tvb(offset + 5, 4):uint(), tvb(offset + 10, 1):uint())
+    subtree:add(tap_fields.cmd_id, tvb(offset + 5, 4), cmd):set_generated()
+    if data_len > 0 then
+        subtree:add(tap_fields.data, tvb(offset + TAP_HEADER_LEN, data_len))
+    end
+
+    local crc_range = tvb(offset + TAP_HEADER_LEN + data_len, 2)
+    local crc_item = subtree:add(tap_fields.crc, crc_range)
+    local computed = crc16_xmodem_even(tvb(offset + 1, TAP_HEADER_LEN - 1 + data_len))
+    if computed ~= crc_range:uint() then
+        crc_item:add_proto_expert_info(crc_bad, string.format("CRC mismatch, computed 0x%04X", computed))
+    end
+
+    pinfo.cols.protocol = "TAP"
+    pinfo.cols.info:append(string.format(" TAP %s > %s cmd %d",
+        tostring(tvb(offset + 1, 4):ipv4()), tostring(tvb(offset + 5, 4):ipv4()), cmd))
+    return total
+end
+
+-- length of the sub-message at offset, nil when it is unknown or does not fit
+local function sub_message_length(tvb, offset, finish)
+    if finish - offset < 2 then return nil end
+    local version = tvb(offset, 1):uint()
+    local msg_type = tvb(offset + 1, 1):uint()
+    local length
+    if msg_type == 2 then
+        local layout = uplink_layouts[version]
+        if layout == nil or finish - offset < layout.len then return nil end
+        length = layout.len + tvb(offset + layout.msg_len[1], layout.msg_len[2]):le_uint()
+    elseif msg_type == 129 or msg_type == 130 then
+        if finish - offset < fixed_lengths[msg_type] then return nil end
+        length = fixed_lengths[msg_type] + tvb(offset + DCU_RESP_MSG_LEN_OFFSET, 1):uint()
+    else
+        length = fixed_lengths[msg_type]
+    end
+    if length == nil or offset + length > finish then return nil end
+    return length
+end
+
+local function dissect_sub_message(tvb, pinfo, tree, offset, length)
+    local version = tvb(offset, 1):uint()
+    local msg_type = tvb(offset + 1, 1):uint()
+    local subtree = tree:add(wp_proto, tvb(offset, length), message_types[msg_type] or "Sub-message")
+    subtree:add(wp_fields.version, tvb(offset, 1))
+    subtree:add(wp_fields.msg_type, tvb(offset + 1, 1))
+
+    if msg_type == 1 then
+        subtree:add(wp_fields.message_id, tvb(offset + 2, 1))
+        subtree:add(wp_fields.status, tvb(offset + 3, 1))
+    elseif msg_type == 2 then
+        local layout = uplink_layouts[version]
+        subtree:add_le(wp_fields.src_address, tvb(offset + 2, 4))
+        subtree:add_le(wp_fields.dst_address, tvb(offset + 6, 4))
+        subtree:add(wp_fields.src_ep, tvb(offset + 10, 1))
+        subtree:add(wp_fields.dst_ep, tvb(offset + 11, 1))
+        subtree:add_le(wp_fields.travel_time, tvb(offset + 12, 4))
+        subtree:add(wp_fields.qos, tvb(offset + 16, 1))
+        subtree:add(wp_fields.hop_count, tvb(offset + layout.hop_count[1], layout.hop_count[2]))
+        subtree:add_le(wp_fields.msg_len, tvb(offset + layout.msg_len[1], layout.msg_len[2]))
+        local payload_len = length - layout.len
+        if payload_len > 0 then
+            local payload_offset = offset + layout.len
+            local payload = subtree:add(wp_fields.payload, tvb(payload_offset, payload_len))
+            if tvb(payload_offset, 1):uint() == TAP_START_BYTE then
+                dissect_tap(tvb, pinfo, payload, payload_offset)
+            end
+        end
+    elseif msg_type == 14 then
+        subtree:add_le(wp_fields.diag_interval, tvb(offset + 2, 2))
+    elseif length == 3 then
+        subtree:add(wp_fields.status, tvb(offset + 2, 1))
+    elseif length > 2 then
+        subtree:add(wp_fields.body, tvb(offset + 2, length - 2))
+    end
+end
+
+function wp_proto.dissector(tvb, pinfo, tree)
+    pinfo.cols.protocol = "WP"
+    pinfo.cols.info = ""
+    local len = tvb:len()
+    local root = tree:add(wp_proto, tvb(), "WP frame")
+    if len < WP_HEADER_LEN + WP_TRAILER_LEN then
+        root:add_proto_expert_info(truncated)
+        return
+    end
+
+    root:add(wp_fields.start_byte, tvb(0, 1))
+    root:add_le(wp_fields.packet_len, tvb(1, 2))
+    root:add(wp_fields.sink_id, tvb(3, 1))
+
+    local trailer = len - WP_TRAILER_LEN
+    local offset = WP_HEADER_LEN
+    while offset < trailer do
+        local length = sub_message_length(tvb, offset, trailer)
+        if length == nil then
+            local rest = root:add(wp_fields.body, tvb(offset, trailer - offset))
+            rest:add_proto_expert_info(truncated, "Sub-message could not be parsed")
+            break
+        end
+        dissect_sub_message(tvb, pinfo, root, offset, length)
+        offset = offset + length
+    end
+
+    root:add_le(wp_fields.dcu_time, tvb(trailer, 4))
+    root:add_le(wp_fields.dcu_number, tvb(trailer + 4, 4))
+    local crc_item = root:add_le(wp_fields.crc, tvb(trailer + 8, 2))
+    local computed = crc16_xmodem_even(tvb(0, trailer + 8))
+    if computed ~= tvb(trailer + 8, 2):le_uint() then
+        crc_item:add_proto_expert_info(crc_bad, string.format("CRC mismatch, computed 0x%04X", computed))
+    end
+    pinfo.cols.info:prepend(string.format("DCU %d", tvb(trailer + 4, 4):le_uint()))
+end
+
+function irda_proto.dissector(tvb, pinfo, tree)
+    pinfo.cols.protocol = "IRDA"
+    pinfo.cols.info = ""
+    local len = tvb:len()
+    local root = tree:add(irda_proto, tvb(), "IRDA stream chunk")
+    local str = tvb:raw()
+    local offset = 0
+    while offset < len do
+        local found = string.find(str, IRDA_MARKER, offset + 1, true)
+        if found == nil then
+            root:add(irda_fields.junk, tvb(offset))
+            break
+        end
+        local marker = found - 1
+        if marker > offset then
+            root:add(irda_fields.junk, tvb(offset, marker - offset))
+        end
+        root:add(irda_fields.marker, tvb(marker, #IRDA_MARKER))
+        offset = marker + #IRDA_MARKER
+
+        if offset + IRDA_HEADER_LEN <= len and tvb(offset, 1):uint() ~= TAP_START_BYTE
+            and tvb(offset + 16, 1):uint() == IRDA_HEADER_TYPE then
+            local header = root:add(irda_fields.header, tvb(offset, IRDA_HEADER_LEN))
+            header:add(irda_fields.dcu_port, tvb(offset + 17, 1))
+            offset = offset + IRDA_HEADER_LEN
+        end
+        if offset < len and tvb(offset, 1):uint() == TAP_START_BYTE then
+            local tap_len = dissect_tap(tvb, pinfo, root, offset)
+            if tap_len == 0 then break end
+            offset = offset + tap_len
+        end
+    end
+end
+
+local wtap_encap = DissectorTable.get("wtap_encap")
+wtap_encap:add(wtap.USER0, wp_proto)
+wtap_encap:add(wtap.USER1, irda_proto)
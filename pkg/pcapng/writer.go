@@ -0,0 +1,164 @@
+package pcapng
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// link types reserved for private use, see
+// https://www.tcpdump.org/linktypes.html
+const (
+	LINKTYPE_USER0 = uint16(147)
+	LINKTYPE_USER1 = uint16(148)
+)
+
+// link types the Lua dissector shipped with this package registers for
+const (
+	// a complete WP frame, start byte to crc
+	LINKTYPE_WP = LINKTYPE_USER0
+	// a chunk of a DCU's IRDA stream: RECT records holding TAP packets
+	LINKTYPE_IRDA = LINKTYPE_USER1
+)
+
+const (
+	blockTypeSHB = uint32(0x0A0D0D0A)
+	blockTypeIDB = uint32(0x00000001)
+	blockTypeEPB = uint32(0x00000006)
+
+	byteOrderMagic = uint32(0x1A2B3C4D)
+
+	optEndOfOpt  = uint16(0)
+	optComment   = uint16(1)
+	optIfName    = uint16(2)
+	optIfTsresol = uint16(9)
+
+	// timestamps are written in microseconds, the pcapng default
+	tsResolution = 6
+
+	snapLen = uint32(0)
+
+	maxOptionLen = 0xFFFF
+)
+
+var ErrUnknownInterface = errors.New("pcapng: unknown interface")
+
+// Interface is one capture interface of the file. Every packet is written on
+// one of them and is dissected according to its link type.
+type Interface struct {
+	LinkType uint16
+	Name     string
+}
+
+// Writer writes a single section pcapng file. It is not safe for concurrent
+// use.
+type Writer struct {
+	w          io.Writer
+	interfaces []Interface
+}
+
+// NewWriter writes the section header and one interface description per
+// interface. Packets refer to an interface by its index in interfaces.
+func NewWriter(w io.Writer, interfaces ...Interface) (*Writer, error) {
+	if len(interfaces) == 0 {
+		return nil, errors.New("pcapng: at least one interface is needed")
+	}
+	writer := &Writer{w: w, interfaces: interfaces}
+
+	shb := make([]byte, 0, 16)
+	shb = binary.LittleEndian.AppendUint32(shb, byteOrderMagic)
+	shb = binary.LittleEndian.AppendUint16(shb, 1)
+	shb = binary.LittleEndian.AppendUint16(shb, 0)
+	// section length not known up front
+	shb = binary.LittleEndian.AppendUint64(shb, 0xFFFFFFFFFFFFFFFF)
+	shb = appendOptions(shb, nil)
+	if err := writer.writeBlock(blockTypeSHB, shb); err != nil {
+		return nil, err
+	}
+
+	for _, iface := range interfaces {
+		idb := make([]byte, 0, 32)
+		idb = binary.LittleEndian.AppendUint16(idb, iface.LinkType)
+		idb = binary.LittleEndian.AppendUint16(idb, 0)
+		idb = binary.LittleEndian.AppendUint32(idb, snapLen)
+		options := []option{{code: optIfTsresol, value: []byte{tsResolution}}}
+		if iface.Name != "" {
+			options = append(options, option{code: optIfName, value: []byte(iface.Name)})
+		}
+		idb = appendOptions(idb, options)
+		if err := writer.writeBlock(blockTypeIDB, idb); err != nil {
+			return nil, err
+		}
+	}
+
+	return writer, nil
+}
+
+// WritePacket writes data as captured at ts on interface iface. comment, if
+// not empty, is attached to the packet and shown by Wireshark.
+func (w *Writer) WritePacket(iface int, ts time.Time, data []byte, comment string) error {
+	if iface < 0 || iface >= len(w.interfaces) {
+		return fmt.Errorf("%w: %d of %d", ErrUnknownInterface, iface, len(w.interfaces))
+	}
+
+	micros := uint64(ts.UnixMicro())
+	epb := make([]byte, 0, 20+len(data)+len(comment)+16)
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(iface))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(micros>>32))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(micros))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(data)))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(data)))
+	epb = appendPadded(epb, data)
+
+	var options []option
+	if comment != "" {
+		if len(comment) > maxOptionLen {
+			comment = comment[:maxOptionLen]
+		}
+		options = append(options, option{code: optComment, value: []byte(comment)})
+	}
+	epb = appendOptions(epb, options)
+
+	return w.writeBlock(blockTypeEPB, epb)
+}
+
+// writeBlock frames body, already padded to 32 bits, with the block type and
+// the total length repeated at both ends.
+func (w *Writer) writeBlock(blockType uint32, body []byte) error {
+	total := uint32(12 + len(body))
+	block := make([]byte, 0, total)
+	block = binary.LittleEndian.AppendUint32(block, blockType)
+	block = binary.LittleEndian.AppendUint32(block, total)
+	block = append(block, body...)
+	block = binary.LittleEndian.AppendUint32(block, total)
+	_, err := w.w.Write(block)
+	return err
+}
+
+type option struct {
+	code  uint16
+	value []byte
+}
+
+// appendOptions appends options followed by opt_endofopt; nothing at all
+// when there are none.
+func appendOptions(buf []byte, options []option) []byte {
+	if len(options) == 0 {
+		return buf
+	}
+	for _, opt := range options {
+		buf = binary.LittleEndian.AppendUint16(buf, opt.code)
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(len(opt.value)))
+		buf = appendPadded(buf, opt.value)
+	}
+	buf = binary.LittleEndian.AppendUint16(buf, optEndOfOpt)
+	return binary.LittleEndian.AppendUint16(buf, 0)
+}
+
+// appendPadded appends data zero padded to 32 bits.
+func appendPadded(buf []byte, data []byte) []byte {
+	buf = append(buf, data...)
+	return append(buf, make([]byte, (4-len(data)%4)%4)...)
+}
@@ -0,0 +1,139 @@
+package pcapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+type block struct {
+	blockType uint32
+	body      []byte
+}
+
+// readBlocks splits a pcapng file into its blocks, checking the total length
+// each block repeats at its end.
+func readBlocks(t *testing.T, data []byte) []block {
+	t.Helper()
+	var blocks []block
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatalf("%d trailing byte(s)", len(data))
+		}
+		blockType := binary.LittleEndian.Uint32(data)
+		total := binary.LittleEndian.Uint32(data[4:])
+		if total%4 != 0 || int(total) > len(data) {
+			t.Fatalf("block %X of length %d in %d byte(s)", blockType, total, len(data))
+		}
+		if trailing := binary.LittleEndian.Uint32(data[total-4:]); trailing != total {
+			t.Fatalf("block %X starts with length %d and ends with %d", blockType, total, trailing)
+		}
+		blocks = append(blocks, block{blockType: blockType, body: data[8 : total-4]})
+		data = data[total:]
+	}
+	return blocks
+}
+
+// readOptions returns the options at the start of body, which must end
+// with opt_endofopt.
+func readOptions(t *testing.T, body []byte) map[uint16][]byte {
+	t.Helper()
+	options := make(map[uint16][]byte)
+	for len(body) >= 4 {
+		code := binary.LittleEndian.Uint16(body)
+		length := int(binary.LittleEndian.Uint16(body[2:]))
+		if code == optEndOfOpt {
+			if length != 0 || len(body) != 4 {
+				t.Fatalf("opt_endofopt of length %d with %d byte(s) after it", length, len(body)-4)
+			}
+			return options
+		}
+		padded := length + (4-length%4)%4
+		if 4+padded > len(body) {
+			t.Fatalf("option %d of length %d runs past the block", code, length)
+		}
+		options[code] = body[4 : 4+length]
+		body = body[4+padded:]
+	}
+	t.Fatalf("options without opt_endofopt")
+	return nil
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewWriter(&out, Interface{LinkType: LINKTYPE_WP, Name: "wp"}, Interface{LinkType: LINKTYPE_IRDA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2024, 10, 5, 1, 57, 22, 123456000, time.UTC)
+	packets := []struct {
+		iface   int
+		data    []byte
+		comment string
+	}{
+		{0, []byte{0xFE, 0x01, 0x02, 0x03}, "dcu=300"},
+		{1, []byte{0xAA}, ""},
+		{0, []byte{1, 2, 3, 4, 5, 6, 7}, "meters=10.0.0.1"},
+		{1, nil, "x"},
+	}
+	for _, p := range packets {
+		if err := w.WritePacket(p.iface, ts, p.data, p.comment); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WritePacket(2, ts, nil, ""); !errors.Is(err, ErrUnknownInterface) {
+		t.Fatalf("packet on interface 2 = %v", err)
+	}
+
+	blocks := readBlocks(t, out.Bytes())
+	if len(blocks) != 3+len(packets) {
+		t.Fatalf("%d blocks, want %d", len(blocks), 3+len(packets))
+	}
+
+	shb := blocks[0]
+	if shb.blockType != blockTypeSHB || binary.LittleEndian.Uint32(shb.body) != byteOrderMagic || len(shb.body) != 16 {
+		t.Fatalf("section header %X of %d byte(s)", shb.blockType, len(shb.body))
+	}
+
+	for i, want := range []Interface{{LinkType: LINKTYPE_WP, Name: "wp"}, {LinkType: LINKTYPE_IRDA}} {
+		idb := blocks[1+i]
+		if idb.blockType != blockTypeIDB || binary.LittleEndian.Uint16(idb.body) != want.LinkType {
+			t.Fatalf("interface %d: block %X link type %d", i, idb.blockType, binary.LittleEndian.Uint16(idb.body))
+		}
+		options := readOptions(t, idb.body[8:])
+		if !bytes.Equal(options[optIfTsresol], []byte{tsResolution}) || string(options[optIfName]) != want.Name {
+			t.Fatalf("interface %d options %v", i, options)
+		}
+	}
+
+	for i, want := range packets {
+		epb := blocks[3+i]
+		if epb.blockType != blockTypeEPB {
+			t.Fatalf("packet %d in block %X", i, epb.blockType)
+		}
+		iface := binary.LittleEndian.Uint32(epb.body)
+		micros := uint64(binary.LittleEndian.Uint32(epb.body[4:]))<<32 | uint64(binary.LittleEndian.Uint32(epb.body[8:]))
+		captured := int(binary.LittleEndian.Uint32(epb.body[12:]))
+		original := int(binary.LittleEndian.Uint32(epb.body[16:]))
+		if int(iface) != want.iface || micros != uint64(ts.UnixMicro()) || captured != len(want.data) || original != len(want.data) {
+			t.Fatalf("packet %d: interface %d, ts %d, lengths %d/%d", i, iface, micros, captured, original)
+		}
+		padded := captured + (4-captured%4)%4
+		data := epb.body[20 : 20+padded]
+		if !bytes.Equal(data[:captured], want.data) || !bytes.Equal(data[captured:], make([]byte, padded-captured)) {
+			t.Fatalf("packet %d data % X", i, data)
+		}
+		options := epb.body[20+padded:]
+		if want.comment == "" {
+			if len(options) != 0 {
+				t.Fatalf("packet %d carries %d byte(s) of options", i, len(options))
+			}
+			continue
+		}
+		if comment := readOptions(t, options)[optComment]; string(comment) != want.comment {
+			t.Fatalf("packet %d comment %q", i, comment)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package tap
+
+import (
+	"errors"
+	"fmt"
+	"parsing-service/pkg/checksum"
+)
+
+// A TAP packet on the wire:
+//
+//	0xAA | src addr (4) | dst addr (4) | src port | dst port | data len | data | crc (2)
+//
+// Addresses and the crc are big-endian. The crc covers everything between the
+// start byte and the crc. By default it is CRC16 XModem zero-padded to an
+// even length; the *With functions take another checksum variant, whose size
+// sets the size of the crc field.
+const (
+	TAP_BODY_HEADER_LEN = TAP_HEADER_LEN - 1 // header without the start byte
+	TAP_OVERHEAD        = TAP_HEADER_LEN + CRC_BYTE_LEN
+	TAP_MAX_DATA_LEN    = 0xFF
+)
+
+var (
+	ErrShortPacket  = errors.New("tap packet too short")
+	ErrStartByte    = errors.New("tap start byte missing")
+	ErrCrcMismatch  = errors.New("tap crc mismatch")
+	ErrDataTooLong  = errors.New("tap data longer than 255 bytes")
+	ErrInvalidField = errors.New("tap field invalid")
+)
+
+// CodecError wraps one of the Err* values above with where and why it
+// happened. Use errors.Is to tell the cases apart.
+type CodecError struct {
+	Err    error
+	Offset int
+	Detail string
+}
+
+func (e *CodecError) Error() string {
+	return fmt.Sprintf("%v at byte %d: %s", e.Err, e.Offset, e.Detail)
+}
+
+func (e *CodecError) Unwrap() error {
+	return e.Err
+}
+
+// ComputeCrc returns the default crc of a packet body, the bytes between the
+// start byte and the crc.
+func ComputeCrc(body []byte) uint16 {
+	return uint16(checksum.FRAME_DEFAULT.Checksum(body))
+}
+
+// Encode returns the wire form of packet, start byte and crc included. The
+// length byte is taken from len(packet.Data).
+func Encode(packet *TAPPacket) ([]byte, error) {
+	return EncodeWith(packet, checksum.FRAME_DEFAULT)
+}
+
+func EncodeWith(packet *TAPPacket, alg *checksum.Algorithm) ([]byte, error) {
+	if len(packet.Data) > TAP_MAX_DATA_LEN {
+		return nil, &CodecError{Err: ErrDataTooLong, Offset: TAP_HEADER_LEN, Detail: fmt.Sprintf("%d bytes of data", len(packet.Data))}
+	}
+	srcAddrNum, err := packet.SrcAddr.DumpToInteger()
+	if err != nil {
+		return nil, &CodecError{Err: ErrInvalidField, Offset: 1, Detail: err.Error()}
+	}
+	destAddrNum, err := packet.DestAddr.DumpToInteger()
+	if err != nil {
+		return nil, &CodecError{Err: ErrInvalidField, Offset: 5, Detail: err.Error()}
+	}
+
+	buf := make([]byte, 0, TAP_HEADER_LEN+len(packet.Data)+alg.Size())
+	buf = append(buf, TAP_START_BYTE)
+	buf = append(buf, byte(srcAddrNum>>24), byte(srcAddrNum>>16), byte(srcAddrNum>>8), byte(srcAddrNum))
+	buf = append(buf, byte(destAddrNum>>24), byte(destAddrNum>>16), byte(destAddrNum>>8), byte(destAddrNum))
+	buf = append(buf, packet.SrcPort, packet.DestPort, byte(len(packet.Data)))
+	buf = append(buf, packet.Data...)
+
+	crc := alg.Checksum(buf[1:])
+	for shift := (alg.Size() - 1) * 8; shift >= 0; shift -= 8 {
+		buf = append(buf, byte(crc>>shift))
+	}
+	return buf, nil
+}
+
+// Decode reads one packet from the start of buf, which must begin with the
+// start byte. Bytes after the crc are ignored; the packet used
+// TAP_OVERHEAD+DataLen of them.
+func Decode(buf []byte) (*TAPPacket, error) {
+	return DecodeWith(buf, checksum.FRAME_DEFAULT)
+}
+
+func DecodeWith(buf []byte, alg *checksum.Algorithm) (*TAPPacket, error) {
+	if len(buf) == 0 {
+		return nil, &CodecError{Err: ErrShortPacket, Offset: 0, Detail: "empty buffer"}
+	}
+	if buf[0] != TAP_START_BYTE {
+		return nil, &CodecError{Err: ErrStartByte, Offset: 0, Detail: fmt.Sprintf("found %X", buf[0])}
+	}
+	return decodeBody(buf[1:], 1, alg)
+}
+
+// ChecksumPicker returns the checksum variants a packet may be closed with,
+// chosen from its header; the data and crc of header are not read yet. A
+// packet is valid when it checks out with any of them, tried in order.
+type ChecksumPicker func(header *TAPPacket) []*checksum.Algorithm
+
+// FixedChecksum picks alg for every packet.
+func FixedChecksum(alg *checksum.Algorithm) ChecksumPicker {
+	return func(*TAPPacket) []*checksum.Algorithm {
+		return []*checksum.Algorithm{alg}
+	}
+}
+
+// DecodePicked is DecodeWith for the variants pick returns for the packet at
+// the start of buf. It also returns the variant the packet checked out with.
+// When none does, the error is the one of the first variant.
+func DecodePicked(buf []byte, pick ChecksumPicker) (*TAPPacket, *checksum.Algorithm, error) {
+	if len(buf) == 0 {
+		return nil, nil, &CodecError{Err: ErrShortPacket, Offset: 0, Detail: "empty buffer"}
+	}
+	if buf[0] != TAP_START_BYTE {
+		return nil, nil, &CodecError{Err: ErrStartByte, Offset: 0, Detail: fmt.Sprintf("found %X", buf[0])}
+	}
+	if len(buf) < TAP_HEADER_LEN {
+		return nil, nil, &CodecError{Err: ErrShortPacket, Offset: 1, Detail: fmt.Sprintf("%d bytes, header needs %d", len(buf)-1, TAP_BODY_HEADER_LEN)}
+	}
+	header := decodeHeader(buf[1:])
+
+	var firstErr error
+	for _, alg := range pick(header) {
+		packet, err := decodeBody(buf[1:], 1, alg)
+		if err == nil {
+			return packet, alg, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		return nil, nil, &CodecError{Err: ErrInvalidField, Offset: 1, Detail: "no checksum variant for the packet"}
+	}
+	return nil, nil, firstErr
+}
+
+// DecodeBody is Decode for a packet whose start byte was already stripped.
+func DecodeBody(body []byte) (*TAPPacket, error) {
+	return decodeBody(body, 0, checksum.FRAME_DEFAULT)
+}
+
+// DecodeUnchecked reads the header and data of a body without a crc, for
+// callers that validated the crc themselves. Data runs to the end of body
+// and DataLen is taken from the header.
+func DecodeUnchecked(body []byte) (*TAPPacket, error) {
+	if len(body) < TAP_BODY_HEADER_LEN {
+		return nil, &CodecError{Err: ErrShortPacket, Offset: 0, Detail: fmt.Sprintf("%d bytes, header needs %d", len(body), TAP_BODY_HEADER_LEN)}
+	}
+	packet := decodeHeader(body)
+	packet.Data = append([]byte(nil), body[TAP_BODY_HEADER_LEN:]...)
+	return packet, nil
+}
+
+// base is the offset of body within the original buffer, used in errors.
+func decodeBody(body []byte, base int, alg *checksum.Algorithm) (*TAPPacket, error) {
+	crcLen := alg.Size()
+	if len(body) < TAP_BODY_HEADER_LEN+crcLen {
+		return nil, &CodecError{Err: ErrShortPacket, Offset: base, Detail: fmt.Sprintf("%d bytes, header and crc need %d", len(body), TAP_BODY_HEADER_LEN+crcLen)}
+	}
+	packet := decodeHeader(body)
+
+	dataEnd := TAP_BODY_HEADER_LEN + int(packet.DataLen)
+	if len(body) < dataEnd+crcLen {
+		return nil, &CodecError{Err: ErrShortPacket, Offset: base + TAP_BODY_HEADER_LEN, Detail: fmt.Sprintf("data length %d needs %d bytes, %d available", packet.DataLen, dataEnd+crcLen, len(body))}
+	}
+
+	if ok, crcInPacket, computed := alg.Verify(body[:dataEnd], body[dataEnd:dataEnd+crcLen]); !ok {
+		return nil, &CodecError{Err: ErrCrcMismatch, Offset: base + dataEnd, Detail: fmt.Sprintf("%s :: %s :: %s packet %X computed %X", packet.SrcAddr, packet.DestAddr, alg.Name, crcInPacket, computed)}
+	}
+
+	packet.Data = append([]byte(nil), body[TAP_BODY_HEADER_LEN:dataEnd]...)
+	return packet, nil
+}
+
+func decodeHeader(body []byte) *TAPPacket {
+	packet := NewTAPPacket()
+	packet.SrcAddr.LoadFromInteger(int(uint32(body[0])<<24 | uint32(body[1])<<16 | uint32(body[2])<<8 | uint32(body[3])))
+	packet.DestAddr.LoadFromInteger(int(uint32(body[4])<<24 | uint32(body[5])<<16 | uint32(body[6])<<8 | uint32(body[7])))
+	packet.SrcPort = body[8]
+	packet.DestPort = body[9]
+	packet.DataLen = body[10]
+	return packet
+}
+
+// CmdID returns the command id a packet answers. Where the destination
+// address carries it depends on the destination port: port 4 uses the middle
+// two octets and every other port the last two octets, except port 219. The
+// octets port 219 carries its command id in are not known; its packets fail
+// with ErrInvalidField, as they always have.
+func (packet *TAPPacket) CmdID() (int, error) {
+	destAddrNum, err := packet.DestAddr.DumpToInteger()
+	if err != nil {
+		return 0, &CodecError{Err: ErrInvalidField, Offset: 5, Detail: err.Error()}
+	}
+
+	switch packet.DestPort {
+	case 4:
+		return int(destAddrNum>>8) & 0xFFFF, nil
+	case 219:
+		return 0, &CodecError{Err: ErrInvalidField, Offset: 5, Detail: "command id octets of destination port 219 unknown"}
+	default:
+		return int(destAddrNum) & 0xFFFF, nil
+	}
+}
@@ -0,0 +1,165 @@
+package tap
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"parsing-service/pkg/checksum"
+)
+
+var codecSentinels = []error{ErrShortPacket, ErrStartByte, ErrCrcMismatch, ErrDataTooLong, ErrInvalidField}
+
+func randomPacket(rng *rand.Rand) *TAPPacket {
+	packet := NewTAPPacket()
+	packet.SrcAddr.LoadFromInteger(int(rng.Uint32()))
+	packet.DestAddr.LoadFromInteger(int(rng.Uint32()))
+	packet.SrcPort = uint8(rng.Intn(256))
+	packet.DestPort = uint8(rng.Intn(256))
+	packet.Data = make([]byte, rng.Intn(TAP_MAX_DATA_LEN+1))
+	rng.Read(packet.Data)
+	return packet
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, alg := range []*checksum.Algorithm{checksum.FRAME_DEFAULT, checksum.CRC16_MODBUS, checksum.CRC32} {
+		for i := 0; i < 500; i++ {
+			packet := randomPacket(rng)
+			buf, err := EncodeWith(packet, alg)
+			if err != nil {
+				t.Fatalf("%s: EncodeWith: %v", alg.Name, err)
+			}
+			if len(buf) != TAP_HEADER_LEN+len(packet.Data)+alg.Size() {
+				t.Fatalf("%s: encoded %d bytes for %d bytes of data", alg.Name, len(buf), len(packet.Data))
+			}
+			// trailing bytes belong to the next packet and are ignored
+			decoded, err := DecodeWith(append(buf, 0xAA, 0x01), alg)
+			if err != nil {
+				t.Fatalf("%s: DecodeWith(%X): %v", alg.Name, buf, err)
+			}
+			if decoded.SrcAddr.String() != packet.SrcAddr.String() || decoded.DestAddr.String() != packet.DestAddr.String() ||
+				decoded.SrcPort != packet.SrcPort || decoded.DestPort != packet.DestPort ||
+				int(decoded.DataLen) != len(packet.Data) || !bytes.Equal(decoded.Data, packet.Data) {
+				t.Fatalf("%s: decoded %+v, want %+v", alg.Name, decoded, packet)
+			}
+		}
+	}
+}
+
+func TestCodecDataTooLong(t *testing.T) {
+	packet := randomPacket(rand.New(rand.NewSource(2)))
+	packet.Data = make([]byte, TAP_MAX_DATA_LEN+1)
+	_, err := Encode(packet)
+	var codecErr *CodecError
+	if !errors.As(err, &codecErr) || !errors.Is(err, ErrDataTooLong) {
+		t.Fatalf("Encode = %v, want ErrDataTooLong", err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	packet := randomPacket(rand.New(rand.NewSource(3)))
+	packet.Data = []byte{1, 2, 3}
+	valid, err := Encode(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	badCrc := append([]byte(nil), valid...)
+	badCrc[len(badCrc)-1] ^= 0xFF
+	badStart := append([]byte(nil), valid...)
+	badStart[0] = 0x55
+
+	for _, tc := range []struct {
+		name   string
+		buf    []byte
+		want   error
+		offset int
+	}{
+		{"empty", nil, ErrShortPacket, 0},
+		{"start byte", badStart, ErrStartByte, 0},
+		{"no header", valid[:TAP_HEADER_LEN], ErrShortPacket, 1},
+		{"short data", valid[:len(valid)-3], ErrShortPacket, TAP_HEADER_LEN},
+		{"no crc", valid[:len(valid)-1], ErrShortPacket, TAP_HEADER_LEN},
+		{"bad crc", badCrc, ErrCrcMismatch, TAP_HEADER_LEN + len(packet.Data)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Decode(tc.buf)
+			var codecErr *CodecError
+			if !errors.As(err, &codecErr) || !errors.Is(err, tc.want) {
+				t.Fatalf("Decode = %v, want %v", err, tc.want)
+			}
+			if codecErr.Offset != tc.offset {
+				t.Fatalf("offset %d, want %d", codecErr.Offset, tc.offset)
+			}
+		})
+	}
+}
+
+func TestCmdID(t *testing.T) {
+	for _, tc := range []struct {
+		destAddr string
+		destPort uint8
+		want     int
+	}{
+		{"10.2.1.4", 4, 0x0201},
+		{"10.2.1.4", 1, 0x0104},
+		{"0.0.255.254", 0, 0xFFFE},
+		{"10.2.1.4", 219, -1},
+	} {
+		packet := NewTAPPacket()
+		packet.DestAddr = NewAddress1(tc.destAddr)
+		packet.DestPort = tc.destPort
+		cmdID, err := packet.CmdID()
+		if tc.want < 0 {
+			if !errors.Is(err, ErrInvalidField) {
+				t.Fatalf("%s port %d: CmdID = %d, %v, want ErrInvalidField", tc.destAddr, tc.destPort, cmdID, err)
+			}
+			continue
+		}
+		if err != nil || cmdID != tc.want {
+			t.Fatalf("%s port %d: CmdID = %X, %v, want %X", tc.destAddr, tc.destPort, cmdID, err, tc.want)
+		}
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	rng := rand.New(rand.NewSource(4))
+	for i := 0; i < 4; i++ {
+		valid, _ := Encode(randomPacket(rng))
+		f.Add(valid)
+		f.Add(valid[:len(valid)/2])
+		f.Add(append([]byte{0x00}, valid...))
+	}
+	f.Add([]byte{})
+	f.Add([]byte{TAP_START_BYTE})
+
+	f.Fuzz(func(t *testing.T, buf []byte) {
+		packet, err := Decode(buf)
+		if err != nil {
+			var codecErr *CodecError
+			if !errors.As(err, &codecErr) {
+				t.Fatalf("Decode(%X) returned %T, want *CodecError", buf, err)
+			}
+			known := false
+			for _, sentinel := range codecSentinels {
+				known = known || errors.Is(err, sentinel)
+			}
+			if !known {
+				t.Fatalf("Decode(%X) = %v, not one of the codec errors", buf, err)
+			}
+			return
+		}
+		if int(packet.DataLen) != len(packet.Data) {
+			t.Fatalf("Decode(%X): DataLen %d with %d bytes of data", buf, packet.DataLen, len(packet.Data))
+		}
+		// a decoded packet encodes back to the bytes it was read from
+		encoded, err := Encode(packet)
+		if err != nil {
+			t.Fatalf("Encode(Decode(%X)): %v", buf, err)
+		}
+		if !bytes.Equal(encoded, buf[:len(encoded)]) {
+			t.Fatalf("Encode(Decode(%X)) = %X", buf, encoded)
+		}
+	})
+}
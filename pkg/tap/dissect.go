@@ -0,0 +1,68 @@
+package tap
+
+import (
+	"fmt"
+	"parsing-service/pkg/checksum"
+	"parsing-service/pkg/dissect"
+)
+
+// Dissect breaks the packet at the start of buf into a field tree, checking
+// the crc with alg. fields, if given, are dissected out of the data.
+// Dissect never fails; whatever is wrong shows up as invalid nodes.
+func Dissect(buf []byte, alg *checksum.Algorithm, fields ...dissect.PayloadField) *dissect.Node {
+	dataLen := 0
+	if len(buf) > TAP_HEADER_LEN-1 {
+		dataLen = int(buf[TAP_HEADER_LEN-1])
+	}
+	dataEnd := TAP_HEADER_LEN + dataLen
+	root := dissect.NewNode("tap packet", buf, 0, dataEnd+alg.Size())
+
+	startByte := dissect.NewNode("startByte", buf, 0, 1)
+	if startByte.Complete() {
+		startByte.SetValue(buf[0])
+		if buf[0] != TAP_START_BYTE {
+			startByte.Invalidate(fmt.Sprintf("expected %X", TAP_START_BYTE))
+		}
+	}
+
+	srcAddr := dissect.NewNode("srcAddr", buf, 1, 4)
+	destAddr := dissect.NewNode("destAddr", buf, 5, 4)
+	for _, node := range []*dissect.Node{srcAddr, destAddr} {
+		if node.Complete() {
+			raw := buf[node.Offset : node.Offset+4]
+			node.SetValue(fmt.Sprintf("%d.%d.%d.%d", raw[0], raw[1], raw[2], raw[3]))
+		}
+	}
+
+	root.Add(startByte, srcAddr, destAddr,
+		byteNode("srcPort", buf, 9),
+		byteNode("destPort", buf, 10),
+		byteNode("dataLen", buf, 11),
+	)
+
+	data := dissect.NewNode("data", buf, TAP_HEADER_LEN, dataLen)
+	if data.Complete() && len(fields) > 0 {
+		data.Add(dissect.PayloadNodes(buf[TAP_HEADER_LEN:dataEnd], TAP_HEADER_LEN, fields)...)
+	}
+	root.Add(data)
+
+	crc := dissect.NewNode("crc", buf, dataEnd, alg.Size())
+	if crc.Complete() {
+		ok, inPacket, computed := alg.Verify(buf[1:dataEnd], buf[dataEnd:dataEnd+alg.Size()])
+		crc.SetValue(fmt.Sprintf("%X", inPacket))
+		if !ok {
+			crc.Invalidate(fmt.Sprintf("%s computed %X", alg.Name, computed))
+		}
+	}
+	root.Add(crc)
+
+	return root
+}
+
+func byteNode(name string, buf []byte, offset int) *dissect.Node {
+	node := dissect.NewNode(name, buf, offset, 1)
+	if node.Complete() {
+		node.SetValue(buf[offset])
+	}
+	return node
+}
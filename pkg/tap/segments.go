@@ -0,0 +1,181 @@
+package tap
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The data of every packet of a segmented response is expected to start with
+// a two byte segment header: the 1-based sequence number of the packet and the
+// total number of packets of the response. This layout is not taken from a
+// meter protocol document, none is available to this service; it is what the
+// reassembler expects. Reassembly therefore runs only for the commands listed
+// in TAP_SEGMENTED_CMD_IDS, which is empty by default, and a command should
+// be listed only once its responses are known to carry this header.
+const (
+	SEGMENT_HEADER_LEN    = 2
+	SEGMENT_SEQ_OFFSET    = 0
+	SEGMENT_TOTAL_OFFSET  = 1
+	SEGMENT_DROP_TIMEOUT  = "timed out"
+	SEGMENT_DROP_RESTART  = "superseded by a new response"
+	SEGMENT_DROP_CAPACITY = "too many pending responses"
+)
+
+// SegmentKey identifies one response in flight: a meter answers one command
+// at a time, so its ip and the command id single out the packets that belong
+// together.
+type SegmentKey struct {
+	MeterIp string
+	CmdID   int
+}
+
+// IncompleteSegmentSet is a response that was given up on before all of its
+// packets arrived. Segments holds the data of the received packets, without
+// their segment headers, ordered by sequence number.
+type IncompleteSegmentSet struct {
+	Key       SegmentKey
+	Total     int
+	Received  []int
+	Segments  [][]byte
+	FirstSeen time.Time
+	Reason    string
+}
+
+type segmentSet struct {
+	total     int
+	segments  map[int][]byte
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// SegmentReassembler joins the packets of segmented responses back into one
+// payload. Packets may arrive in any order and retransmitted packets replace
+// the earlier copy. A response that stops receiving packets for longer than
+// the timeout, or that is evicted to stay within maxPending responses, is
+// handed back as an IncompleteSegmentSet.
+type SegmentReassembler struct {
+	mu         sync.Mutex
+	pending    map[SegmentKey]*segmentSet
+	segmented  map[int]bool
+	timeout    time.Duration
+	maxPending int
+}
+
+func NewSegmentReassembler(segmentedCmdIDs []int, timeout time.Duration, maxPending int) *SegmentReassembler {
+	segmented := make(map[int]bool, len(segmentedCmdIDs))
+	for _, cmdID := range segmentedCmdIDs {
+		segmented[cmdID] = true
+	}
+	return &SegmentReassembler{
+		pending:    make(map[SegmentKey]*segmentSet),
+		segmented:  segmented,
+		timeout:    timeout,
+		maxPending: maxPending,
+	}
+}
+
+// IsSegmented reports whether responses to cmdID carry a segment header.
+func (r *SegmentReassembler) IsSegmented(cmdID int) bool {
+	return r.segmented[cmdID]
+}
+
+// Add stores the data of one packet. Once the last missing packet of a
+// response arrives the joined payload is returned with complete set. Responses
+// dropped to make room for this packet are returned as well.
+func (r *SegmentReassembler) Add(key SegmentKey, data []byte, now time.Time) (payload []byte, complete bool, dropped []IncompleteSegmentSet, err error) {
+	if len(data) < SEGMENT_HEADER_LEN {
+		return nil, false, nil, fmt.Errorf("segment of %v carries %d byte(s), shorter than the segment header", key, len(data))
+	}
+	seq := int(data[SEGMENT_SEQ_OFFSET])
+	total := int(data[SEGMENT_TOTAL_OFFSET])
+	if total == 0 || seq == 0 || seq > total {
+		return nil, false, nil, fmt.Errorf("segment of %v has invalid sequence %d of %d", key, seq, total)
+	}
+	body := append([]byte(nil), data[SEGMENT_HEADER_LEN:]...)
+
+	if total == 1 {
+		return body, true, nil, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	set, ok := r.pending[key]
+	if ok && set.total != total {
+		// the meter started over with a different response to the same command
+		dropped = append(dropped, set.incomplete(key, SEGMENT_DROP_RESTART))
+		delete(r.pending, key)
+		ok = false
+	}
+	if !ok {
+		if r.maxPending > 0 && len(r.pending) >= r.maxPending {
+			dropped = append(dropped, r.evictOldest())
+		}
+		set = &segmentSet{total: total, segments: make(map[int][]byte), firstSeen: now}
+		r.pending[key] = set
+	}
+	set.segments[seq] = body
+	set.lastSeen = now
+
+	if len(set.segments) < set.total {
+		return nil, false, dropped, nil
+	}
+
+	delete(r.pending, key)
+	for i := 1; i <= set.total; i++ {
+		payload = append(payload, set.segments[i]...)
+	}
+	return payload, true, dropped, nil
+}
+
+// Expire drops and returns every response that has not received a packet
+// within the timeout.
+func (r *SegmentReassembler) Expire(now time.Time) []IncompleteSegmentSet {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired []IncompleteSegmentSet
+	for key, set := range r.pending {
+		if now.Sub(set.lastSeen) > r.timeout {
+			expired = append(expired, set.incomplete(key, SEGMENT_DROP_TIMEOUT))
+			delete(r.pending, key)
+		}
+	}
+	return expired
+}
+
+func (r *SegmentReassembler) evictOldest() IncompleteSegmentSet {
+	var oldestKey SegmentKey
+	var oldest *segmentSet
+	for key, set := range r.pending {
+		if oldest == nil || set.lastSeen.Before(oldest.lastSeen) {
+			oldestKey, oldest = key, set
+		}
+	}
+	delete(r.pending, oldestKey)
+	return oldest.incomplete(oldestKey, SEGMENT_DROP_CAPACITY)
+}
+
+func (s *segmentSet) incomplete(key SegmentKey, reason string) IncompleteSegmentSet {
+	received := make([]int, 0, len(s.segments))
+	for seq := range s.segments {
+		received = append(received, seq)
+	}
+	sort.Ints(received)
+
+	segments := make([][]byte, 0, len(received))
+	for _, seq := range received {
+		segments = append(segments, s.segments[seq])
+	}
+
+	return IncompleteSegmentSet{
+		Key:       key,
+		Total:     s.total,
+		Received:  received,
+		Segments:  segments,
+		FirstSeen: s.firstSeen,
+		Reason:    reason,
+	}
+}
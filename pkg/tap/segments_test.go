@@ -0,0 +1,68 @@
+package tap
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func segment(seq, total byte, body ...byte) []byte {
+	return append([]byte{seq, total}, body...)
+}
+
+func TestSegmentsOutOfOrder(t *testing.T) {
+	r := NewSegmentReassembler([]int{300}, time.Minute, 0)
+	key := SegmentKey{MeterIp: "10.0.0.1", CmdID: 300}
+	now := time.Now()
+
+	for _, data := range [][]byte{segment(3, 3, 'e', 'f'), segment(1, 3, 'a', 'b')} {
+		if _, complete, _, err := r.Add(key, data, now); complete || err != nil {
+			t.Fatalf("Add(%X) = complete %v err %v, want pending", data, complete, err)
+		}
+	}
+	payload, complete, _, err := r.Add(key, segment(2, 3, 'c', 'd'), now)
+	if err != nil || !complete {
+		t.Fatalf("last segment: complete %v err %v", complete, err)
+	}
+	if !bytes.Equal(payload, []byte("abcdef")) {
+		t.Fatalf("payload = %q, want the segments in sequence order", payload)
+	}
+}
+
+func TestSegmentsDuplicate(t *testing.T) {
+	r := NewSegmentReassembler([]int{300}, time.Minute, 0)
+	key := SegmentKey{MeterIp: "10.0.0.1", CmdID: 300}
+	now := time.Now()
+
+	r.Add(key, segment(1, 2, 'x'), now)
+	// a retransmission neither completes the response nor adds a segment
+	if _, complete, _, _ := r.Add(key, segment(1, 2, 'a'), now); complete {
+		t.Fatal("a duplicate segment completed the response")
+	}
+	payload, complete, _, _ := r.Add(key, segment(2, 2, 'b'), now)
+	if !complete || !bytes.Equal(payload, []byte("ab")) {
+		t.Fatalf("payload = %q complete %v, want the retransmitted copy", payload, complete)
+	}
+}
+
+func TestSegmentsTimeout(t *testing.T) {
+	r := NewSegmentReassembler([]int{300}, time.Minute, 0)
+	key := SegmentKey{MeterIp: "10.0.0.1", CmdID: 300}
+	start := time.Now()
+
+	r.Add(key, segment(2, 3, 'c'), start)
+	if expired := r.Expire(start.Add(time.Minute)); len(expired) != 0 {
+		t.Fatalf("expired at the timeout: %+v", expired)
+	}
+	expired := r.Expire(start.Add(time.Minute + time.Second))
+	if len(expired) != 1 {
+		t.Fatalf("got %d expired sets, want 1", len(expired))
+	}
+	set := expired[0]
+	if set.Key != key || set.Total != 3 || len(set.Received) != 1 || set.Received[0] != 2 || set.Reason != SEGMENT_DROP_TIMEOUT {
+		t.Fatalf("expired set = %+v", set)
+	}
+	if _, complete, _, _ := r.Add(key, segment(1, 3, 'a'), start.Add(2*time.Minute)); complete {
+		t.Fatal("an expired set was completed")
+	}
+}
@@ -1,8 +1,7 @@
 package tap
 
 import (
-	// "errors"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -19,30 +18,6 @@ const (
 	DCU_TIME_BYTE_LEN = 4
 )
 
-// Polynomial: The polynomial used for this CRC calculation. For CRC-16-CCITT, it's 0x1021.
-const poly uint16 = 0x1021
-
-// Initial value for the CRC calculation. Can be changed based on the specific CRC-16 variant.
-// const initial uint16 = 0xFFFF
-const initial uint16 = 0x0000
-
-// crc16 calculates the CRC-16 checksum for a given byte array using the specified polynomial and initial value.
-func crc16(data []byte) uint16 {
-	// log.Println(" IN_CRC_FUN bytes came ", data)
-	crc := initial
-	for _, b := range data {
-		crc ^= uint16(b) << 8
-		for i := 0; i < 8; i++ {
-			if crc&0x8000 != 0 {
-				crc = (crc << 1) ^ poly
-			} else {
-				crc <<= 1
-			}
-		}
-	}
-	return crc
-}
-
 // SerializeValueError represents an error when a value cannot be serialized.
 type SerializeValueError struct {
 	Value    int
@@ -368,126 +343,40 @@ func (packet *TAPPacket) String() string {
 		packet.SrcAddr.addressString, packet.DestAddr.addressString, packet.SrcPort, packet.DestPort, packet.DataLen, packet.Data)
 }
 
+// Serialize is Encode for callers that have no use for the error; a packet
+// that cannot be encoded serializes to nil.
 func (packet *TAPPacket) Serialize() []byte {
-	srcAddrNum, err := packet.SrcAddr.DumpToInteger()
+	buf, err := Encode(packet)
 	if err != nil {
 		fmt.Println("Error:", err)
+		return nil
 	}
-	destAddrNum, err := packet.DestAddr.DumpToInteger()
-	if err != nil {
-		fmt.Println("Error:", err)
-	}
-
-	buf := make([]byte, 0)
-	buf = append(buf, 170)
-	buf = append(buf, byte((srcAddrNum&0xFF000000)>>24))
-	buf = append(buf, byte((srcAddrNum&0x00FF0000)>>16))
-	buf = append(buf, byte((srcAddrNum&0x0000FF00)>>8))
-	buf = append(buf, byte(srcAddrNum&0x000000FF))
-	buf = append(buf, byte((destAddrNum&0xFF000000)>>24))
-	buf = append(buf, byte((destAddrNum&0x00FF0000)>>16))
-	buf = append(buf, byte((destAddrNum&0x0000FF00)>>8))
-	buf = append(buf, byte(destAddrNum&0x000000FF))
-	buf = append(buf, packet.SrcPort)
-	buf = append(buf, packet.DestPort)
-	buf = append(buf, packet.DataLen)
-	buf = append(buf, packet.Data...)
-	var crcbytes1 []byte
-	crcbytes1 = buf[1:]
-
-	if len(buf[1:])%2 != 0 {
-		crcbytes1 = append(crcbytes1, 0x00)
-	}
-
-	crc := crc16(crcbytes1)
-	buf = append(buf, byte(crc>>8), byte(crc&0xFF))
-
 	return buf
 }
 
+// Deserialize decodes a packet whose start byte was already stripped into
+// packet1, see DecodeBody.
 func (packet1 *TAPPacket) Deserialize(buf []byte) error {
-	// fmt.Println("Step-1 Bytes for Deserialize are", buf, "length", len(buf))
-	// log.Printf("TAP Decoding Packet Payload %x ",buf)
-
-	if len(buf) < 13 {
-		return fmt.Errorf("buffer length is less than expected")
-	}
-	srcAddrNum := (uint32(buf[0]) << 24) | (uint32(buf[1]) << 16) | (uint32(buf[2]) << 8) | uint32(buf[3])
-	destAddrNum := (uint32(buf[4]) << 24) | (uint32(buf[5]) << 16) | (uint32(buf[6]) << 8) | uint32(buf[7])
-	var err error
-	packet1.SrcAddr, err = NewAddress("0.0.0.0")
+	packet, err := DecodeBody(buf)
 	if err != nil {
-		fmt.Println("Error:", err)
-		return err
-	}
-	if err := packet1.SrcAddr.LoadFromInteger(int(srcAddrNum)); err != nil {
-		return err
-	}
-
-	packet1.DestAddr, err = NewAddress("0.0.0.0")
-	if err != nil {
-		fmt.Println("Error:", err)
-		return err
-	}
-
-	if err := packet1.DestAddr.LoadFromInteger(int(destAddrNum)); err != nil {
 		return err
 	}
-	// fmt.Println("Step-1 SP", buf[8], "DP", buf[9])
-
-	packet1.SrcPort = buf[8]
-	packet1.DestPort = buf[9]
-	// fmt.Println("Step-1 Payload Len", buf[10], " Data buf[11:]", buf[11:])
-
-	packet1.DataLen = buf[10]
-	// Extract the last 4 bytes from buf
-	last4Bytes := buf[len(buf)-4:]
-
-	// packet1.DcuNo = binary.BigEndian.Uint32(buf[13+packet1.DataLen : 17+packet1.DataLen])
-	DcuNo := uint32(last4Bytes[0])<<24 | uint32(last4Bytes[1])<<16 | uint32(last4Bytes[2])<<8 | uint32(last4Bytes[3])
-	fmt.Printf("DCU No %d", DcuNo)
-	if uint8(len(buf)-16) > packet1.DataLen {
-		packet1.Data = buf[11 : 11+packet1.DataLen]
-		// fmt.Println("Packet Data ", packet1.Data)
-		crcInPacket := binary.BigEndian.Uint16(buf[11+packet1.DataLen : 13+packet1.DataLen])
-		var crcbytes []byte
-		crcbytes = buf[:11+packet1.DataLen]
-
-		if len(buf[:11+packet1.DataLen])%2 != 0 {
-			crcbytes = append(crcbytes, 0x00)
-		}
-		crcComputed := crc16(crcbytes)
-
-		cb := make([]byte, 2)
-		binary.BigEndian.PutUint16(cb, uint16(crcComputed))
-
-		// fmt.Println("CRC Computed Bytes for  ", buf[:11+packet1.DataLen], " computed CRC ", crcComputed, " bytes are ", cb)
-		if crcComputed != crcInPacket {
-			fmt.Println("crcComputed ", crcComputed, "crcInPacket", crcInPacket, "bytes ", buf[11+packet1.DataLen:13+packet1.DataLen])
-			return fmt.Errorf("%s :: %s :: CRC is not correct buf %x", packet1.SrcAddr.addressString, packet1.DestAddr.addressString, buf)
-		}
-		// log.Printf("CRC Matched ")
-		// log.Println("PERFECT  Packet Payload ",packet1.DcuNo)
-
-		return nil
-	} else {
-		if !(uint8(len(buf)-16) > packet1.DataLen) || !(uint8(len(buf)-13) > packet1.DataLen) {
-			log.Println("WRONG  Packet Payload ", DcuNo, "length", len(buf))
-			return nil
-		}
-		return nil
-	}
-
+	*packet1 = *packet
+	return nil
 }
 
 func TapDecode(buffer []byte) TAPPacket {
 	// log.Println(strings.Repeat("*", 80))
 	// log.Printf("Buffer Data: %v\n", buffer)
 	// log.Printf("Coming Buffer: % X\n", buffer)
-	startByte := buffer[0]
 	var tapPacket TAPPacket
+	if len(buffer) == 0 {
+		log.Printf("Empty buffer\n")
+		return tapPacket
+	}
+	startByte := buffer[0]
 	if startByte != TAP_START_BYTE {
-		if !(buffer[18] == TAP_START_BYTE && buffer[16] == 16) {
+		if !(len(buffer) > 18 && buffer[18] == TAP_START_BYTE && buffer[16] == 16) {
 			log.Printf("Start Byte is not correct: %v\n", startByte)
 			// log.Printf("Buffer: % X\n", buffer)
 			return tapPacket
@@ -502,6 +391,26 @@ func TapDecode(buffer []byte) TAPPacket {
 	return tapPacket
 }
 
+// ErrBlockLoadLength is returned for block-load payloads too short for the
+// layout their length selects.
+var ErrBlockLoadLength = errors.New("block load payload length matches no layout")
+
+// blockLoadLengthOK reports whether a payload of n bytes holds every field
+// Deserialize reads for its length.
+func blockLoadLengthOK(n int) bool {
+	switch {
+	case n < 28:
+		return false
+	case n > 38 && n < 44:
+		return false
+	case n > 44 && n < 48:
+		return false
+	case n > 70 && n < 85:
+		return false
+	}
+	return true
+}
+
 type BlockLoadParser struct {
 	MeterIp                    string  `json:"meter_ip"`
 	MeterNumber                string  `json:"meter_number"`
@@ -554,10 +463,13 @@ func convertBitMaskCode(bitMaskPktLen float64, bitMask []byte, reverse bool) str
 	return bitMaskString
 }
 
-func (blp *BlockLoadParser) Deserialize(payload []byte, FromPush bool, dcu_no uint32, meter_ip string) {
+// Deserialize fills blp from a block-load or daily profile payload. The
+// readings are not sanity checked here; the validation rules run on them
+// after decoding.
+func (blp *BlockLoadParser) Deserialize(payload []byte, FromPush bool, dcu_no uint32, meter_ip string) error {
 	payloadLen := len(payload)
-	if payloadLen == 0 {
-		return
+	if !blockLoadLengthOK(payloadLen) {
+		return fmt.Errorf("%w: %d byte(s)", ErrBlockLoadLength, payloadLen)
 	}
 
 	unixStrTime := DeserializeUInt32(payload[0:4], "reverse")
@@ -628,11 +540,7 @@ func (blp *BlockLoadParser) Deserialize(payload []byte, FromPush bool, dcu_no ui
 		blp.DailyCummApparentEnergyExp = float64(DeserializeUInt32(payload[77:81], "reverse")) / 100.0
 		blp.DailyTemperature = float64(DeserializeInt32(payload[81:85], "reverse")) / 1000.0
 	}
-
-	if blp.ImportWh > 14400 || blp.ImportVah > 14400 || blp.AvgVoltage > 400 || blp.AvgCurrent > 70 {
-		errMsg := fmt.Sprintf("Corrupt Value for Meter %s buf %x, Blockload crossing the threshold ImportWh %f :: ImportVah %f :: AvgVoltage %f :: AvgCurrent%f", blp.MeterIp, payload, blp.ImportWh, blp.ImportVah, blp.AvgVoltage, blp.AvgCurrent)
-		log.Printf(errMsg)
-	}
+	return nil
 }
 
 // func main() {
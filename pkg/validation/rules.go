@@ -0,0 +1,296 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// rule kinds
+const (
+	KIND_RANGE     = "range"
+	KIND_RATE      = "rate"
+	KIND_MONOTONIC = "monotonic"
+	KIND_TIMESTAMP = "timestamp"
+)
+
+// Rule is one check run on decoded readings. Range rules bound Field by Min
+// and Max, either of which may be unset. Rate rules bound the change of Field
+// per hour against the meter's last accepted reading, and monotonic rules
+// reject a cumulative register going down; both skip zero values, which
+// layouts without the register report. Timestamp rules reject readings
+// stamped more than MaxFuture ahead of now or more than MaxAge ago.
+//
+// A rule applies to meters of the listed Categories and firmware Groups,
+// to all of them when a list is empty.
+type Rule struct {
+	ID         string
+	Kind       string
+	Field      string
+	Min        *float64
+	Max        *float64
+	MaxPerHour float64
+	MaxFuture  time.Duration
+	MaxAge     time.Duration
+	Categories []string
+	Groups     []int
+}
+
+// Reading is a decoded record as the rules see it: its numeric fields by
+// name and the time it was taken.
+type Reading struct {
+	MeterIp  string
+	Category string
+	GroupID  int
+	At       time.Time
+	Values   map[string]float64
+}
+
+// Violation is a failed rule.
+type Violation struct {
+	RuleID  string   `json:"rule_id"`
+	Kind    string   `json:"kind"`
+	Field   string   `json:"field,omitempty"`
+	Value   *float64 `json:"value,omitempty"`
+	Message string   `json:"message"`
+}
+
+func (rule *Rule) appliesTo(reading Reading) bool {
+	if len(rule.Categories) > 0 {
+		found := false
+		for _, category := range rule.Categories {
+			found = found || category == reading.Category
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(rule.Groups) > 0 {
+		found := false
+		for _, groupID := range rule.Groups {
+			found = found || groupID == reading.GroupID
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type sample struct {
+	value float64
+	at    time.Time
+}
+
+// BaselineReset keeps a meter from being quarantined for good once its
+// baseline no longer fits it, after a register reset or a meter swap. The
+// baseline is replaced by the meter's latest reading after AfterFailures
+// readings in a row failed a rate or monotonic rule, and dropped once it was
+// last moved more than MaxAge ago. 0 disables either.
+type BaselineReset struct {
+	AfterFailures int
+	MaxAge        time.Duration
+}
+
+// baseline is the last accepted value of every field of a meter.
+type baseline struct {
+	fields    map[string]sample
+	updatedAt time.Time
+	// readings failed in a row since
+	failures int
+}
+
+// Engine runs rules on readings. Rate and monotonic rules compare with the
+// last reading of the meter that passed every rule, so a rejected spike does
+// not become the baseline of the next one; reset says when a baseline that
+// keeps rejecting readings is given up.
+type Engine struct {
+	rules []Rule
+	reset BaselineReset
+
+	mu sync.Mutex
+	// meter ip to its baseline
+	accepted map[string]*baseline
+}
+
+func NewEngine(rules []Rule, reset BaselineReset) *Engine {
+	return &Engine{rules: rules, reset: reset, accepted: make(map[string]*baseline)}
+}
+
+// Validate returns the rules reading fails, in rule order.
+func (e *Engine) Validate(reading Reading, now time.Time) []Violation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	last := e.accepted[reading.MeterIp]
+	if last != nil && e.reset.MaxAge > 0 && now.Sub(last.updatedAt) > e.reset.MaxAge {
+		delete(e.accepted, reading.MeterIp)
+		last = nil
+	}
+	var previous map[string]sample
+	if last != nil {
+		previous = last.fields
+	}
+
+	var violations []Violation
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if !rule.appliesTo(reading) {
+			continue
+		}
+		if violation := rule.check(reading, previous, now); violation != nil {
+			violations = append(violations, *violation)
+		}
+	}
+	if len(violations) > 0 {
+		if last == nil || !againstBaseline(violations) {
+			return violations
+		}
+		last.failures++
+		if e.reset.AfterFailures > 0 && last.failures >= e.reset.AfterFailures {
+			// the reading is still rejected, the next one is checked against it
+			e.accepted[reading.MeterIp] = &baseline{fields: reading.samples(), updatedAt: now}
+		}
+		return violations
+	}
+
+	if last == nil {
+		last = &baseline{fields: make(map[string]sample)}
+		e.accepted[reading.MeterIp] = last
+	}
+	last.updatedAt = now
+	last.failures = 0
+	previous = last.fields
+	for field, value := range reading.Values {
+		if value == 0 {
+			continue
+		}
+		if known, ok := previous[field]; !ok || !reading.At.Before(known.at) {
+			previous[field] = sample{value: value, at: reading.At}
+		}
+	}
+	return nil
+}
+
+// againstBaseline reports whether any of violations is of a rule comparing
+// with the baseline.
+func againstBaseline(violations []Violation) bool {
+	for _, violation := range violations {
+		if violation.Kind == KIND_RATE || violation.Kind == KIND_MONOTONIC {
+			return true
+		}
+	}
+	return false
+}
+
+// samples returns the non-zero values of reading, as a baseline keeps them.
+func (reading Reading) samples() map[string]sample {
+	fields := make(map[string]sample, len(reading.Values))
+	for field, value := range reading.Values {
+		if value != 0 {
+			fields[field] = sample{value: value, at: reading.At}
+		}
+	}
+	return fields
+}
+
+func (rule *Rule) check(reading Reading, previous map[string]sample, now time.Time) *Violation {
+	violation := func(value *float64, format string, args ...interface{}) *Violation {
+		return &Violation{RuleID: rule.ID, Kind: rule.Kind, Field: rule.Field, Value: value, Message: fmt.Sprintf(format, args...)}
+	}
+
+	if rule.Kind == KIND_TIMESTAMP {
+		switch {
+		case reading.At.IsZero():
+			return violation(nil, "reading has no timestamp")
+		case rule.MaxFuture > 0 && reading.At.Sub(now) > rule.MaxFuture:
+			return violation(nil, "timestamp %s is more than %s ahead", reading.At.Format(time.RFC3339), rule.MaxFuture)
+		case rule.MaxAge > 0 && now.Sub(reading.At) > rule.MaxAge:
+			return violation(nil, "timestamp %s is more than %s old", reading.At.Format(time.RFC3339), rule.MaxAge)
+		}
+		return nil
+	}
+
+	value, ok := reading.Values[rule.Field]
+	if !ok {
+		return nil
+	}
+	switch rule.Kind {
+	case KIND_RANGE:
+		if rule.Min != nil && value < *rule.Min {
+			return violation(&value, "%s %v below %v", rule.Field, value, *rule.Min)
+		}
+		if rule.Max != nil && value > *rule.Max {
+			return violation(&value, "%s %v above %v", rule.Field, value, *rule.Max)
+		}
+	case KIND_RATE:
+		last, ok := previous[rule.Field]
+		hours := reading.At.Sub(last.at).Hours()
+		if !ok || value == 0 || hours <= 0 {
+			return nil
+		}
+		if rate := math.Abs(value-last.value) / hours; rate > rule.MaxPerHour {
+			return violation(&value, "%s changed by %.3f per hour since %v, limit %v", rule.Field, rate, last.value, rule.MaxPerHour)
+		}
+	case KIND_MONOTONIC:
+		last, ok := previous[rule.Field]
+		if !ok || value == 0 || !reading.At.After(last.at) {
+			return nil
+		}
+		if value < last.value {
+			return violation(&value, "%s went down from %v", rule.Field, last.value)
+		}
+	}
+	return nil
+}
+
+// Categories assigns meters a category by IP address or CIDR block. Single
+// addresses win over blocks; among blocks the first listed matching one
+// wins.
+type Categories struct {
+	byIp     map[string]string
+	networks []*net.IPNet
+	names    []string
+}
+
+// CategoryEntry assigns Category to Meters, an IP address or CIDR block.
+type CategoryEntry struct {
+	Meters   string
+	Category string
+}
+
+func NewCategories(entries []CategoryEntry) (*Categories, error) {
+	categories := &Categories{byIp: make(map[string]string)}
+	for _, entry := range entries {
+		if ip := net.ParseIP(entry.Meters); ip != nil {
+			categories.byIp[ip.String()] = entry.Category
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry.Meters)
+		if err != nil {
+			return nil, fmt.Errorf("meter category %q: %w", entry.Meters, err)
+		}
+		categories.networks = append(categories.networks, network)
+		categories.names = append(categories.names, entry.Category)
+	}
+	return categories, nil
+}
+
+// Of returns the category of a meter, empty when none is assigned.
+func (c *Categories) Of(meterIp string) string {
+	ip := net.ParseIP(meterIp)
+	if ip == nil {
+		return ""
+	}
+	if category, ok := c.byIp[ip.String()]; ok {
+		return category
+	}
+	for i, network := range c.networks {
+		if network.Contains(ip) {
+			return c.names[i]
+		}
+	}
+	return ""
+}
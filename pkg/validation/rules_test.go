@@ -0,0 +1,88 @@
+package validation
+
+import (
+	"testing"
+	"time"
+)
+
+func monotonicEngine(reset BaselineReset) *Engine {
+	return NewEngine([]Rule{{ID: "import_monotonic", Kind: KIND_MONOTONIC, Field: "import_Wh"}}, reset)
+}
+
+func importReading(at time.Time, importWh float64) Reading {
+	return Reading{MeterIp: "10.0.0.1", At: at, Values: map[string]float64{"import_Wh": importWh}}
+}
+
+func TestBaselineResetAfterFailures(t *testing.T) {
+	e := monotonicEngine(BaselineReset{AfterFailures: 3})
+	start := time.Now()
+	if v := e.Validate(importReading(start, 5000), start); len(v) != 0 {
+		t.Fatalf("first reading rejected: %+v", v)
+	}
+
+	// the register was reset, every reading is below the old baseline
+	for i := 1; i <= 3; i++ {
+		at := start.Add(time.Duration(i) * time.Hour)
+		if v := e.Validate(importReading(at, float64(10*i)), at); len(v) != 1 {
+			t.Fatalf("reading %d after the reset passed", i)
+		}
+	}
+	at := start.Add(4 * time.Hour)
+	if v := e.Validate(importReading(at, 40), at); len(v) != 0 {
+		t.Fatalf("reading after %d failures still rejected: %+v", 3, v)
+	}
+}
+
+func TestBaselineFailuresCountInARow(t *testing.T) {
+	e := monotonicEngine(BaselineReset{AfterFailures: 2})
+	start := time.Now()
+	e.Validate(importReading(start, 5000), start)
+
+	steps := []struct {
+		value  float64
+		reject bool
+	}{
+		{10, true},
+		{5100, false},
+		// the pass above started the count again
+		{20, true},
+	}
+	for i, step := range steps {
+		at := start.Add(time.Duration(i+1) * time.Hour)
+		if v := e.Validate(importReading(at, step.value), at); (len(v) > 0) != step.reject {
+			t.Fatalf("step %d: violations %+v, want rejected %v", i, v, step.reject)
+		}
+	}
+}
+
+func TestBaselineMaxAge(t *testing.T) {
+	e := monotonicEngine(BaselineReset{MaxAge: 24 * time.Hour})
+	start := time.Now()
+	e.Validate(importReading(start, 5000), start)
+
+	at := start.Add(time.Hour)
+	if v := e.Validate(importReading(at, 10), at); len(v) != 1 {
+		t.Fatal("a fresh baseline was dropped")
+	}
+	at = start.Add(25 * time.Hour)
+	if v := e.Validate(importReading(at, 10), at); len(v) != 0 {
+		t.Fatalf("a stale baseline was kept: %+v", v)
+	}
+}
+
+func TestGroupScopedRules(t *testing.T) {
+	max := 270.0
+	e := NewEngine([]Rule{{ID: "voltage_range", Kind: KIND_RANGE, Field: "avg_voltage", Max: &max, Groups: []int{3}}}, BaselineReset{})
+	now := time.Now()
+	reading := Reading{MeterIp: "10.0.0.1", At: now, Values: map[string]float64{"avg_voltage": 300}}
+
+	for _, tt := range []struct {
+		groupID int
+		reject  bool
+	}{{3, true}, {4, false}, {0, false}} {
+		reading.GroupID = tt.groupID
+		if v := e.Validate(reading, now); (len(v) > 0) != tt.reject {
+			t.Fatalf("group %d: violations %+v, want rejected %v", tt.groupID, v, tt.reject)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package wp
+
+import "parsing-service/pkg/checksum"
+
+// OutgoingMessage is a sub-message to send to a DCU: the bytes following its
+// protocol version and message type.
+type OutgoingMessage struct {
+	ProtocolVersion uint8
+	Type            MessageType
+	Body            []byte
+}
+
+// Build frames messages for sink sinkID of DCU dcuNumber the way the DCU
+// frames its own: header, sub-messages, and the trailer with dcuTime and a
+// crc computed by alg. The packet length field holds the length of the whole
+// frame, as assumed in the header layout.
+func Build(sinkID uint8, dcuNumber uint32, dcuTime uint32, alg *checksum.Algorithm, messages ...OutgoingMessage) []byte {
+	length := headerLen + trailerLen
+	for _, message := range messages {
+		length += messageTypeField.end() + len(message.Body)
+	}
+
+	data := make([]byte, 0, length)
+	data = append(data, START_BYTE)
+	data = appendUint(data, uint32(length), packetLenField.Size)
+	data = append(data, sinkID)
+	for _, message := range messages {
+		data = append(data, message.ProtocolVersion, byte(message.Type))
+		data = append(data, message.Body...)
+	}
+	data = appendUint(data, dcuTime, dcuTimeField.Size)
+	data = appendUint(data, dcuNumber, dcuNumberField.Size)
+	return appendUint(data, alg.Checksum(data), crcField.Size)
+}
+
+// appendUint appends the size low bytes of value, little-endian.
+func appendUint(data []byte, value uint32, size int) []byte {
+	for i := 0; i < size; i++ {
+		data = append(data, byte(value>>(8*i)))
+	}
+	return data
+}
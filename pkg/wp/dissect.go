@@ -0,0 +1,118 @@
+package wp
+
+import (
+	"fmt"
+	"parsing-service/pkg/checksum"
+	"parsing-service/pkg/dissect"
+	"sort"
+)
+
+// PayloadDissector dissects the payload of an uplink, which starts at base
+// within the frame. It returns nil to leave the payload as raw bytes.
+type PayloadDissector func(message Message, payload []byte, base int) *dissect.Node
+
+// Dissect breaks a WP frame into a field tree: header, one node per
+// sub-message with its fields, and trailer. It checks the crc with alg and
+// walks sub-messages the way Parse does, so a broken sub-message shows up as
+// an invalid node and dissection carries on after it. uplinks, if not nil,
+// is called for every uplink payload.
+func Dissect(data []byte, alg *checksum.Algorithm, uplinks PayloadDissector) *dissect.Node {
+	root := dissect.NewNode("wp frame", data, 0, len(data))
+
+	header := dissect.NewNode("header", data, 0, headerLen)
+	header.Add(fieldNodes(headerLayout, data, 0, len(data))...)
+	if start := header.Find(startByteField.Name); start != nil && start.Complete() && data[0] != START_BYTE {
+		start.Invalidate(fmt.Sprintf("expected %X", START_BYTE))
+	}
+	root.Add(header)
+
+	if len(data) < headerLen+trailerLen {
+		return root.Invalidate(fmt.Sprintf("frame of %d bytes is shorter than header and trailer, %d bytes", len(data), headerLen+trailerLen))
+	}
+	trailer := len(data) - trailerLen
+
+	messages := dissect.NewNode("messages", data, headerLen, trailer-headerLen)
+	protocolVersion := data[headerLen]
+	for index := headerLen; index < trailer; {
+		node, length, err := dissectMessage(data, index, trailer, uplinks)
+		if err != nil {
+			// the bytes up to the next plausible sub-message make up the broken one
+			next := resync(data, index+1, trailer, protocolVersion)
+			node = dissect.NewNode("sub-message", data, index, next-index).Invalidate(err.Error())
+			messages.Add(node)
+			index = next
+			continue
+		}
+		messages.Add(node)
+		index += length
+	}
+	root.Add(messages)
+
+	trailerNode := dissect.NewNode("trailer", data, trailer, trailerLen)
+	trailerNode.Add(fieldNodes(trailerLayout, data, trailer, len(data))...)
+	crcOffset := trailer + crcField.Offset
+	if crc := trailerNode.Find(crcField.Name); crc != nil && crc.Complete() {
+		crc.SetValue(fmt.Sprintf("%X", crcField.uint(data, trailer)))
+		if alg.Size() != crcField.Size {
+			crc.Invalidate(fmt.Sprintf("checksum %s is %d bytes, the frame crc is %d", alg.Name, alg.Size(), crcField.Size))
+		} else if computed := alg.Checksum(data[:crcOffset]); crcField.uint(data, trailer) != computed {
+			crc.Invalidate(fmt.Sprintf("%s computed %X", alg.Name, computed))
+		}
+	}
+	root.Add(trailerNode)
+
+	return root
+}
+
+// dissectMessage returns the node of the sub-message at index and its length.
+func dissectMessage(data []byte, index int, end int, uplinks PayloadDissector) (*dissect.Node, int, error) {
+	message, err := ParseMessage(data, index, end)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	p := protocolFor(message.ProtocolVersion)
+	layout := p.layouts[message.Type]
+
+	node := dissect.NewNode(message.Name(), data, index, message.Length)
+	node.Add(fieldNodes(layout, data, index, end)...)
+	if typeNode := node.Find(messageTypeField.Name); typeNode != nil {
+		typeNode.SetValue(fmt.Sprintf("%d (%s)", message.Type, message.Type))
+	}
+
+	if _, ok := p.payloadLenFields[message.Type]; ok {
+		payloadStart := index + layout.length()
+		payload := dissect.NewNode("payload", data, payloadStart, index+message.Length-payloadStart)
+		if message.Type == UplinkMsg && uplinks != nil {
+			if inner := uplinks(message, message.Uplink.Payload, payloadStart); inner != nil {
+				payload.Add(inner)
+			}
+		}
+		node.Add(payload)
+	}
+
+	return node, message.Length, nil
+}
+
+func fieldNodes(l layout, data []byte, base int, end int) []*dissect.Node {
+	nodes := make([]*dissect.Node, 0, len(l))
+	for _, f := range l {
+		nodes = append(nodes, fieldNode(f, data, base, end))
+	}
+	// layouts list fields by meaning, the tree shows them in byte order
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Offset < nodes[j].Offset })
+	return nodes
+}
+
+// fieldNode dissects one field; fields up to 4 bytes get their decoded
+// little-endian value.
+func fieldNode(f field, data []byte, base int, end int) *dissect.Node {
+	if end > len(data) {
+		end = len(data)
+	}
+	node := dissect.NewNode(f.Name, data[:end], base+f.Offset, f.Size)
+	if node.Complete() && f.Size <= 4 {
+		node.SetValue(f.uint(data, base))
+	}
+	return node
+}
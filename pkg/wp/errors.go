@@ -0,0 +1,44 @@
+package wp
+
+import "fmt"
+
+// ParseError reports why a WP frame or sub-message could not be parsed.
+// Offset is the position of Field within the frame. For truncation errors
+// Expected and Available give the length the field needs and the bytes left;
+// other failures carry a Reason instead. Err, when set, is the sentinel the
+// error matches with errors.Is.
+type ParseError struct {
+	Field     string
+	Offset    int
+	Expected  int
+	Available int
+	Reason    string
+	Err       error
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ParseError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("wp parse error at byte %d, field %s: %s", e.Offset, e.Field, e.Reason)
+	}
+	return fmt.Sprintf("wp parse error at byte %d, field %s: expected %d byte(s), %d available", e.Offset, e.Field, e.Expected, e.Available)
+}
+
+// requireBytes checks that length bytes starting at offset lie before end.
+func requireBytes(data []byte, end int, field string, offset int, length int) error {
+	if end > len(data) {
+		end = len(data)
+	}
+	if offset >= 0 && length >= 0 && offset+length <= end {
+		return nil
+	}
+
+	available := end - offset
+	if available < 0 {
+		available = 0
+	}
+	return &ParseError{Field: field, Offset: offset, Expected: length, Available: available}
+}
@@ -0,0 +1,115 @@
+package wp
+
+const (
+	START_BYTE = byte(0xFE)
+
+	// uplinks from this source endpoint announce a sink change
+	SINK_CHANGE_ENDPOINT = uint8(0x16)
+	// uplinks from this source endpoint upwards carry RF diagnostics
+	RF_DIAG_ENDPOINT_MIN = uint8(240)
+)
+
+type MessageType uint8
+
+const (
+	DownlinkSentStatusMsg        MessageType = 1
+	UplinkMsg                    MessageType = 2
+	SetAppConfigRespMsg          MessageType = 4
+	SetSinkConfigRespMsg         MessageType = 6
+	SetDiagRespMsg               MessageType = 8
+	GetAppConfigMsg              MessageType = 10
+	GetSinkConfigMsg             MessageType = 12
+	GetDiagMsg                   MessageType = 14
+	SetStackStateRespMsg         MessageType = 16
+	SetOtapActionRespMsg         MessageType = 31
+	GetOtapActionRespMsg         MessageType = 33
+	UploadScratchPadChunkRespMsg MessageType = 35
+	ProcessScratchPadRespMsg     MessageType = 37
+	DcuRespMsg                   MessageType = 129
+	DcuDiagRespMsg               MessageType = 130
+)
+
+var messageTypeNames = map[MessageType]string{
+	DownlinkSentStatusMsg:        "Downlink_Sent_Status_Msg",
+	UplinkMsg:                    "Uplink_Msg",
+	SetAppConfigRespMsg:          "Set_App_Config_Resp_Msg",
+	SetSinkConfigRespMsg:         "Set_Sink_Config_Resp_Msg",
+	SetDiagRespMsg:               "Set_Diag_Resp_Msg",
+	GetAppConfigMsg:              "Get_App_Config_Msg",
+	GetSinkConfigMsg:             "Get_Sink_Config_Msg",
+	GetDiagMsg:                   "Get_Diag_Msg",
+	SetStackStateRespMsg:         "Set_Stack_State_Resp_Msg",
+	SetOtapActionRespMsg:         "Set_Otap_Action_Resp_Msg",
+	GetOtapActionRespMsg:         "Get_Otap_Action_Resp_Msg",
+	UploadScratchPadChunkRespMsg: "Upload_ScratchPad_Chunk_Resp_Msg",
+	ProcessScratchPadRespMsg:     "Process_ScratchPad_Resp_Msg",
+	DcuRespMsg:                   "Dcu_Resp_Msg",
+	DcuDiagRespMsg:               "Dcu_Diag_Resp_Msg",
+}
+
+func (t MessageType) String() string {
+	if name, ok := messageTypeNames[t]; ok {
+		return name
+	}
+	return "Unkown_Msg"
+}
+
+// Frame is one parsed WP frame. Parse builds a new Frame for every call and
+// copies every byte slice out of the input, so a Frame never changes after it
+// is returned and can be shared between goroutines.
+type Frame struct {
+	PacketLen uint16
+	SinkID    uint8
+	DcuTime   uint32
+	DcuNumber uint32
+	Crc       uint16
+	Messages  []Message
+}
+
+// Message is one sub-message of a frame. Only the fields that belong to its
+// Type are set.
+type Message struct {
+	// position and total length of the sub-message within the frame
+	Offset int
+	Length int
+
+	// as sent, also for versions parsed with the v1 layout
+	ProtocolVersion uint8
+	Type            MessageType
+
+	// DownlinkSentStatusMsg
+	MessageID uint8
+	// DownlinkSentStatusMsg and the Set_*_Resp / scratchpad responses
+	Status uint8
+	// GetDiagMsg
+	DiagInterval uint16
+	// GetAppConfigMsg, GetSinkConfigMsg, GetOtapActionRespMsg, DcuRespMsg and DcuDiagRespMsg
+	Body []byte
+	// UplinkMsg
+	Uplink Uplink
+}
+
+type Uplink struct {
+	SrcAddress  uint32
+	DstAddress  uint32
+	SrcEndpoint uint8
+	DstEndpoint uint8
+	TravelTime  uint32
+	Qos         uint8
+	HopCount    uint8
+	Payload     []byte
+}
+
+// Name returns the message name used on the wire-facing topics. Uplinks are
+// further split by source endpoint into sink changes and RF diagnostics.
+func (m Message) Name() string {
+	if m.Type == UplinkMsg {
+		switch {
+		case m.Uplink.SrcEndpoint == SINK_CHANGE_ENDPOINT:
+			return "Sink_Change_Msg"
+		case m.Uplink.SrcEndpoint >= RF_DIAG_ENDPOINT_MIN:
+			return "Wp_Rf_Diag_Msg"
+		}
+	}
+	return m.Type.String()
+}
@@ -0,0 +1,106 @@
+package wp
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+)
+
+type goldenMessage struct {
+	Type        string `json:"type"`
+	MessageID   uint8  `json:"message_id"`
+	Status      uint8  `json:"status"`
+	SrcAddress  uint32 `json:"src_address"`
+	DstAddress  uint32 `json:"dst_address"`
+	SrcEndpoint uint8  `json:"src_endpoint"`
+	DstEndpoint uint8  `json:"dst_endpoint"`
+	TravelTime  uint32 `json:"travel_time"`
+	Qos         uint8  `json:"qos"`
+	HopCount    uint8  `json:"hop_count"`
+	Payload     string `json:"payload"`
+}
+
+type goldenFrame struct {
+	Name     string `json:"name"`
+	Source   string `json:"source"`
+	Hex      string `json:"hex"`
+	Expected *struct {
+		PacketLen uint16          `json:"packet_len"`
+		SinkID    uint8           `json:"sink_id"`
+		DcuTime   uint32          `json:"dcu_time"`
+		DcuNumber uint32          `json:"dcu_number"`
+		Crc       uint16          `json:"crc"`
+		Messages  []goldenMessage `json:"messages"`
+	} `json:"expected"`
+	ErrorField string `json:"error_field"`
+}
+
+func loadGoldenFrames(t *testing.T) []goldenFrame {
+	raw, err := os.ReadFile("testdata/frames.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Frames []goldenFrame `json:"frames"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc.Frames
+}
+
+func TestParseGoldenFrames(t *testing.T) {
+	for _, golden := range loadGoldenFrames(t) {
+		t.Run(golden.Name, func(t *testing.T) {
+			data, err := hex.DecodeString(golden.Hex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			frame, err := Parse(data)
+
+			if golden.ErrorField != "" {
+				var parseErr *ParseError
+				if !errors.As(err, &parseErr) || parseErr.Field != golden.ErrorField {
+					t.Fatalf("err = %v, want a %s error", err, golden.ErrorField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			want := golden.Expected
+			if int(frame.PacketLen) != len(data) {
+				t.Fatalf("packetLen %d of a %d byte frame, Build assumes it counts the whole frame", frame.PacketLen, len(data))
+			}
+			if frame.PacketLen != want.PacketLen || frame.SinkID != want.SinkID || frame.DcuTime != want.DcuTime || frame.DcuNumber != want.DcuNumber || frame.Crc != want.Crc {
+				t.Fatalf("frame = len %d sink %d time %d dcu %d crc %X, want len %d sink %d time %d dcu %d crc %X",
+					frame.PacketLen, frame.SinkID, frame.DcuTime, frame.DcuNumber, frame.Crc,
+					want.PacketLen, want.SinkID, want.DcuTime, want.DcuNumber, want.Crc)
+			}
+			if len(frame.Messages) != len(want.Messages) {
+				t.Fatalf("got %d sub-messages, want %d", len(frame.Messages), len(want.Messages))
+			}
+			for i, message := range frame.Messages {
+				got := goldenMessage{
+					Type:        message.Name(),
+					MessageID:   message.MessageID,
+					Status:      message.Status,
+					SrcAddress:  message.Uplink.SrcAddress,
+					DstAddress:  message.Uplink.DstAddress,
+					SrcEndpoint: message.Uplink.SrcEndpoint,
+					DstEndpoint: message.Uplink.DstEndpoint,
+					TravelTime:  message.Uplink.TravelTime,
+					Qos:         message.Uplink.Qos,
+					HopCount:    message.Uplink.HopCount,
+					Payload:     hex.EncodeToString(message.Uplink.Payload),
+				}
+				if got != want.Messages[i] {
+					t.Fatalf("sub-message %d = %+v, want %+v", i, got, want.Messages[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package wp
+
+// field is one field of a WP frame. Multi-byte fields are little-endian.
+// Offset is relative to the start of the section holding the field: the frame
+// header, a sub-message or the frame trailer.
+type field struct {
+	Name   string
+	Offset int
+	Size   int
+}
+
+func (f field) end() int {
+	return f.Offset + f.Size
+}
+
+// uint reads the field from the section starting at base.
+func (f field) uint(data []byte, base int) uint32 {
+	var value uint32
+	for i := f.Size - 1; i >= 0; i-- {
+		value = value<<8 | uint32(data[base+f.Offset+i])
+	}
+	return value
+}
+
+// bytes returns the raw bytes of the field from the section starting at base.
+func (f field) bytes(data []byte, base int) []byte {
+	return data[base+f.Offset : base+f.end()]
+}
+
+// layout lists the fields of a section in wire order.
+type layout []field
+
+func (l layout) length() int {
+	length := 0
+	for _, field := range l {
+		if field.end() > length {
+			length = field.end()
+		}
+	}
+	return length
+}
+
+// frame header, offsets from the start byte. packetLen is taken to count
+// every byte of the frame, start byte to crc. No captured frame has confirmed
+// this, no gateway frame document is available to this service: Build writes
+// it so and Parse does not rely on it, frames are delimited by the message
+// carrying them.
+var (
+	startByteField = field{Name: "startByte", Offset: 0, Size: 1}
+	packetLenField = field{Name: "packetLen", Offset: 1, Size: 2}
+	sinkIDField    = field{Name: "sinkId", Offset: 3, Size: 1}
+
+	headerLayout = layout{startByteField, packetLenField, sinkIDField}
+)
+
+// frame trailer, offsets from the first byte after the last sub-message
+var (
+	dcuTimeField   = field{Name: "dcuTime", Offset: 0, Size: 4}
+	dcuNumberField = field{Name: "dcuNumber", Offset: 4, Size: 4}
+	crcField       = field{Name: "crc", Offset: 8, Size: 2}
+
+	trailerLayout = layout{dcuTimeField, dcuNumberField, crcField}
+)
+
+// sub-message fields, offsets from the protocol version byte
+var (
+	protocolVersionField = field{Name: "protocolVersion", Offset: 0, Size: 1}
+	messageTypeField     = field{Name: "messageType", Offset: 1, Size: 1}
+
+	messageIDField    = field{Name: "messageId", Offset: 2, Size: 1}
+	sentStatusField   = field{Name: "status", Offset: 3, Size: 1}
+	respStatusField   = field{Name: "status", Offset: 2, Size: 1}
+	appConfigField    = field{Name: "appConfig", Offset: 2, Size: 80}
+	sinkConfigField   = field{Name: "sinkConfig", Offset: 2, Size: 10}
+	diagIntervalField = field{Name: "diagInterval", Offset: 2, Size: 2}
+	otapActionField   = field{Name: "otapAction", Offset: 2, Size: 5}
+
+	dcuRespHeadField   = field{Name: "respHead", Offset: 2, Size: 11}
+	dcuRespMsgLenField = field{Name: "msgLen", Offset: 13, Size: 1}
+	dcuRespTailField   = field{Name: "respTail", Offset: 14, Size: 2}
+)
+
+var (
+	statusRespLayout = layout{protocolVersionField, messageTypeField, respStatusField}
+	dcuRespLayout    = layout{protocolVersionField, messageTypeField, dcuRespHeadField, dcuRespMsgLenField, dcuRespTailField}
+)
+
+var (
+	headerLen  = headerLayout.length()
+	trailerLen = trailerLayout.length()
+)
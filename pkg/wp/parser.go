@@ -0,0 +1,116 @@
+package wp
+
+import (
+	"errors"
+	"fmt"
+	"parsing-service/pkg/checksum"
+)
+
+// Parse validates a WP frame and decodes all of its sub-messages. It keeps no
+// state between calls and is safe to call from many goroutines.
+//
+// A frame whose length, start byte or CRC is wrong returns an empty Frame and
+// a *ParseError. A frame of a protocol version without a parser returns its
+// header and trailer only, with an error matching ErrUnsupportedVersion;
+// versions without a parser of their own are otherwise parsed as v1. A
+// broken sub-message is skipped, parsing resumes at the next plausible
+// sub-message, and the Frame is returned together with the joined errors of
+// every skipped sub-message.
+func Parse(data []byte) (Frame, error) {
+	return ParseWith(data, checksum.FRAME_DEFAULT)
+}
+
+// ParseWith is Parse with the crc checked by alg. The trailer has room for a
+// 16 bit crc only.
+func ParseWith(data []byte, alg *checksum.Algorithm) (Frame, error) {
+	if alg.Size() != crcField.Size {
+		return Frame{}, &ParseError{Field: crcField.Name, Reason: fmt.Sprintf("checksum %s is %d bytes, the frame crc is %d", alg.Name, alg.Size(), crcField.Size)}
+	}
+	if err := requireBytes(data, len(data), "frame", 0, headerLen+trailerLen); err != nil {
+		return Frame{}, err
+	}
+	if data[0] != START_BYTE {
+		return Frame{}, &ParseError{Field: startByteField.Name, Offset: 0, Reason: fmt.Sprintf("start byte %X, expected %X", data[0], START_BYTE)}
+	}
+
+	trailer := len(data) - trailerLen
+	frame := Frame{
+		PacketLen: uint16(packetLenField.uint(data, 0)),
+		SinkID:    uint8(sinkIDField.uint(data, 0)),
+		DcuTime:   dcuTimeField.uint(data, trailer),
+		DcuNumber: dcuNumberField.uint(data, trailer),
+		Crc:       uint16(crcField.uint(data, trailer)),
+	}
+
+	crcOffset := trailer + crcField.Offset
+	if computed := uint16(alg.Checksum(data[:crcOffset])); computed != frame.Crc {
+		return Frame{}, &ParseError{Field: crcField.Name, Offset: crcOffset, Reason: fmt.Sprintf("%s crc mismatch, packet %X computed %X", alg.Name, frame.Crc, computed)}
+	}
+
+	// a frame is rejected as a whole when its first sub-message is of a
+	// version known not to be parseable, there is nothing to resync on
+	protocolVersion := data[headerLen]
+	if headerLen < trailer {
+		if _, err := protocolAt(data, headerLen, trailer); errors.Is(err, ErrUnsupportedVersion) {
+			return frame, err
+		}
+	}
+
+	var parseErrors []error
+	for index := headerLen; index < trailer; {
+		message, err := ParseMessage(data, index, trailer)
+		if err != nil {
+			// skip the broken sub-message and carry on from the next plausible one
+			parseErrors = append(parseErrors, err)
+			index = resync(data, index+1, trailer, protocolVersion)
+			continue
+		}
+		frame.Messages = append(frame.Messages, message)
+		index += message.Length
+	}
+
+	return frame, errors.Join(parseErrors...)
+}
+
+// ParseMessage decodes the sub-message starting at index with the parser
+// of its protocol version. end is the first byte of the frame trailer; a
+// sub-message must not run past it.
+func ParseMessage(data []byte, index int, end int) (Message, error) {
+	p, err := protocolAt(data, index, end)
+	if err != nil {
+		return Message{}, err
+	}
+	length, err := p.messageLength(data, index, end)
+	if err != nil {
+		return Message{}, err
+	}
+
+	message := Message{
+		Offset:          index,
+		Length:          length,
+		ProtocolVersion: uint8(protocolVersionField.uint(data, index)),
+		Type:            MessageType(messageTypeField.uint(data, index)),
+	}
+	p.decoders[message.Type](data, index, length, &message)
+
+	return message, nil
+}
+
+// resync scans forward from index for the next position that carries the
+// frame's protocol version followed by a complete sub-message. It returns end
+// when no such position exists.
+func resync(data []byte, index int, end int, protocolVersion byte) int {
+	for ; index < end; index++ {
+		if data[index] != protocolVersion {
+			continue
+		}
+		if _, err := subMessageLength(data, index, end); err == nil {
+			return index
+		}
+	}
+	return end
+}
+
+func copyBytes(data []byte) []byte {
+	return append([]byte(nil), data...)
+}
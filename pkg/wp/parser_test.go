@@ -0,0 +1,247 @@
+package wp
+
+import (
+	"errors"
+	"testing"
+
+	"parsing-service/pkg/checksum"
+)
+
+// v1UplinkBody is the body of a v1 uplink sub-message after its version and
+// type bytes. None of the bytes is 0x01, so resync never finds a protocol
+// version byte inside it.
+func v1UplinkBody(payload []byte) []byte {
+	body := []byte{
+		0x44, 0x33, 0x22, 0x11, // src address
+		0x88, 0x77, 0x66, 0x55, // dst address
+		0x0A,                   // src endpoint
+		0x0B,                   // dst endpoint
+		0x10, 0x20, 0x30, 0x40, // travel time
+		0x05,               // qos
+		byte(len(payload)), // msg len
+		0x03,               // hop count
+	}
+	return append(body, payload...)
+}
+
+func validFrame() []byte {
+	return Build(2, 0x00012345, 0x65000000, checksum.FRAME_DEFAULT,
+		OutgoingMessage{ProtocolVersion: PROTOCOL_V1, Type: UplinkMsg, Body: v1UplinkBody([]byte{0xAA, 0xBB, 0xCC})},
+		OutgoingMessage{ProtocolVersion: PROTOCOL_V1, Type: SetAppConfigRespMsg, Body: []byte{0x00}},
+	)
+}
+
+// resyncFrame has a sub-message of an unknown type ahead of a good one.
+func resyncFrame() []byte {
+	return Build(2, 7, 9, checksum.FRAME_DEFAULT,
+		OutgoingMessage{ProtocolVersion: PROTOCOL_V1, Type: MessageType(0x7F), Body: []byte{0xEE, 0xEE}},
+		OutgoingMessage{ProtocolVersion: PROTOCOL_V1, Type: SetAppConfigRespMsg, Body: []byte{0x00}},
+	)
+}
+
+// unwrapAll flattens the errors joined by Parse.
+func unwrapAll(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, e := range joined.Unwrap() {
+			errs = append(errs, unwrapAll(e)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
+
+func FuzzParse(f *testing.F) {
+	valid := validFrame()
+	f.Add(valid)
+	for _, n := range []int{0, 1, 4, 13, 14, 20, len(valid) - 1} {
+		f.Add(valid[:n])
+	}
+	f.Add(resyncFrame())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		frame, err := Parse(data)
+		if err == nil {
+			return
+		}
+		for _, e := range unwrapAll(err) {
+			var parseErr *ParseError
+			if !errors.As(e, &parseErr) {
+				t.Fatalf("Parse(%X) returned %T %v, want *ParseError", data, e, e)
+			}
+		}
+		for _, message := range frame.Messages {
+			if message.Offset < headerLen || message.Offset+message.Length > len(data)-trailerLen {
+				t.Fatalf("Parse(%X) returned sub-message at %d+%d outside the frame", data, message.Offset, message.Length)
+			}
+		}
+	})
+}
+
+func TestParseValidFrame(t *testing.T) {
+	frame, err := Parse(validFrame())
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if frame.SinkID != 2 || frame.DcuNumber != 0x00012345 || frame.DcuTime != 0x65000000 {
+		t.Fatalf("header/trailer = sink %d dcu %X time %X", frame.SinkID, frame.DcuNumber, frame.DcuTime)
+	}
+	if len(frame.Messages) != 2 {
+		t.Fatalf("got %d sub-messages, want 2", len(frame.Messages))
+	}
+	uplink := frame.Messages[0].Uplink
+	if uplink.SrcAddress != 0x11223344 || uplink.DstAddress != 0x55667788 || uplink.TravelTime != 0x40302010 || uplink.HopCount != 3 {
+		t.Fatalf("uplink = %+v", uplink)
+	}
+	if string(uplink.Payload) != string([]byte{0xAA, 0xBB, 0xCC}) {
+		t.Fatalf("payload = %X", uplink.Payload)
+	}
+}
+
+func TestParseResyncsPastBrokenSubMessage(t *testing.T) {
+	frame, err := Parse(resyncFrame())
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Field != messageTypeField.Name || parseErr.Offset != headerLen+1 {
+		t.Fatalf("err = %v, want a messageType error at %d", err, headerLen+1)
+	}
+	if len(frame.Messages) != 1 || frame.Messages[0].Type != SetAppConfigRespMsg {
+		t.Fatalf("messages = %+v, want the Set_App_Config_Resp after the broken one", frame.Messages)
+	}
+}
+
+func TestParseFrameErrors(t *testing.T) {
+	valid := validFrame()
+	badStart := append([]byte(nil), valid...)
+	badStart[0] = 0x00
+	badCrc := append([]byte(nil), valid...)
+	badCrc[len(badCrc)-1] ^= 0xFF
+
+	tests := []struct {
+		name      string
+		data      []byte
+		field     string
+		offset    int
+		expected  int
+		available int
+		reason    bool
+	}{
+		{name: "empty", data: nil, field: "frame", offset: 0, expected: headerLen + trailerLen, available: 0},
+		{name: "start byte only", data: valid[:1], field: "frame", offset: 0, expected: headerLen + trailerLen, available: 1},
+		{name: "one short of header and trailer", data: valid[:headerLen+trailerLen-1], field: "frame", offset: 0, expected: headerLen + trailerLen, available: headerLen + trailerLen - 1},
+		{name: "bad start byte", data: badStart, field: startByteField.Name, offset: 0, reason: true},
+		{name: "bad crc", data: badCrc, field: crcField.Name, offset: len(valid) - crcField.Size, reason: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := Parse(tt.data)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("err = %v, want *ParseError", err)
+			}
+			if len(frame.Messages) != 0 {
+				t.Fatalf("got %d sub-messages from a rejected frame", len(frame.Messages))
+			}
+			if parseErr.Field != tt.field || parseErr.Offset != tt.offset {
+				t.Fatalf("error at %s/%d, want %s/%d: %v", parseErr.Field, parseErr.Offset, tt.field, tt.offset, parseErr)
+			}
+			if tt.reason {
+				if parseErr.Reason == "" {
+					t.Fatalf("error has no reason: %v", parseErr)
+				}
+				return
+			}
+			if parseErr.Expected != tt.expected || parseErr.Available != tt.available {
+				t.Fatalf("expected/available = %d/%d, want %d/%d", parseErr.Expected, parseErr.Available, tt.expected, tt.available)
+			}
+		})
+	}
+}
+
+func TestParseTruncatedSubMessage(t *testing.T) {
+	// absolute offset of a sub-message field when the sub-message is first
+	at := func(f field) int { return headerLen + f.Offset }
+	full := v1UplinkBody([]byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE})
+	// the body starts after the version and type bytes
+	cut := func(f field, missing int) []byte { return full[:f.Offset-messageTypeField.end()+f.Size-missing] }
+
+	tests := []struct {
+		name      string
+		body      []byte
+		field     string
+		offset    int
+		expected  int
+		available int
+	}{
+		{name: "no uplink header", body: nil, field: "srcAddress", offset: at(v1Uplink.srcAddress), expected: 4, available: 0},
+		{name: "src address cut", body: cut(v1Uplink.srcAddress, 1), field: "srcAddress", offset: at(v1Uplink.srcAddress), expected: 4, available: 3},
+		{name: "dst address cut", body: cut(v1Uplink.dstAddress, 2), field: "dstAddress", offset: at(v1Uplink.dstAddress), expected: 4, available: 2},
+		{name: "src endpoint missing", body: cut(v1Uplink.srcEP, 1), field: "srcEndpoint", offset: at(v1Uplink.srcEP), expected: 1, available: 0},
+		{name: "dst endpoint missing", body: cut(v1Uplink.dstEP, 1), field: "dstEndpoint", offset: at(v1Uplink.dstEP), expected: 1, available: 0},
+		{name: "travel time cut", body: cut(v1Uplink.travelTime, 3), field: "travelTime", offset: at(v1Uplink.travelTime), expected: 4, available: 1},
+		{name: "qos missing", body: cut(v1Uplink.qos, 1), field: "qos", offset: at(v1Uplink.qos), expected: 1, available: 0},
+		{name: "msg len missing", body: cut(v1Uplink.msgLen, 1), field: "msgLen", offset: at(v1Uplink.msgLen), expected: 1, available: 0},
+		{name: "hop count missing", body: cut(v1Uplink.hopCount, 1), field: "hopCount", offset: at(v1Uplink.hopCount), expected: 1, available: 0},
+		{name: "payload missing", body: full[:len(full)-5], field: "payload", offset: at(v1Uplink.hopCount) + 1, expected: 5, available: 0},
+		{name: "payload cut", body: full[:len(full)-2], field: "payload", offset: at(v1Uplink.hopCount) + 1, expected: 5, available: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := Build(1, 1, 1, checksum.FRAME_DEFAULT, OutgoingMessage{ProtocolVersion: PROTOCOL_V1, Type: UplinkMsg, Body: tt.body})
+			frame, err := Parse(data)
+			errs := unwrapAll(err)
+			if len(errs) != 1 {
+				t.Fatalf("got errors %v, want one", err)
+			}
+			var parseErr *ParseError
+			if !errors.As(errs[0], &parseErr) {
+				t.Fatalf("err = %v, want *ParseError", errs[0])
+			}
+			if len(frame.Messages) != 0 {
+				t.Fatalf("got sub-messages %+v from a truncated uplink", frame.Messages)
+			}
+			got := [4]interface{}{parseErr.Field, parseErr.Offset, parseErr.Expected, parseErr.Available}
+			want := [4]interface{}{tt.field, tt.offset, tt.expected, tt.available}
+			if got != want {
+				t.Fatalf("field/offset/expected/available = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// today's gateways are parsed with the v1 layout whatever version byte they
+// send, only versions known to change the layout are rejected
+func TestParseVersions(t *testing.T) {
+	payload := []byte{0xAA, 0xBB, 0xCC}
+	for _, tt := range []struct {
+		version     uint8
+		unsupported bool
+	}{
+		{0, false},
+		{PROTOCOL_V1, false},
+		{PROTOCOL_V2, true},
+		{3, false},
+		{0xFF, false},
+	} {
+		data := Build(2, 0x00012345, 0x65000000, checksum.FRAME_DEFAULT,
+			OutgoingMessage{ProtocolVersion: tt.version, Type: UplinkMsg, Body: v1UplinkBody(payload)},
+		)
+		frame, err := Parse(data)
+		if tt.unsupported {
+			if !errors.Is(err, ErrUnsupportedVersion) || len(frame.Messages) != 0 || frame.DcuNumber != 0x00012345 {
+				t.Fatalf("version %d: got %+v, %v, want the frame header rejected with ErrUnsupportedVersion", tt.version, frame, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("version %d: %v", tt.version, err)
+		}
+		if len(frame.Messages) != 1 {
+			t.Fatalf("version %d: got %d sub-messages, want 1", tt.version, len(frame.Messages))
+		}
+		message := frame.Messages[0]
+		if message.ProtocolVersion != tt.version || message.Uplink.SrcAddress != 0x11223344 ||
+			message.Uplink.HopCount != 3 || string(message.Uplink.Payload) != string(payload) {
+			t.Fatalf("version %d: message %+v", tt.version, message)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package wp
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// WP protocol versions, the first byte of every sub-message
+const (
+	PROTOCOL_V1 = uint8(1)
+	// gateway firmware v2 reworks the uplink header. Its layout is not
+	// specified yet, so v2 sub-messages are not parsed.
+	PROTOCOL_V2 = uint8(2)
+)
+
+var ErrUnsupportedVersion = errors.New("unsupported wp protocol version")
+
+// decoder fills the type specific fields of the sub-message of length bytes
+// starting at index. The length has been checked against the layout.
+type decoder func(data []byte, index int, length int, message *Message)
+
+// protocol is everything needed to parse the sub-messages of one protocol
+// version, keyed by message type.
+type protocol struct {
+	version          uint8
+	layouts          map[MessageType]layout
+	payloadLenFields map[MessageType]field
+	decoders         map[MessageType]decoder
+}
+
+// uplinkFields is the uplink header of one protocol version. The payload
+// follows the last field of the header.
+type uplinkFields struct {
+	srcAddress field
+	dstAddress field
+	srcEP      field
+	dstEP      field
+	travelTime field
+	qos        field
+	hopCount   field
+	msgLen     field
+}
+
+var (
+	v1Uplink = uplinkFields{
+		srcAddress: field{Name: "srcAddress", Offset: 2, Size: 4},
+		dstAddress: field{Name: "dstAddress", Offset: 6, Size: 4},
+		srcEP:      field{Name: "srcEndpoint", Offset: 10, Size: 1},
+		dstEP:      field{Name: "dstEndpoint", Offset: 11, Size: 1},
+		travelTime: field{Name: "travelTime", Offset: 12, Size: 4},
+		qos:        field{Name: "qos", Offset: 16, Size: 1},
+		msgLen:     field{Name: "msgLen", Offset: 17, Size: 1},
+		hopCount:   field{Name: "hopCount", Offset: 18, Size: 1},
+	}
+)
+
+// layout lists the uplink header in wire order, so the first field found
+// missing in a truncated header is the one that is reported.
+func (u uplinkFields) layout() layout {
+	fields := layout{
+		protocolVersionField, messageTypeField,
+		u.srcAddress, u.dstAddress,
+		u.srcEP, u.dstEP,
+		u.travelTime, u.qos,
+		u.hopCount, u.msgLen,
+	}
+	sort.SliceStable(fields, func(i, j int) bool { return fields[i].Offset < fields[j].Offset })
+	return fields
+}
+
+func (u uplinkFields) decode(data []byte, index int, length int, message *Message) {
+	payloadStart := index + u.layout().length()
+	message.Uplink = Uplink{
+		SrcAddress:  u.srcAddress.uint(data, index),
+		DstAddress:  u.dstAddress.uint(data, index),
+		SrcEndpoint: uint8(u.srcEP.uint(data, index)),
+		DstEndpoint: uint8(u.dstEP.uint(data, index)),
+		TravelTime:  u.travelTime.uint(data, index),
+		Qos:         uint8(u.qos.uint(data, index)),
+		HopCount:    uint8(u.hopCount.uint(data, index)),
+		Payload:     copyBytes(data[payloadStart : index+length]),
+	}
+}
+
+func decodeSentStatus(data []byte, index int, length int, message *Message) {
+	message.MessageID = uint8(messageIDField.uint(data, index))
+	message.Status = uint8(sentStatusField.uint(data, index))
+}
+
+func decodeRespStatus(data []byte, index int, length int, message *Message) {
+	message.Status = uint8(respStatusField.uint(data, index))
+}
+
+func decodeDiagInterval(data []byte, index int, length int, message *Message) {
+	message.DiagInterval = uint16(diagIntervalField.uint(data, index))
+}
+
+func decodeBodyField(f field) decoder {
+	return func(data []byte, index int, length int, message *Message) {
+		message.Body = copyBytes(f.bytes(data, index))
+	}
+}
+
+func decodeDcuResp(data []byte, index int, length int, message *Message) {
+	message.Body = copyBytes(data[index+dcuRespHeadField.Offset : index+length])
+}
+
+// newProtocol builds a protocol from the sub-messages every version shares
+// and the version's own uplink header.
+func newProtocol(version uint8, uplink uplinkFields) *protocol {
+	return &protocol{
+		version: version,
+		layouts: map[MessageType]layout{
+			DownlinkSentStatusMsg:        {protocolVersionField, messageTypeField, messageIDField, sentStatusField},
+			UplinkMsg:                    uplink.layout(),
+			SetAppConfigRespMsg:          statusRespLayout,
+			SetSinkConfigRespMsg:         statusRespLayout,
+			SetDiagRespMsg:               statusRespLayout,
+			GetAppConfigMsg:              {protocolVersionField, messageTypeField, appConfigField},
+			GetSinkConfigMsg:             {protocolVersionField, messageTypeField, sinkConfigField},
+			GetDiagMsg:                   {protocolVersionField, messageTypeField, diagIntervalField},
+			SetStackStateRespMsg:         statusRespLayout,
+			SetOtapActionRespMsg:         statusRespLayout,
+			GetOtapActionRespMsg:         {protocolVersionField, messageTypeField, otapActionField},
+			UploadScratchPadChunkRespMsg: statusRespLayout,
+			ProcessScratchPadRespMsg:     statusRespLayout,
+			DcuRespMsg:                   dcuRespLayout,
+			DcuDiagRespMsg:               dcuRespLayout,
+		},
+		payloadLenFields: map[MessageType]field{
+			UplinkMsg:      uplink.msgLen,
+			DcuRespMsg:     dcuRespMsgLenField,
+			DcuDiagRespMsg: dcuRespMsgLenField,
+		},
+		decoders: map[MessageType]decoder{
+			DownlinkSentStatusMsg:        decodeSentStatus,
+			UplinkMsg:                    uplink.decode,
+			SetAppConfigRespMsg:          decodeRespStatus,
+			SetSinkConfigRespMsg:         decodeRespStatus,
+			SetDiagRespMsg:               decodeRespStatus,
+			GetAppConfigMsg:              decodeBodyField(appConfigField),
+			GetSinkConfigMsg:             decodeBodyField(sinkConfigField),
+			GetDiagMsg:                   decodeDiagInterval,
+			SetStackStateRespMsg:         decodeRespStatus,
+			SetOtapActionRespMsg:         decodeRespStatus,
+			GetOtapActionRespMsg:         decodeBodyField(otapActionField),
+			UploadScratchPadChunkRespMsg: decodeRespStatus,
+			ProcessScratchPadRespMsg:     decodeRespStatus,
+			DcuRespMsg:                   decodeDcuResp,
+			DcuDiagRespMsg:               decodeDcuResp,
+		},
+	}
+}
+
+var protocols = map[uint8]*protocol{
+	PROTOCOL_V1: newProtocol(PROTOCOL_V1, v1Uplink),
+}
+
+// unparsedVersions are the versions known to change the sub-message layouts
+// that have no parser yet, with the reason.
+var unparsedVersions = map[uint8]string{
+	PROTOCOL_V2: "gateway firmware v2 uplink header not specified yet",
+}
+
+// SupportedVersions lists the protocol versions Parse has a parser of its
+// own for. Sub-messages of any version that is neither one of these nor
+// known to be unparsed are parsed as v1.
+func SupportedVersions() []uint8 {
+	versions := make([]uint8, 0, len(protocols))
+	for version := range protocols {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+// protocolAt returns the protocol of the sub-message starting at index.
+func protocolAt(data []byte, index int, end int) (*protocol, error) {
+	if err := requireBytes(data, end, protocolVersionField.Name, index+protocolVersionField.Offset, protocolVersionField.Size); err != nil {
+		return nil, err
+	}
+	version := uint8(protocolVersionField.uint(data, index))
+	if reason, ok := unparsedVersions[version]; ok {
+		return nil, &ParseError{Field: protocolVersionField.Name, Offset: index + protocolVersionField.Offset, Reason: fmt.Sprintf("protocol version %d not supported: %s", version, reason), Err: ErrUnsupportedVersion}
+	}
+	return protocolFor(version), nil
+}
+
+// protocolFor returns the parser of version. Gateways were parsed with the
+// v1 layout whatever their version byte said before versions were told
+// apart, so every version without a parser of its own still is.
+func protocolFor(version uint8) *protocol {
+	if p, ok := protocols[version]; ok {
+		return p
+	}
+	return protocols[PROTOCOL_V1]
+}
+
+// messageLength returns the total length of the sub-message starting at
+// index, checking that all of it lies before end.
+func (p *protocol) messageLength(data []byte, index int, end int) (int, error) {
+	if err := requireBytes(data, end, messageTypeField.Name, index+messageTypeField.Offset, messageTypeField.Size); err != nil {
+		return 0, err
+	}
+
+	messageType := MessageType(messageTypeField.uint(data, index))
+	layout, ok := p.layouts[messageType]
+	if !ok {
+		return 0, &ParseError{Field: messageTypeField.Name, Offset: index + messageTypeField.Offset, Reason: fmt.Sprintf("unknown message type %d in protocol version %d", messageType, p.version)}
+	}
+
+	for _, field := range layout {
+		if err := requireBytes(data, end, field.Name, index+field.Offset, field.Size); err != nil {
+			return 0, err
+		}
+	}
+
+	length := layout.length()
+	if lenField, ok := p.payloadLenFields[messageType]; ok {
+		payloadLen := int(lenField.uint(data, index))
+		if err := requireBytes(data, end, "payload", index+length, payloadLen); err != nil {
+			return 0, err
+		}
+		length += payloadLen
+	}
+
+	return length, nil
+}
+
+// subMessageLength is messageLength with the protocol taken from the
+// sub-message's version byte.
+func subMessageLength(data []byte, index int, end int) (int, error) {
+	p, err := protocolAt(data, index, end)
+	if err != nil {
+		return 0, err
+	}
+	return p.messageLength(data, index, end)
+}
@@ -0,0 +1,18 @@
+package routers
+
+import (
+	captureController "parsing-service/apps/capture/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCaptureRoutes adds the runtime switches of the pcapng capture.
+// :kind is dcu or meter, :id the DCU number or meter IP.
+func RegisterCaptureRoutes(rg *gin.RouterGroup, c *captureController.CaptureController) {
+	capture := rg.Group("/capture")
+	capture.GET("/targets", c.ListTargets)
+	capture.PUT("/targets/:kind/:id", c.EnableTarget)
+	capture.DELETE("/targets/:kind/:id", c.DisableTarget)
+	capture.GET("/targets/:kind/:id/file", c.DownloadCapture)
+	capture.GET("/dissector.lua", c.DownloadDissector)
+}
@@ -0,0 +1,13 @@
+package routers
+
+import (
+	clockController "parsing-service/apps/clock/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterClockRoutes(rg *gin.RouterGroup, c *clockController.ClockController) {
+	clock := rg.Group("/clock")
+	clock.GET("/dcus", c.ListClocks)
+	clock.GET("/dcus/:dcuNumber", c.GetClock)
+}
@@ -0,0 +1,13 @@
+package routers
+
+import (
+	downlinkController "parsing-service/apps/downlinks/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterDownlinkRoutes(rg *gin.RouterGroup, c *downlinkController.DownlinkController) {
+	downlinks := rg.Group("/downlinks")
+	downlinks.POST("", c.RecordDownlink)
+	downlinks.GET("/pending", c.ListPending)
+}
@@ -0,0 +1,12 @@
+package routers
+
+import (
+	kafkaController "parsing-service/apps/kafka/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterKafkaRoutes(rg *gin.RouterGroup, c *kafkaController.KafkaController) {
+	kafka := rg.Group("/kafka")
+	kafka.GET("/clusters", c.ClusterHealth)
+}
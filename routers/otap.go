@@ -0,0 +1,13 @@
+package routers
+
+import (
+	otapController "parsing-service/apps/otap/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterOtapRoutes(rg *gin.RouterGroup, c *otapController.OtapController) {
+	otap := rg.Group("/otap")
+	otap.GET("/sessions", c.ListSessions)
+	otap.GET("/sessions/:id", c.GetSession)
+}
@@ -0,0 +1,12 @@
+package routers
+
+import (
+	presenceController "parsing-service/apps/presence/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterPresenceRoutes(rg *gin.RouterGroup, c *presenceController.PresenceController) {
+	rg.GET("/meters/:ip", c.GetMeter)
+	rg.GET("/dcus/:dcuId", c.GetDcu)
+}
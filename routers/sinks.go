@@ -0,0 +1,14 @@
+package routers
+
+import (
+	sinkController "parsing-service/apps/sinks/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterSinkRoutes(rg *gin.RouterGroup, c *sinkController.SinkController) {
+	sinks := rg.Group("/sinks")
+	sinks.GET("/attachments", c.FindAttachment)
+	sinks.GET("/attachments/:nodeAddress", c.GetAttachment)
+	sinks.GET("/dcus/:dcuNumber/sinks/:sinkId/meters", c.GetSinkMeters)
+}
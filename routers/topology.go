@@ -0,0 +1,13 @@
+package routers
+
+import (
+	topologyController "parsing-service/apps/topology/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterTopologyRoutes(rg *gin.RouterGroup, c *topologyController.TopologyController) {
+	topology := rg.Group("/topology")
+	topology.GET("/graph", c.GetGraph)
+	topology.GET("/meters/:nodeAddress", c.GetMeterRoute)
+}
@@ -1,9 +1,59 @@
 package scripts
 
 import (
+	decoder "parsing-service/apps/decoder/models"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func ExecuteScriptsPostToMigrations(db *gorm.DB) {
+// the IS 15959 event codes every firmware group logs, rows already present
+// are left as they are
+var defaultEventCatalogue = []decoder.EventCatalogue{
+	{VendorCode: 51, EventCode: "PHASE_REVERSAL", State: decoder.EVENT_STATE_OCCURRENCE, Category: decoder.EVENT_CATEGORY_TAMPER, Description: "R phase current reversal"},
+	{VendorCode: 52, EventCode: "PHASE_REVERSAL", State: decoder.EVENT_STATE_RESTORATION, Category: decoder.EVENT_CATEGORY_TAMPER, Description: "R phase current reversal"},
+	{VendorCode: 53, EventCode: "PHASE_REVERSAL", State: decoder.EVENT_STATE_OCCURRENCE, Category: decoder.EVENT_CATEGORY_TAMPER, Description: "Y phase current reversal"},
+	{VendorCode: 54, EventCode: "PHASE_REVERSAL", State: decoder.EVENT_STATE_RESTORATION, Category: decoder.EVENT_CATEGORY_TAMPER, Description: "Y phase current reversal"},
+	{VendorCode: 55, EventCode: "PHASE_REVERSAL", State: decoder.EVENT_STATE_OCCURRENCE, Category: decoder.EVENT_CATEGORY_TAMPER, Description: "B phase current reversal"},
+	{VendorCode: 56, EventCode: "PHASE_REVERSAL", State: decoder.EVENT_STATE_RESTORATION, Category: decoder.EVENT_CATEGORY_TAMPER, Description: "B phase current reversal"},
+	{VendorCode: 101, EventCode: "POWER_FAIL", State: decoder.EVENT_STATE_OCCURRENCE, Category: decoder.EVENT_CATEGORY_POWER, Description: "power failure"},
+	{VendorCode: 102, EventCode: "POWER_FAIL", State: decoder.EVENT_STATE_RESTORATION, Category: decoder.EVENT_CATEGORY_POWER, Description: "power restored"},
+	{VendorCode: 201, EventCode: "MAGNET_TAMPER", State: decoder.EVENT_STATE_OCCURRENCE, Category: decoder.EVENT_CATEGORY_TAMPER, Description: "magnetic influence"},
+	{VendorCode: 202, EventCode: "MAGNET_TAMPER", State: decoder.EVENT_STATE_RESTORATION, Category: decoder.EVENT_CATEGORY_TAMPER, Description: "magnetic influence removed"},
+	{VendorCode: 251, EventCode: "COVER_OPEN", State: decoder.EVENT_STATE_OCCURRENCE, Category: decoder.EVENT_CATEGORY_TAMPER, Description: "meter cover opened"},
+}
 
+// the OBIS codes of the fields the compiled-in parsers decode, already in
+// their units; rows already present are left as they are
+var defaultFieldCatalogue = []decoder.FieldCatalogue{
+	{FieldName: "cumm_import_Wh", ObisCode: "1.0.1.8.0.255", Unit: "Wh", QuantityType: "active_energy_import"},
+	{FieldName: "cumm_export_Wh", ObisCode: "1.0.2.8.0.255", Unit: "Wh", QuantityType: "active_energy_export"},
+	{FieldName: "cumm_import_VAh", ObisCode: "1.0.9.8.0.255", Unit: "VAh", QuantityType: "apparent_energy_import"},
+	{FieldName: "cumm_export_VAh", ObisCode: "1.0.10.8.0.255", Unit: "VAh", QuantityType: "apparent_energy_export"},
+	{FieldName: "import_Wh", ObisCode: "1.0.1.29.0.255", Unit: "Wh", QuantityType: "block_active_energy_import"},
+	{FieldName: "export_Wh", ObisCode: "1.0.2.29.0.255", Unit: "Wh", QuantityType: "block_active_energy_export"},
+	{FieldName: "import_VAh", ObisCode: "1.0.9.29.0.255", Unit: "VAh", QuantityType: "block_apparent_energy_import"},
+	{FieldName: "export_VAh", ObisCode: "1.0.10.29.0.255", Unit: "VAh", QuantityType: "block_apparent_energy_export"},
+	{FieldName: "avg_voltage", ObisCode: "1.0.12.27.0.255", Unit: "V", QuantityType: "average_voltage"},
+	{FieldName: "avg_current", ObisCode: "1.0.11.27.0.255", Unit: "A", QuantityType: "average_current"},
+	{FieldName: "voltage", ObisCode: "1.0.12.7.0.255", Unit: "V", QuantityType: "voltage"},
+	{FieldName: "current", ObisCode: "1.0.11.7.0.255", Unit: "A", QuantityType: "current"},
+	{FieldName: "active_power_W", ObisCode: "1.0.1.7.0.255", Unit: "W", QuantityType: "active_power"},
+	{FieldName: "apparent_power_VA", ObisCode: "1.0.9.7.0.255", Unit: "VA", QuantityType: "apparent_power"},
+	{FieldName: "power_factor", ObisCode: "1.0.13.7.0.255", Unit: "", QuantityType: "power_factor"},
+	{FieldName: "frequency", ObisCode: "1.0.14.7.0.255", Unit: "Hz", QuantityType: "frequency"},
+	{FieldName: "max_demand_W", ObisCode: "1.0.1.6.0.255", Unit: "W", QuantityType: "active_max_demand"},
+	{FieldName: "max_demand_VA", ObisCode: "1.0.9.6.0.255", Unit: "VA", QuantityType: "apparent_max_demand"},
+	{FieldName: "power_on_minutes", ObisCode: "0.0.94.91.14.255", Unit: "min", QuantityType: "power_on_duration"},
+	{FieldName: "daily_cumm_active_energy_imp", ObisCode: "1.0.1.8.0.255", Unit: "Wh", QuantityType: "active_energy_import"},
+	{FieldName: "daily_cumm_apparent_energy_imp", ObisCode: "1.0.9.8.0.255", Unit: "VAh", QuantityType: "apparent_energy_import"},
+	{FieldName: "daily_cumm_active_energy_exp", ObisCode: "1.0.2.8.0.255", Unit: "Wh", QuantityType: "active_energy_export"},
+	{FieldName: "daily_cumm_apparent_energy_exp", ObisCode: "1.0.10.8.0.255", Unit: "VAh", QuantityType: "apparent_energy_export"},
+	{FieldName: "temperature", ObisCode: "0.0.96.9.0.255", Unit: "°C", QuantityType: "temperature"},
+	{FieldName: "daily_temperature", ObisCode: "0.0.96.9.0.255", Unit: "°C", QuantityType: "temperature"},
+}
+
+func ExecuteScriptsPostToMigrations(db *gorm.DB) {
+	db.Clauses(clause.OnConflict{DoNothing: true}).Create(&defaultEventCatalogue)
+	db.Clauses(clause.OnConflict{DoNothing: true}).Create(&defaultFieldCatalogue)
 }